@@ -1,52 +1,82 @@
 package main
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/securityhub"
-	"github.com/gofrs/uuid"
 	"io"
 	"log"
-	"path/filepath"
+	"os"
+	"shared/artifact"
+	"shared/blobstore"
+	"shared/finding"
+	"shared/layout"
+	"strconv"
 	"time"
 )
 
 type Lambda struct {
 	ctx               context.Context
-	s3Client          *s3.Client
+	store             blobstore.BlobStore
 	securityHubClient *securityhub.Client
 }
 
 func New(cfg aws.Config) *Lambda {
 	m := new(Lambda)
 	m.securityHubClient = securityhub.NewFromConfig(cfg)
-	m.s3Client = s3.NewFromConfig(cfg)
+	m.store = blobstore.NewFromConfig(cfg)
+
+	if store, ok := m.store.(*blobstore.S3); ok {
+		store.PartSize = resolveBufferSize()
+	}
+
 	return m
 }
 
+// resolveBufferSize returns the size of the in-memory buffer in bytes, configured in megabytes with BUFFER_SIZE.
+func resolveBufferSize() int {
+	num, err := strconv.Atoi(os.Getenv("BUFFER_SIZE"))
+
+	if err != nil || num*blobstore.Megabyte < blobstore.MinimumPartSize {
+		return blobstore.DefaultPartSize
+	}
+
+	return num * blobstore.Megabyte
+}
+
 func (x *Lambda) Handler(ctx context.Context, request Request) (Response, error) {
 	x.ctx = ctx
 	log.Printf("Running a report for: %s", request.Report)
 	log.Printf("Use the '%s' bucket", request.Bucket)
 
-	aggregatedFindings, err := x.aggregateFindings(request.Bucket, request.Findings)
+	objectKey := layout.Unique(request.Report, "aggregated")
+	writer := x.store.NewWriter(ctx, request.Bucket, objectKey)
+	hashWriter := artifact.NewWriter(writer)
+	count, err := x.aggregateFindings(request.Bucket, request.Findings, request.Checksums, hashWriter)
+
+	if err == nil {
+		err = writer.Close()
+	}
 
 	if err != nil {
+		_ = writer.Abort()
 		return Response{}, err
 	}
 
-	findingsData, err := json.Marshal(aggregatedFindings)
+	log.Printf("Aggregated %d findings from %d files into s3://%s/%s (%d bytes)",
+		count, len(request.Findings), request.Bucket, objectKey, writer.Size())
 
-	if err != nil {
-		return Response{}, err
+	// The source files are replaced by the aggregated file, only the checksums of earlier aggregations are kept.
+	checksums := artifact.Checksums{}
+
+	for _, key := range request.AggregatedFindings {
+		if checksum := request.Checksums.Get(key); checksum != nil {
+			checksums[key] = *checksum
+		}
 	}
 
-	objectKey := x.resolveBucketKey("aggregated", request.Report)
-	err = x.uploadFile(request.Bucket, objectKey, findingsData)
+	checksums[objectKey] = artifact.Checksum{SHA256: hashWriter.Sum(), Records: count}
 
 	return Response{
 		Report:             request.Report,
@@ -54,76 +84,81 @@ func (x *Lambda) Handler(ctx context.Context, request Request) (Response, error)
 		Controls:           request.Controls,
 		GroupBy:            request.GroupBy,
 		Filter:             request.Filter,
+		SplitBy:            request.SplitBy,
+		Options:            request.Options,
 		FindingCount:       0,
 		Findings:           []string{},
 		AggregatedFindings: append(request.AggregatedFindings, objectKey),
 		NextToken:          request.NextToken,
 		Timestamp:          time.Now().Unix(),
-	}, err
+		Checksums:          checksums,
+	}, nil
 }
 
-func (x *Lambda) aggregateFindings(bucket string, findings []string) ([]Finding, error) {
-	var aggregatedFindings []Finding
+// aggregateFindings streams the findings of every source object into a single JSON list, one finding at a time.
+func (x *Lambda) aggregateFindings(bucket string, keys []string, checksums artifact.Checksums, writer io.Writer) (int, error) {
+	encoder := finding.NewEncoder(writer)
 
-	for _, finding := range findings {
-		data, err := x.downloadFile(bucket, finding)
+	for _, key := range keys {
+		checksum, err := checksums.Require(key)
 
 		if err != nil {
-			return aggregatedFindings, err
+			return encoder.Count(), err
 		}
 
-		var findings []Finding
-		err = json.Unmarshal(data, &findings)
+		err = x.streamFindings(bucket, key, checksum, encoder)
 
 		if err != nil {
-			return aggregatedFindings, err
+			return encoder.Count(), err
 		}
-
-		aggregatedFindings = append(aggregatedFindings, findings...)
 	}
 
-	return aggregatedFindings, nil
+	return encoder.Count(), encoder.Close()
 }
 
-func (x *Lambda) downloadFile(bucket string, key string) ([]byte, error) {
-	log.Printf("Downloading s3://%s/%s", bucket, key)
+// streamFindings copies the findings of a single source object into the encoder. The checksum is only known after the
+// last finding is read, on a mismatch the caller aborts the upload so the aggregated file is never used.
+func (x *Lambda) streamFindings(bucket string, key string, checksum *artifact.Checksum, encoder *finding.Encoder) error {
+	body, err := x.store.Open(x.ctx, bucket, key)
 
-	response, err := x.s3Client.GetObject(x.ctx, &s3.GetObjectInput{
-		Bucket: aws.String(bucket),
-		Key:    aws.String(key),
-	})
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	return io.ReadAll(response.Body)
-}
+	defer body.Close()
+	reader := artifact.NewReader(body)
+	decoder := finding.NewDecoder(reader)
+	records := 0
 
-func (x *Lambda) uploadFile(bucket string, key string, data []byte) error {
-	log.Printf("Upload file to s3://%s/%s", bucket, key)
+	for {
+		record, err := decoder.Next()
 
-	_, err := x.s3Client.PutObject(x.ctx, &s3.PutObjectInput{
-		Bucket: aws.String(bucket),
-		Key:    aws.String(key),
-		Body:   bytes.NewReader(data),
-	})
+		if err == io.EOF {
+			// The decoder stops at the end of the list, read the remainder to hash the complete object.
+			_, err = io.Copy(io.Discard, reader)
 
-	viewData := string(data)
-	log.Printf(viewData)
+			if err != nil {
+				return err
+			}
 
-	return err
-}
+			err = checksum.VerifySum(key, reader.Sum())
+
+			if err != nil {
+				return err
+			}
 
-func (x *Lambda) resolveBucketKey(prefix string, report string) string {
-	t := time.Now()
-	id, _ := uuid.NewV6()
-
-	return filepath.Join(
-		report,
-		prefix,
-		fmt.Sprintf("%d", t.Year()),
-		fmt.Sprintf("%02d", int(t.Month())),
-		fmt.Sprintf("%02d", t.Day()),
-		fmt.Sprintf("%s.json", id.String()),
-	)
+			return checksum.VerifyRecords(key, records)
+		}
+
+		if err != nil {
+			return fmt.Errorf("s3://%s/%s: %w", bucket, key, err)
+		}
+
+		records++
+		err = encoder.Encode(record)
+
+		if err != nil {
+			return err
+		}
+	}
 }
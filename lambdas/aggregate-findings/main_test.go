@@ -13,6 +13,9 @@ import (
 	"github.com/stretchr/testify/assert"
 	"io"
 	"os"
+	"shared/artifact"
+	"shared/blobstore"
+	"shared/finding"
 	"testing"
 )
 
@@ -39,29 +42,34 @@ func GetFilters(report string) *types.AwsSecurityFindingFilters {
 	}
 }
 
-func generateFinding(prefix string, index int) Finding {
-	return Finding{
+func generateFinding(prefix string, index int) finding.Finding {
+	return finding.Finding{
 		Id: fmt.Sprintf("%s-%d", prefix, index),
 	}
 }
 
-func generateFindings(prefix string, count int) []Finding {
-	var findings []Finding
+func generateFindings(prefix string, count int) []finding.Finding {
+	var findings []finding.Finding
 	for i := 0; i < count; i++ {
 		findings = append(findings, generateFinding(prefix, i))
 	}
 	return findings
 }
 
-func toReader(findings []Finding) *bytes.Reader {
+func toReader(findings []finding.Finding) *bytes.Reader {
 	data, _ := json.Marshal(findings)
 	return bytes.NewReader(data)
 }
 
-func toReadCloser(findings []Finding) io.ReadCloser {
+func toReadCloser(findings []finding.Finding) io.ReadCloser {
 	return io.NopCloser(toReader(findings))
 }
 
+func toChecksum(findings []finding.Finding) artifact.Checksum {
+	data, _ := json.Marshal(findings)
+	return artifact.NewChecksum(data, len(findings))
+}
+
 func readEvent(path string) Request {
 	file, _ := os.ReadFile(path)
 
@@ -99,7 +107,13 @@ func TestHandler(t *testing.T) {
 			IgnoreFields:  []string{"Key"},
 		})
 
-		response, err := lambda.Handler(ctx, event)
+		request := event
+		request.Checksums = artifact.Checksums{
+			"my/first/batch.json":  toChecksum(firstBatch),
+			"my/second/batch.json": toChecksum(secondBatch),
+		}
+
+		response, err := lambda.Handler(ctx, request)
 		testtools.ExitTest(stubber, t)
 		assert.NoError(t, err)
 		assert.Equal(t, event.Report, response.Report)
@@ -109,6 +123,31 @@ func TestHandler(t *testing.T) {
 		assert.Equal(t, 0, response.FindingCount)
 		assert.Equal(t, 0, len(response.Findings))
 		assert.Equal(t, 1, len(response.AggregatedFindings))
+		assert.Equal(t, artifact.Checksums{response.AggregatedFindings[0]: toChecksum(expectedBatch)}, response.Checksums)
+	})
+
+	t.Run("Fail on checksum mismatch", func(t *testing.T) {
+
+		ctx := context.Background()
+		firstBatch := generateFindings("first", 10)
+
+		stubber := testtools.NewStubber()
+		lambda := New(*stubber.SdkConfig)
+		stubber.Add(testtools.Stub{
+			OperationName: "GetObject",
+			Input:         &s3.GetObjectInput{Bucket: aws.String("my-sample-bucket"), Key: aws.String("my/first/batch.json")},
+			Output:        &s3.GetObjectOutput{Body: toReadCloser(firstBatch[:9])},
+		})
+
+		request := event
+		request.Checksums = artifact.Checksums{"my/first/batch.json": toChecksum(firstBatch)}
+
+		_, err := lambda.Handler(ctx, request)
+		testtools.ExitTest(stubber, t)
+
+		var integrityErr *artifact.IntegrityError
+		assert.True(t, errors.As(err, &integrityErr))
+		assert.Equal(t, "my/first/batch.json", integrityErr.Key)
 	})
 
 	t.Run("Fail on downloading finding files", func(t *testing.T) {
@@ -130,7 +169,34 @@ func TestHandler(t *testing.T) {
 			Error:         raiseErr,
 		})
 
-		_, err := lambda.Handler(ctx, event)
+		request := event
+		request.Checksums = artifact.Checksums{
+			"my/first/batch.json":  toChecksum(firstBatch),
+			"my/second/batch.json": toChecksum(generateFindings("second", 10)),
+		}
+
+		_, err := lambda.Handler(ctx, request)
+		testtools.ExitTest(stubber, t)
+		assert.Error(t, err)
+	})
+
+	t.Run("Fail on malformed finding files", func(t *testing.T) {
+
+		ctx := context.Background()
+		malformed := []byte(`{"Id": "first-0"}`)
+
+		stubber := testtools.NewStubber()
+		lambda := New(*stubber.SdkConfig)
+		stubber.Add(testtools.Stub{
+			OperationName: "GetObject",
+			Input:         &s3.GetObjectInput{Bucket: aws.String("my-sample-bucket"), Key: aws.String("my/first/batch.json")},
+			Output:        &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(malformed))},
+		})
+
+		request := event
+		request.Checksums = artifact.Checksums{"my/first/batch.json": artifact.NewChecksum(malformed, 1)}
+
+		_, err := lambda.Handler(ctx, request)
 		testtools.ExitTest(stubber, t)
 		assert.Error(t, err)
 	})
@@ -162,8 +228,49 @@ func TestHandler(t *testing.T) {
 			Error:         raiseErr,
 		})
 
-		_, err := lambda.Handler(ctx, event)
+		request := event
+		request.Checksums = artifact.Checksums{
+			"my/first/batch.json":  toChecksum(firstBatch),
+			"my/second/batch.json": toChecksum(secondBatch),
+		}
+
+		_, err := lambda.Handler(ctx, request)
 		testtools.ExitTest(stubber, t)
 		assert.Error(t, err)
 	})
+
+	t.Run("Fail on a missing checksum", func(t *testing.T) {
+
+		ctx := context.Background()
+
+		stubber := testtools.NewStubber()
+		lambda := New(*stubber.SdkConfig)
+
+		_, err := lambda.Handler(ctx, event)
+		testtools.ExitTest(stubber, t)
+
+		var integrityErr *artifact.IntegrityError
+		assert.True(t, errors.As(err, &integrityErr))
+		assert.Equal(t, "my/first/batch.json", integrityErr.Key)
+		assert.Equal(t, "Checksum", integrityErr.Field)
+	})
+}
+
+func TestResolveBufferSize(t *testing.T) {
+	t.Run("Default buffer size", func(t *testing.T) {
+		_ = os.Setenv("BUFFER_SIZE", "")
+		assert.Equal(t, 8*blobstore.Megabyte, resolveBufferSize())
+	})
+
+	t.Run("Configured buffer size", func(t *testing.T) {
+		_ = os.Setenv("BUFFER_SIZE", "64")
+		assert.Equal(t, 64*blobstore.Megabyte, resolveBufferSize())
+		_ = os.Setenv("BUFFER_SIZE", "")
+	})
+
+	t.Run("Buffer size below the S3 part minimum", func(t *testing.T) {
+		_ = os.Setenv("BUFFER_SIZE", "1")
+		assert.Equal(t, 8*blobstore.Megabyte, resolveBufferSize())
+		_ = os.Setenv("BUFFER_SIZE", "")
+	})
 }
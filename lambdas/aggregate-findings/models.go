@@ -1,6 +1,10 @@
 package main
 
-import "github.com/aws/aws-sdk-go-v2/service/securityhub/types"
+import (
+	"github.com/aws/aws-sdk-go-v2/service/securityhub/types"
+	"shared/artifact"
+	"shared/report"
+)
 
 type Request struct {
 	Report             string                          `json:"Report"`
@@ -8,11 +12,14 @@ type Request struct {
 	Controls           string                          `json:"Controls"`
 	GroupBy            string                          `json:"GroupBy"`
 	Filter             types.AwsSecurityFindingFilters `json:"Filter"`
+	SplitBy            []string                        `json:"SplitBy"`
+	Options            report.Options                  `json:"Options"`
 	Findings           []string                        `json:"Findings"`
 	FindingCount       int                             `json:"FindingCount"`
 	AggregatedFindings []string                        `json:"AggregatedFindings"`
 	NextToken          string                          `json:"NextToken"`
 	Timestamp          int64                           `json:"Timestamp"`
+	Checksums          artifact.Checksums              `json:"Checksums"`
 }
 
 type Response struct {
@@ -21,19 +28,12 @@ type Response struct {
 	Controls           string                          `json:"Controls"`
 	GroupBy            string                          `json:"GroupBy"`
 	Filter             types.AwsSecurityFindingFilters `json:"Filter"`
+	SplitBy            []string                        `json:"SplitBy"`
+	Options            report.Options                  `json:"Options"`
 	Findings           []string                        `json:"Findings"`
 	FindingCount       int                             `json:"FindingCount"`
 	AggregatedFindings []string                        `json:"AggregatedFindings"`
 	NextToken          string                          `json:"NextToken"`
 	Timestamp          int64                           `json:"Timestamp"`
-}
-
-type Finding struct {
-	Id             string `json:"Id"`
-	Status         string `json:"Status"`
-	ProductArn     string `json:"ProductArn"`
-	GeneratorId    string `json:"GeneratorId"`
-	AwsAccountId   string `json:"AwsAccountId"`
-	AwsAccountName string `json:"AwsAccountName"`
-	Title          string `json:"Title"`
+	Checksums          artifact.Checksums              `json:"Checksums"`
 }
@@ -0,0 +1,388 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"shared/dimension"
+	"shared/score"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// The widgets a dashboard can show for every workload.
+const (
+	WidgetScore          = "Score"
+	WidgetScoreTrend     = "ScoreTrend"
+	WidgetFindings       = "Findings"
+	WidgetControlsFailed = "ControlsFailed"
+	WidgetStatus         = "Status"
+)
+
+const (
+	defaultNamespace = "SecurityPosture"
+	defaultColumns   = 2
+	defaultHeight    = 6
+	defaultPeriod    = 21600
+	// gridWidth is the width of a CloudWatch dashboard in grid units.
+	gridWidth = 24
+	// maxWidgets is the number of widgets CloudWatch allows on a dashboard.
+	maxWidgets = 500
+)
+
+var defaultWidgets = []string{WidgetScore, WidgetFindings}
+
+// dashboardName matches the names CloudWatch accepts for a dashboard.
+var dashboardName = regexp.MustCompile(`^[A-Za-z0-9_-]{1,255}$`)
+
+// Body is the dashboard body of CloudWatch, see the dashboard body structure in the CloudWatch API reference.
+type Body struct {
+	Widgets []Widget `json:"widgets"`
+}
+
+type Widget struct {
+	Type       string `json:"type"`
+	X          int    `json:"x"`
+	Y          int    `json:"y"`
+	Width      int    `json:"width"`
+	Height     int    `json:"height"`
+	Properties any    `json:"properties"`
+}
+
+type TextProperties struct {
+	Markdown string `json:"markdown"`
+}
+
+type MetricProperties struct {
+	Title       string       `json:"title"`
+	View        string       `json:"view"`
+	Stacked     bool         `json:"stacked"`
+	Region      string       `json:"region"`
+	Period      int          `json:"period"`
+	Stat        string       `json:"stat"`
+	Metrics     [][]any      `json:"metrics"`
+	YAxis       *YAxis       `json:"yAxis,omitempty"`
+	Annotations *Annotations `json:"annotations,omitempty"`
+	Legend      Legend       `json:"legend"`
+}
+
+type YAxis struct {
+	Left Axis `json:"left"`
+}
+
+type Axis struct {
+	Min float64 `json:"min"`
+	Max float64 `json:"max"`
+}
+
+type Annotations struct {
+	Horizontal []Annotation `json:"horizontal"`
+}
+
+type Annotation struct {
+	Color string  `json:"color"`
+	Label string  `json:"label"`
+	Value float64 `json:"value"`
+	Fill  string  `json:"fill"`
+}
+
+type Legend struct {
+	Position string `json:"position"`
+}
+
+// Layout is the resolved dashboard options of a report.
+type Layout struct {
+	Name      string
+	Namespace string
+	Region    string
+	Widgets   []string
+	Columns   int
+	Height    int
+	Period    int
+	// DimensionSet is the set of the metrics on the dashboard, nil for the default dimensions of publish-metrics.
+	DimensionSet []string
+}
+
+// Line is a metric on a widget: the dimensions of the metric and the label of its line.
+type Line struct {
+	Label      string
+	Dimensions []dimension.Dimension
+}
+
+// NewLayout validates the dashboard options of the report and fills in the defaults.
+func NewLayout(request Request, region string) (Layout, error) {
+	options := request.Options.Dashboard
+	layout := Layout{
+		Name:      options.Name,
+		Namespace: request.Options.Namespace,
+		Region:    region,
+		Widgets:   options.Widgets,
+		Columns:   options.Columns,
+		Height:    options.Height,
+		Period:    options.Period,
+	}
+
+	if !request.Options.PublishesToCloudWatch() {
+		return layout, fmt.Errorf("dashboard needs the metrics in CloudWatch, add the CloudWatch or EMF sink to %s",
+			strings.Join(request.Options.Sinks, ", "))
+	}
+
+	if layout.Name == "" {
+		layout.Name = request.Report
+	}
+
+	if !dashboardName.MatchString(layout.Name) {
+		return layout, fmt.Errorf("dashboard name `%s` is not valid, use up to 255 letters, digits, - and _", layout.Name)
+	}
+
+	if layout.Namespace == "" {
+		layout.Namespace = defaultNamespace
+	}
+
+	if len(layout.Widgets) == 0 {
+		layout.Widgets = defaultWidgets
+	}
+
+	for _, widget := range layout.Widgets {
+		switch widget {
+		case WidgetScore, WidgetScoreTrend, WidgetFindings, WidgetControlsFailed, WidgetStatus:
+		default:
+			return layout, fmt.Errorf("widget `%s` is not supported, use %s, %s, %s, %s or %s", widget,
+				WidgetScore, WidgetScoreTrend, WidgetFindings, WidgetControlsFailed, WidgetStatus)
+		}
+	}
+
+	if layout.Columns == 0 {
+		layout.Columns = defaultColumns
+	}
+
+	if layout.Columns < 0 || layout.Columns > 6 {
+		return layout, fmt.Errorf("columns %d is not supported, use 1 to 6", layout.Columns)
+	}
+
+	if layout.Height == 0 {
+		layout.Height = defaultHeight
+	}
+
+	if layout.Height < 0 {
+		return layout, fmt.Errorf("height %d is negative", layout.Height)
+	}
+
+	if layout.Period == 0 {
+		layout.Period = defaultPeriod
+	}
+
+	if layout.Period < 0 || layout.Period%60 != 0 {
+		return layout, fmt.Errorf("period %d is not a positive multiple of 60 seconds", layout.Period)
+	}
+
+	// The dashboard needs the workload and environment of every metric, the first set that has both is used.
+	for _, set := range request.Options.DimensionSets {
+		if slices.Contains(set, "Workload") && slices.Contains(set, "Environment") {
+			layout.DimensionSet = set
+			break
+		}
+	}
+
+	if len(request.Options.DimensionSets) > 0 && layout.DimensionSet == nil {
+		return layout, fmt.Errorf("dashboard needs a dimension set with Workload and Environment")
+	}
+
+	return layout, nil
+}
+
+// Build returns the dashboard body with a header and a row of widgets for every workload of the accounts, sorted by
+// name. The widgets have a line for every environment of the workload.
+func Build(report string, accounts []*CalculatedScore, layout Layout) (Body, error) {
+	workloads := map[string][]Line{}
+	statuses := map[string]map[score.Status]bool{}
+	seen := map[string]bool{}
+
+	for _, calculated := range accounts {
+		status := score.Resolve(calculated.Status)
+
+		// Excluded accounts are not published.
+		if status == score.StatusExcluded {
+			continue
+		}
+
+		workload := workloadName(calculated)
+		line := newLine(report, calculated, layout.DimensionSet)
+
+		if statuses[workload] == nil {
+			statuses[workload] = map[score.Status]bool{}
+		}
+		statuses[workload][status] = true
+
+		key := workload + "\x00" + lineKey(line)
+		if seen[key] {
+			continue
+		}
+
+		seen[key] = true
+		workloads[workload] = append(workloads[workload], line)
+	}
+
+	names := make([]string, 0, len(workloads))
+	for workload := range workloads {
+		names = append(names, workload)
+	}
+	sort.Strings(names)
+
+	if count := len(names) * (len(layout.Widgets) + 1); count > maxWidgets {
+		return Body{}, fmt.Errorf("dashboard has %d widgets for %d workloads, CloudWatch allows %d", count, len(names), maxWidgets)
+	}
+
+	body := Body{Widgets: []Widget{}}
+	width := gridWidth / layout.Columns
+	y := 0
+
+	for _, workload := range names {
+		lines := workloads[workload]
+		sort.SliceStable(lines, func(i, j int) bool {
+			return lines[i].Label < lines[j].Label
+		})
+
+		body.Widgets = append(body.Widgets, Widget{
+			Type:       "text",
+			Y:          y,
+			Width:      gridWidth,
+			Height:     1,
+			Properties: TextProperties{Markdown: "## " + workload},
+		})
+		y++
+
+		for i, widget := range layout.Widgets {
+			column := i % layout.Columns
+
+			if column == 0 && i > 0 {
+				y += layout.Height
+			}
+
+			body.Widgets = append(body.Widgets, Widget{
+				Type:       "metric",
+				X:          column * width,
+				Y:          y,
+				Width:      width,
+				Height:     layout.Height,
+				Properties: newMetricProperties(widget, workload, lines, sortedStatuses(statuses[workload]), layout),
+			})
+		}
+
+		y += layout.Height
+	}
+
+	return body, nil
+}
+
+func newMetricProperties(widget string, workload string, lines []Line, statuses []score.Status, layout Layout) MetricProperties {
+	properties := MetricProperties{
+		Title:   fmt.Sprintf("%s - %s", workload, widget),
+		View:    "timeSeries",
+		Region:  layout.Region,
+		Period:  layout.Period,
+		Stat:    "Maximum",
+		Legend:  Legend{Position: "bottom"},
+		Metrics: [][]any{},
+	}
+
+	switch widget {
+	case WidgetScore:
+		properties.View = "gauge"
+		properties.Metrics = metrics(layout.Namespace, "Score", lines)
+		properties.YAxis = &YAxis{Left: Axis{Min: 0, Max: 100}}
+		properties.Annotations = &Annotations{Horizontal: []Annotation{
+			{Color: "#d62728", Label: "Failing", Value: 0, Fill: "above"},
+			{Color: "#f89256", Label: "Warning", Value: 90, Fill: "above"},
+			{Color: "#2ca02c", Label: "Passing", Value: 95, Fill: "above"},
+		}}
+	case WidgetScoreTrend:
+		properties.Metrics = metrics(layout.Namespace, "Score", lines)
+		properties.YAxis = &YAxis{Left: Axis{Min: 0, Max: 100}}
+	case WidgetFindings:
+		properties.Metrics = metrics(layout.Namespace, "Findings", lines)
+	case WidgetControlsFailed:
+		properties.Metrics = metrics(layout.Namespace, "ControlsFailed", lines)
+	case WidgetStatus:
+		// Every account is counted by its status, the counts of a status are summed per environment.
+		properties.Stacked = true
+		properties.Stat = "Sum"
+		for _, status := range statuses {
+			for _, line := range lines {
+				dimensions := dimension.WithStatus(line.Dimensions, string(status))
+				properties.Metrics = append(properties.Metrics, metric(layout.Namespace, "Accounts", dimensions, line.Label+" "+string(status)))
+			}
+		}
+	}
+
+	return properties
+}
+
+func metrics(namespace string, name string, lines []Line) [][]any {
+	rendered := make([][]any, 0, len(lines))
+	for _, line := range lines {
+		rendered = append(rendered, metric(namespace, name, line.Dimensions, line.Label))
+	}
+	return rendered
+}
+
+// metric renders a metric in the array notation of the dashboard body: namespace, name, dimension names and values, and
+// the rendering properties.
+func metric(namespace string, name string, dimensions []dimension.Dimension, label string) []any {
+	rendered := []any{namespace, name}
+	for _, dimension := range dimensions {
+		rendered = append(rendered, dimension.Name, dimension.Value)
+	}
+	return append(rendered, map[string]string{"label": label})
+}
+
+// newLine returns the dimensions the metrics of the account are published under, as publish-metrics does. The label is
+// made of the values that tell the lines of a workload apart, like the environment.
+func newLine(report string, calculated *CalculatedScore, set []string) Line {
+	dimensions := dimension.Metric(dimension.Labels{
+		Report:             report,
+		AccountId:          calculated.AccountId,
+		AccountName:        calculated.AccountName,
+		Workload:           calculated.Workload,
+		Environment:        calculated.Environment,
+		OrganizationalUnit: calculated.OrganizationalUnit,
+		Dimensions:         calculated.Dimensions,
+	}, set)
+
+	var label []string
+	for _, dimension := range dimensions {
+		if dimension.Name != "Report" && dimension.Name != "Workload" {
+			label = append(label, dimension.Value)
+		}
+	}
+
+	return Line{Label: strings.Join(label, " / "), Dimensions: dimensions}
+}
+
+func lineKey(line Line) string {
+	var parts []string
+	for _, dimension := range line.Dimensions {
+		parts = append(parts, dimension.Name+"="+dimension.Value)
+	}
+	return strings.Join(parts, "\x00")
+}
+
+func sortedStatuses(statuses map[score.Status]bool) []score.Status {
+	sorted := make([]score.Status, 0, len(statuses))
+	for status := range statuses {
+		sorted = append(sorted, status)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i] < sorted[j]
+	})
+	return sorted
+}
+
+// workloadName returns the workload of the account, accounts without a workload are grouped under None.
+func workloadName(calculated *CalculatedScore) string {
+	if calculated.Workload == "" {
+		return dimension.Missing
+	}
+
+	return calculated.Workload
+}
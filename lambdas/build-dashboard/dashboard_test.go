@@ -0,0 +1,127 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"shared/dimension"
+	"shared/report"
+	"testing"
+)
+
+func TestNewLayout(t *testing.T) {
+	request := Request{Report: "my-report", Options: report.Options{Dashboard: &report.Dashboard{}}}
+
+	t.Run("Defaults", func(t *testing.T) {
+		layout, err := NewLayout(request, "eu-west-1")
+		require.NoError(t, err)
+		assert.Equal(t, Layout{
+			Name:      "my-report",
+			Namespace: "SecurityPosture",
+			Region:    "eu-west-1",
+			Widgets:   []string{WidgetScore, WidgetFindings},
+			Columns:   2,
+			Height:    6,
+			Period:    21600,
+		}, layout)
+	})
+
+	t.Run("Dimension set with the workload and environment", func(t *testing.T) {
+		request := request
+		request.Options.DimensionSets = [][]string{{"Report"}, {"Environment", "Workload"}}
+
+		layout, err := NewLayout(request, "eu-west-1")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Environment", "Workload"}, layout.DimensionSet)
+	})
+
+	invalid := map[string]report.Options{
+		"dashboard name `my report` is not valid, use up to 255 letters, digits, - and _":                 {Dashboard: &report.Dashboard{Name: "my report"}},
+		"widget `Map` is not supported, use Score, ScoreTrend, Findings, ControlsFailed or Status":        {Dashboard: &report.Dashboard{Widgets: []string{"Map"}}},
+		"columns 7 is not supported, use 1 to 6":                                                          {Dashboard: &report.Dashboard{Columns: 7}},
+		"height -1 is negative":                                                                           {Dashboard: &report.Dashboard{Height: -1}},
+		"period 90 is not a positive multiple of 60 seconds":                                              {Dashboard: &report.Dashboard{Period: 90}},
+		"dashboard needs a dimension set with Workload and Environment":                                   {Dashboard: &report.Dashboard{}, DimensionSets: [][]string{{"Report", "Workload"}}},
+		"dashboard needs the metrics in CloudWatch, add the CloudWatch or EMF sink to Prometheus, StatsD": {Dashboard: &report.Dashboard{}, Sinks: []string{"Prometheus", "StatsD"}},
+	}
+
+	for message, options := range invalid {
+		_, err := NewLayout(Request{Report: "my-report", Options: options}, "eu-west-1")
+		assert.EqualError(t, err, message)
+	}
+}
+
+func TestBuild(t *testing.T) {
+	accounts := []*CalculatedScore{
+		{AccountId: "111122223333", Workload: "payments", Environment: "test", Status: "SCORED"},
+		{AccountId: "333322221111", Workload: "payments", Environment: "production", Status: "SCORED"},
+		{AccountId: "444455556666", Workload: "payments", Environment: "production", Status: "BASELINING"},
+		{AccountId: "555566667777", Environment: "sandbox", Status: "NO_FINDINGS"},
+		{AccountId: "666677778888", Workload: "excluded", Environment: "production", Status: "EXCLUDED"},
+	}
+	layout := Layout{Name: "my-report", Namespace: "SecurityPosture", Region: "eu-west-1", Columns: 2, Height: 6, Period: 3600,
+		Widgets: []string{WidgetScore, WidgetFindings, WidgetStatus}}
+
+	t.Run("Widgets per workload", func(t *testing.T) {
+		body, err := Build("my-report", accounts, layout)
+		require.NoError(t, err)
+		assert.Equal(t, 8, len(body.Widgets))
+
+		var positions [][4]int
+		for _, widget := range body.Widgets {
+			positions = append(positions, [4]int{widget.X, widget.Y, widget.Width, widget.Height})
+		}
+
+		assert.Equal(t, [][4]int{
+			{0, 0, 24, 1}, {0, 1, 12, 6}, {12, 1, 12, 6}, {0, 7, 12, 6},
+			{0, 13, 24, 1}, {0, 14, 12, 6}, {12, 14, 12, 6}, {0, 20, 12, 6},
+		}, positions)
+
+		assert.Equal(t, TextProperties{Markdown: "## None"}, body.Widgets[0].Properties)
+		assert.Equal(t, TextProperties{Markdown: "## payments"}, body.Widgets[4].Properties)
+
+		score := body.Widgets[5].Properties.(MetricProperties)
+		assert.Equal(t, "gauge", score.View)
+		assert.Equal(t, 3600, score.Period)
+		assert.Equal(t, [][]any{
+			{"SecurityPosture", "Score", "Report", "my-report", "Workload", "payments", "Environment", "production", map[string]string{"label": "production"}},
+			{"SecurityPosture", "Score", "Report", "my-report", "Workload", "payments", "Environment", "test", map[string]string{"label": "test"}},
+		}, score.Metrics)
+
+		status := body.Widgets[7].Properties.(MetricProperties)
+		assert.Equal(t, "Sum", status.Stat)
+		assert.Equal(t, 4, len(status.Metrics))
+		assert.Equal(t, []any{"SecurityPosture", "Accounts", "Report", "my-report", "Workload", "payments", "Environment", "production",
+			"Status", "BASELINING", map[string]string{"label": "production BASELINING"}}, status.Metrics[0])
+	})
+
+	t.Run("Split dimensions and dimension sets", func(t *testing.T) {
+		split := []*CalculatedScore{
+			{Workload: "payments", Environment: "production", Dimensions: []dimension.Dimension{{Name: "Region", Value: "eu-west-1"}}},
+			{Workload: "payments", Environment: "production", Dimensions: []dimension.Dimension{{Name: "Region", Value: "us-east-1"}}},
+		}
+		layout := layout
+		layout.Widgets = []string{WidgetFindings}
+
+		body, err := Build("my-report", split, layout)
+		require.NoError(t, err)
+		assert.Equal(t, []any{"SecurityPosture", "Findings", "Report", "my-report", "Workload", "payments", "Environment", "production",
+			"Region", "us-east-1", map[string]string{"label": "production / us-east-1"}}, body.Widgets[1].Properties.(MetricProperties).Metrics[1])
+
+		layout.DimensionSet = []string{"Workload", "Environment"}
+		body, err = Build("my-report", split, layout)
+		require.NoError(t, err)
+		assert.Equal(t, [][]any{
+			{"SecurityPosture", "Findings", "Workload", "payments", "Environment", "production", map[string]string{"label": "production"}},
+		}, body.Widgets[1].Properties.(MetricProperties).Metrics)
+	})
+
+	t.Run("Too many widgets", func(t *testing.T) {
+		var many []*CalculatedScore
+		for i := 0; i < 200; i++ {
+			many = append(many, &CalculatedScore{Workload: string(rune('a'+i%26)) + string(rune('a'+i/26)), Environment: "production"})
+		}
+
+		_, err := Build("my-report", many, layout)
+		assert.EqualError(t, err, "dashboard has 800 widgets for 200 workloads, CloudWatch allows 500")
+	})
+}
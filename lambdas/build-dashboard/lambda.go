@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"log"
+	"os"
+	"shared/blobstore"
+)
+
+// The modes of the function, API puts the dashboard in CloudWatch and S3 writes its body to the bucket, for accounts
+// where the function cannot call the CloudWatch API.
+const (
+	modeAPI = "API"
+	modeS3  = "S3"
+)
+
+type Lambda struct {
+	ctx    context.Context
+	client *cloudwatch.Client
+	store  blobstore.BlobStore
+	region string
+}
+
+func New(cfg aws.Config) *Lambda {
+	m := new(Lambda)
+	m.client = cloudwatch.NewFromConfig(cfg)
+	m.store = blobstore.NewFromConfig(cfg)
+	m.region = cfg.Region
+	return m
+}
+
+func (x *Lambda) Handler(ctx context.Context, request Request) (Response, error) {
+	x.ctx = ctx
+
+	if request.Options.Dashboard == nil {
+		log.Printf("Report %s does not build a dashboard", request.Report)
+		return Response{}, nil
+	}
+
+	mode := os.Getenv("DASHBOARD_MODE")
+	if mode == "" {
+		mode = modeAPI
+	}
+
+	if mode != modeAPI && mode != modeS3 {
+		return Response{}, fmt.Errorf("DASHBOARD_MODE `%s` is not supported, use %s or %s", mode, modeAPI, modeS3)
+	}
+
+	layout, err := NewLayout(request, x.region)
+
+	if err != nil {
+		return Response{}, err
+	}
+
+	body, err := Build(request.Report, request.Accounts, layout)
+
+	if err != nil {
+		return Response{}, err
+	}
+
+	data, err := json.Marshal(body)
+
+	if err != nil {
+		return Response{}, err
+	}
+
+	response := Response{Dashboard: layout.Name}
+
+	if mode == modeS3 {
+		response.Key = fmt.Sprintf("%s/dashboards/%s.json", request.Report, layout.Name)
+		log.Printf("Writing dashboard %s with %d widgets to %s", layout.Name, len(body.Widgets), response.Key)
+		return response, x.store.Upload(x.ctx, request.Bucket, response.Key, data)
+	}
+
+	output, err := x.client.PutDashboard(x.ctx, &cloudwatch.PutDashboardInput{
+		DashboardName: aws.String(layout.Name),
+		DashboardBody: aws.String(string(data)),
+	})
+
+	if err != nil {
+		return response, err
+	}
+
+	// The dashboard is saved with validation warnings, like a metric that has no data yet.
+	for _, message := range output.DashboardValidationMessages {
+		log.Printf("Dashboard %s: %s (%s)", layout.Name, aws.ToString(message.Message), aws.ToString(message.DataPath))
+	}
+
+	log.Printf("Put dashboard %s with %d widgets", layout.Name, len(body.Widgets))
+
+	return response, nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/awsdocs/aws-doc-sdk-examples/gov2/testtools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"shared/blobstore/blobtest"
+	"shared/report"
+	"testing"
+)
+
+func readEvent(path string) Request {
+	file, _ := os.ReadFile(path)
+
+	var event Request
+	_ = json.Unmarshal(file, &event)
+	return event
+}
+
+func TestHandler(t *testing.T) {
+	ctx := context.Background()
+	event := readEvent("../../events/build-dashboard.json")
+
+	t.Run("Put the dashboard", func(t *testing.T) {
+		stubber := testtools.NewStubber()
+		stubber.SdkConfig.Region = "eu-west-1"
+		lambda := New(*stubber.SdkConfig)
+		stubber.Add(testtools.Stub{
+			OperationName: "PutDashboard",
+			Input:         &cloudwatch.PutDashboardInput{DashboardName: aws.String("aws-foundational-security-best-practices")},
+			Output: &cloudwatch.PutDashboardOutput{DashboardValidationMessages: []types.DashboardValidationMessage{
+				{DataPath: aws.String("/widgets/1"), Message: aws.String("The metric has no data")},
+			}},
+			IgnoreFields: []string{"DashboardBody"},
+		})
+
+		response, err := lambda.Handler(ctx, event)
+		testtools.ExitTest(stubber, t)
+
+		assert.NoError(t, err)
+		assert.Equal(t, Response{Dashboard: "aws-foundational-security-best-practices"}, response)
+	})
+
+	t.Run("Write the dashboard to the bucket", func(t *testing.T) {
+		_ = os.Setenv("DASHBOARD_MODE", "S3")
+		defer func() { _ = os.Setenv("DASHBOARD_MODE", "") }()
+
+		stubber := testtools.NewStubber()
+		stubber.SdkConfig.Region = "eu-west-1"
+		lambda := New(*stubber.SdkConfig)
+		store := blobtest.New(t)
+		lambda.store = store
+
+		request := readEvent("../../events/build-dashboard.json")
+		request.Options.Dashboard.Name = "security-posture"
+
+		response, err := lambda.Handler(ctx, request)
+		testtools.ExitTest(stubber, t)
+
+		require.NoError(t, err)
+		assert.Equal(t, "aws-foundational-security-best-practices/dashboards/security-posture.json", response.Key)
+
+		data := store.Read(response.Key)
+
+		var body struct {
+			Widgets []struct {
+				Type       string         `json:"type"`
+				Properties map[string]any `json:"properties"`
+			} `json:"widgets"`
+		}
+		require.NoError(t, json.Unmarshal(data, &body))
+		assert.Equal(t, 8, len(body.Widgets))
+		assert.Equal(t, "## my-workload", body.Widgets[0].Properties["markdown"])
+		assert.Equal(t, "eu-west-1", body.Widgets[1].Properties["region"])
+		assert.Equal(t, "## payments", body.Widgets[4].Properties["markdown"])
+	})
+
+	t.Run("Unsupported mode", func(t *testing.T) {
+		_ = os.Setenv("DASHBOARD_MODE", "FILE")
+		defer func() { _ = os.Setenv("DASHBOARD_MODE", "") }()
+
+		stubber := testtools.NewStubber()
+		lambda := New(*stubber.SdkConfig)
+
+		_, err := lambda.Handler(ctx, event)
+		testtools.ExitTest(stubber, t)
+
+		assert.EqualError(t, err, "DASHBOARD_MODE `FILE` is not supported, use API or S3")
+	})
+
+	t.Run("Skip a report without a dashboard", func(t *testing.T) {
+		stubber := testtools.NewStubber()
+		lambda := New(*stubber.SdkConfig)
+
+		request := readEvent("../../events/build-dashboard.json")
+		request.Options = report.Options{}
+
+		response, err := lambda.Handler(ctx, request)
+		testtools.ExitTest(stubber, t)
+
+		assert.NoError(t, err)
+		assert.Equal(t, Response{}, response)
+	})
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"shared/dimension"
+	"shared/report"
+	"shared/score"
+)
+
+// CalculatedScore is the part of the result of calculate-score the metrics of an account are published under.
+type CalculatedScore struct {
+	AccountId          string                `json:"AccountId"`
+	AccountName        string                `json:"AccountName"`
+	Workload           string                `json:"Workload"`
+	Environment        string                `json:"Environment"`
+	OrganizationalUnit string                `json:"OrganizationalUnit,omitempty"`
+	Status             score.Status          `json:"Status"`
+	Dimensions         []dimension.Dimension `json:"Dimensions"`
+}
+
+type Request struct {
+	Report    string             `json:"Report"`
+	Timestamp int64              `json:"Timestamp"`
+	Bucket    string             `json:"Bucket"`
+	Accounts  []*CalculatedScore `json:"Accounts"`
+	Options   report.Options     `json:"Options"`
+}
+
+type Response struct {
+	// Dashboard is the name of the dashboard, it is only put in CloudWatch in the API mode.
+	Dashboard string `json:"Dashboard"`
+	// Key is the dashboard body in the bucket, in the S3 mode.
+	Key string `json:"Key,omitempty"`
+}
@@ -2,6 +2,10 @@ package main
 
 import (
 	"log"
+	"shared/control"
+	"shared/finding"
+	"slices"
+	"sort"
 	"strings"
 )
 
@@ -13,6 +17,7 @@ func NewCalculator(expectedControls []string) *Calculator {
 		failed:           0,
 		passed:           0,
 		processHistory:   history,
+		results:          map[string]*control.Result{},
 	}
 }
 
@@ -24,6 +29,7 @@ type Calculator struct {
 	controls         int
 	findings         int
 	processHistory   map[Status][]string
+	results          map[string]*control.Result
 }
 
 type Status string
@@ -34,26 +40,27 @@ const (
 	StatusNotProcessed Status = "NOT YET"
 )
 
-func (x *Calculator) resolveIdentifier(finding *Finding, groupBy string) string {
+func (x *Calculator) resolveIdentifier(record *finding.Finding, groupBy string) string {
 	switch groupBy {
 	case "Title":
 		for _, control := range x.expectedControls {
-			if strings.HasPrefix(finding.Title, control) {
+			if strings.HasPrefix(record.Title, control) {
 				return control
 			}
 		}
 
-		return finding.Title
+		return record.Title
 	}
 
-	return finding.GeneratorId
+	return record.GeneratorId
 }
 
-func (x *Calculator) ProcessFinding(finding *Finding, groupBy string) {
+func (x *Calculator) ProcessFinding(record *finding.Finding, groupBy string) {
 	x.findings++
-	status := x.resolveStatus(finding)
-	identifier := x.resolveIdentifier(finding, groupBy)
+	status := x.resolveStatus(record)
+	identifier := x.resolveIdentifier(record, groupBy)
 	log.Printf("Resolved identifier: %s\n", identifier)
+	x.recordResult(record, identifier, status)
 
 	switch x.hasBeenProcessed(identifier) {
 	// The control has not been processed yet, so we will increment the current status.
@@ -76,6 +83,26 @@ func (x *Calculator) ProcessFinding(finding *Finding, groupBy string) {
 	}
 }
 
+// recordResult adds the finding to the result of its control.
+func (x *Calculator) recordResult(record *finding.Finding, identifier string, status Status) {
+	result, ok := x.results[identifier]
+
+	if !ok {
+		result = &control.Result{Control: identifier, Title: record.Title, Status: control.StatusPassed}
+		x.results[identifier] = result
+	}
+
+	result.FindingIds = append(result.FindingIds, record.Id)
+
+	if status == StatusFailed {
+		result.Status = control.StatusFailed
+
+		if record.ResourceId != "" {
+			result.FailingResources = append(result.FailingResources, record.ResourceId)
+		}
+	}
+}
+
 func (x *Calculator) Score() float64 {
 	if x.total == 0 {
 		return float64(100)
@@ -102,8 +129,8 @@ func (x *Calculator) hasBeenProcessed(identifier string) Status {
 	return StatusNotProcessed
 }
 
-func (x *Calculator) resolveStatus(finding *Finding) Status {
-	switch finding.Status {
+func (x *Calculator) resolveStatus(record *finding.Finding) Status {
+	switch record.Status {
 	case "FAILED":
 		return StatusFailed
 	case "WARNING":
@@ -128,6 +155,41 @@ func (x *Calculator) ControlPassedCount() int {
 	return x.ControlCount() - x.ControlFailedCount()
 }
 
+// FailedControls returns the identifiers of the failed controls, sorted.
+func (x *Calculator) FailedControls() []string {
+	controls := append([]string{}, x.processHistory[StatusFailed]...)
+	sort.Strings(controls)
+	return slices.Compact(controls)
+}
+
 func (x *Calculator) FindingCount() int {
 	return x.findings
 }
+
+// Results returns the result of every control, sorted by control. The expected controls without findings are included
+// with the NO_FINDINGS status.
+func (x *Calculator) Results() []control.Result {
+	var results []control.Result
+
+	for _, result := range x.results {
+		sort.Strings(result.FailingResources)
+		sort.Strings(result.FindingIds)
+		result.FailingResources = slices.Compact(result.FailingResources)
+		result.FindingIds = slices.Compact(result.FindingIds)
+		results = append(results, *result)
+	}
+
+	for _, expected := range x.expectedControls {
+		if _, ok := x.results[expected]; !ok {
+			results = append(results, control.Result{Control: expected, Status: control.StatusNoFindings})
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Control < results[j].Control
+	})
+
+	return slices.CompactFunc(results, func(a control.Result, b control.Result) bool {
+		return a.Control == b.Control
+	})
+}
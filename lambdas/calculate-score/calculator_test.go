@@ -3,18 +3,20 @@ package main
 import (
 	"github.com/aws/aws-sdk-go-v2/service/securityhub/types"
 	"github.com/stretchr/testify/assert"
+	"shared/control"
+	"shared/finding"
 	"testing"
 )
 
-func generateFinding(generatorId string, status types.ComplianceStatus) *Finding {
-	return &Finding{
+func generateFinding(generatorId string, status types.ComplianceStatus) *finding.Finding {
+	return &finding.Finding{
 		GeneratorId: generatorId,
 		Status:      string(status),
 	}
 }
 
-func generateFindingByTitle(title string, status types.ComplianceStatus) *Finding {
-	return &Finding{
+func generateFindingByTitle(title string, status types.ComplianceStatus) *finding.Finding {
+	return &finding.Finding{
 		Title:  title,
 		Status: string(status),
 	}
@@ -202,4 +204,28 @@ func TestCalculator(t *testing.T) {
 		assert.Equal(t, 2, calc.ControlFailedCount())
 		assert.Equal(t, 4, calc.FindingCount())
 	})
+
+	t.Run("Failed controls are listed once, sorted", func(t *testing.T) {
+		calc := NewCalculator([]string{})
+		calc.ProcessFinding(generateFinding("control-2", types.ComplianceStatusFailed), "GeneratorId")
+		calc.ProcessFinding(generateFinding("control-1", types.ComplianceStatusPassed), "GeneratorId")
+		calc.ProcessFinding(generateFinding("control-3", types.ComplianceStatusPassed), "GeneratorId")
+		calc.ProcessFinding(generateFinding("control-3", types.ComplianceStatusWarning), "GeneratorId")
+		calc.ProcessFinding(generateFinding("control-2", types.ComplianceStatusFailed), "GeneratorId")
+		assert.Equal(t, []string{"control-2", "control-3"}, calc.FailedControls())
+	})
+
+	t.Run("Results list the failing resources and findings of every control", func(t *testing.T) {
+		calc := NewCalculator([]string{"control-1", "control-2", "control-3"})
+		calc.ProcessFinding(&finding.Finding{Id: "finding-2", GeneratorId: "control-2", Title: "Control 2", Status: "PASSED", ResourceId: "bucket-a"}, "GeneratorId")
+		calc.ProcessFinding(&finding.Finding{Id: "finding-3", GeneratorId: "control-2", Title: "Control 2", Status: "FAILED", ResourceId: "bucket-b"}, "GeneratorId")
+		calc.ProcessFinding(&finding.Finding{Id: "finding-1", GeneratorId: "control-2", Title: "Control 2", Status: "WARNING", ResourceId: "bucket-b"}, "GeneratorId")
+		calc.ProcessFinding(&finding.Finding{Id: "finding-4", GeneratorId: "control-1", Title: "Control 1", Status: "PASSED", ResourceId: "bucket-a"}, "GeneratorId")
+
+		assert.Equal(t, []control.Result{
+			{Control: "control-1", Title: "Control 1", Status: control.StatusPassed, FindingIds: []string{"finding-4"}},
+			{Control: "control-2", Title: "Control 2", Status: control.StatusFailed, FailingResources: []string{"bucket-b"}, FindingIds: []string{"finding-1", "finding-2", "finding-3"}},
+			{Control: "control-3", Status: control.StatusNoFindings},
+		}, calc.Results())
+	})
 }
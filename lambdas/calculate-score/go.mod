@@ -3,16 +3,17 @@ module calculate-score
 go 1.21
 
 require (
-	github.com/aws/aws-lambda-go v1.46.0
 	github.com/aws/aws-sdk-go-v2 v1.25.1
 	github.com/aws/aws-sdk-go-v2/config v1.27.2
 	github.com/aws/aws-sdk-go-v2/service/s3 v1.50.3
-	github.com/aws/aws-sdk-go-v2/service/securityhub v1.43.2
+	github.com/aws/aws-sdk-go-v2/service/securityhub v1.45.2
 	github.com/awsdocs/aws-doc-sdk-examples/gov2/testtools v0.0.0-20230929172021-f4d62c78cc98
 	github.com/stretchr/testify v1.8.4
+	shared v0.0.0
 )
 
 require (
+	github.com/aws/aws-lambda-go v1.46.0 // indirect
 	github.com/aws/aws-sdk-go-v2/aws/protocol/eventstream v1.6.1 // indirect
 	github.com/aws/aws-sdk-go-v2/credentials v1.17.2 // indirect
 	github.com/aws/aws-sdk-go-v2/feature/ec2/imds v1.15.1 // indirect
@@ -32,3 +33,5 @@ require (
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
+
+replace shared => ../../shared
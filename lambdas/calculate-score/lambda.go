@@ -4,39 +4,78 @@ import (
 	"context"
 	"encoding/json"
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/service/s3"
-	"io"
+	"github.com/aws/aws-sdk-go-v2/service/securityhub"
+	"github.com/aws/aws-sdk-go-v2/service/securityhub/types"
 	"log"
+	"shared/artifact"
+	"shared/blobstore"
+	"shared/control"
+	"shared/finding"
+	"shared/score"
 )
 
 type Lambda struct {
-	ctx      context.Context
-	s3Client *s3.Client
+	ctx               context.Context
+	store             blobstore.BlobStore
+	securityHubClient *securityhub.Client
 }
 
 func New(cfg aws.Config) *Lambda {
 	m := new(Lambda)
-	m.s3Client = s3.NewFromConfig(cfg)
+	m.store = blobstore.NewFromConfig(cfg)
+	m.securityHubClient = securityhub.NewFromConfig(cfg)
 	return m
 }
 
 func (x *Lambda) Handler(ctx context.Context, request Request) (Response, error) {
 	response := Response{
-		AccountId:   request.AccountId,
-		AccountName: request.AccountName,
-		Workload:    request.Workload,
-		Environment: request.Environment,
-		Score:       0,
+		AccountId:          request.AccountId,
+		AccountName:        request.AccountName,
+		Workload:           request.Workload,
+		Environment:        request.Environment,
+		OrganizationalUnit: request.OrganizationalUnit,
+		Owner:              request.Owner,
+		Score:              0,
+		Dimensions:         request.Dimensions,
 	}
 
-	if request.Bucket == "" || request.Key == "" || request.Controls == "" {
+	x.ctx = ctx
+
+	if request.Excluded {
+		log.Printf("Account %s is excluded by the classification file", request.AccountId)
+		response.Status = score.StatusExcluded
 		return response, nil
 	}
 
-	x.ctx = ctx
+	response.Baseline = request.Baseline
+	response, err := x.calculate(request, response)
+
+	// The score of a newly joined account is still calculated, but it is left out of the roll-ups until the grace
+	// period has passed, as Config and Security Hub are still evaluating the account.
+	if err == nil && request.Baseline != nil {
+		log.Printf("Account %s is baselining for another %d hours, status: %s", request.AccountId, request.Baseline.RemainingHours, response.Status)
+		response.Status = score.StatusBaselining
+	}
+
+	return response, err
+}
+
+// calculate scores the findings of the account, or resolves why there are none.
+func (x *Lambda) calculate(request Request, response Response) (Response, error) {
+	if request.Bucket == "" || request.Key == "" || request.Controls == "" {
+		status, err := x.resolveMissingStatus(request.AccountId)
+		response.Status = status
+
+		if err == nil {
+			log.Printf("No findings for %s, status: %s", request.AccountId, status)
+		}
+
+		return response, err
+	}
+
 	log.Printf("Calculating the security score for: %s", request.AccountId)
 
-	findings, err := x.downloadFindings(request.Bucket, request.Key)
+	findings, err := x.downloadFindings(request.Bucket, request.Key, request.Checksum)
 
 	if err != nil {
 		return response, err
@@ -49,10 +88,16 @@ func (x *Lambda) Handler(ctx context.Context, request Request) (Response, error)
 
 	calc := NewCalculator(controls)
 
-	for _, finding := range findings {
-		calc.ProcessFinding(finding, request.GroupBy)
+	for _, record := range findings {
+		calc.ProcessFinding(record, request.GroupBy)
 	}
 
+	if calc.FindingCount() == 0 {
+		response.Status = score.StatusNoFindings
+		return response, nil
+	}
+
+	response.Status = score.StatusScored
 	response.Score = calc.Score()
 	response.ControlCount = calc.ControlCount()
 	response.ControlFailedCount = calc.ControlFailedCount()
@@ -61,13 +106,77 @@ func (x *Lambda) Handler(ctx context.Context, request Request) (Response, error)
 	log.Printf("%d controls (%d Passed and %d Failed)", calc.total, calc.passed, calc.failed)
 	log.Printf("Compliance score is: %.2f%%", response.Score)
 
+	if request.Options.RecordsFailedControls() {
+		response.FailedControls, err = x.uploadFailedControls(request.Bucket, request.Key, calc.FailedControls())
+
+		if err != nil {
+			return response, err
+		}
+	}
+
+	if request.Options.RecordsControlResults() {
+		response.ControlResults, err = x.uploadControlResults(request.Bucket, request.Key, calc.Results())
+	}
+
 	return response, err
 }
 
+// uploadFailedControls writes the failed controls next to the findings of the account, the list is too large to pass
+// along in the payload for every account.
+func (x *Lambda) uploadFailedControls(bucket string, key string, controls []string) (string, error) {
+	data, err := json.Marshal(controls)
+
+	if err != nil {
+		return "", err
+	}
+
+	failedKey := control.FailedKey(key)
+	log.Printf("Uploading %d failed controls to %s", len(controls), failedKey)
+
+	return failedKey, x.store.Upload(x.ctx, bucket, failedKey, data)
+}
+
+// uploadControlResults writes the result of every control next to the findings of the account, for the export of the
+// report.
+func (x *Lambda) uploadControlResults(bucket string, key string, results []control.Result) (string, error) {
+	data, err := json.Marshal(results)
+
+	if err != nil {
+		return "", err
+	}
+
+	resultsKey := control.Key(key)
+	log.Printf("Uploading the results of %d controls to %s", len(results), resultsKey)
+
+	return resultsKey, x.store.Upload(x.ctx, bucket, resultsKey, data)
+}
+
+// resolveMissingStatus tells a new account apart from an account that did not enable the standard of the report, by
+// looking for any active Security Hub finding of the account.
+func (x *Lambda) resolveMissingStatus(accountId string) (score.Status, error) {
+	output, err := x.securityHubClient.GetFindings(x.ctx, &securityhub.GetFindingsInput{
+		Filters: &types.AwsSecurityFindingFilters{
+			AwsAccountId: []types.StringFilter{{Comparison: types.StringFilterComparisonEquals, Value: aws.String(accountId)}},
+			RecordState:  []types.StringFilter{{Comparison: types.StringFilterComparisonEquals, Value: aws.String("ACTIVE")}},
+		},
+		MaxResults: aws.Int32(1),
+	})
+
+	if err != nil {
+		return score.StatusError, err
+	}
+
+	if len(output.Findings) == 0 {
+		return score.StatusNoFindings, nil
+	}
+
+	return score.StatusStandardNotEnabled, nil
+}
+
 func (x *Lambda) downloadControls(bucket string, key string) ([]string, error) {
 	var controls []string
 
-	data, err := x.downloadFile(bucket, key)
+	data, err := x.store.Download(x.ctx, bucket, key)
 
 	if err != nil {
 		return controls, err
@@ -79,31 +188,25 @@ func (x *Lambda) downloadControls(bucket string, key string) ([]string, error) {
 	return controls, err
 }
 
-func (x *Lambda) downloadFindings(bucket string, key string) ([]*Finding, error) {
-	var findings []*Finding
-
-	data, err := x.downloadFile(bucket, key)
+func (x *Lambda) downloadFindings(bucket string, key string, checksum *artifact.Checksum) ([]*finding.Finding, error) {
+	data, err := x.store.Download(x.ctx, bucket, key)
 
 	if err != nil {
-		return []*Finding{}, err
+		return []*finding.Finding{}, err
 	}
 
-	err = json.Unmarshal(data, &findings)
-	log.Printf("Downloaded %d findings", len(findings))
+	err = checksum.VerifySum(key, artifact.Sum(data))
 
-	return findings, err
-}
+	if err != nil {
+		return []*finding.Finding{}, err
+	}
 
-func (x *Lambda) downloadFile(bucket string, key string) ([]byte, error) {
-	log.Printf("Downloading s3://%s/%s", bucket, key)
+	findings, err := finding.Decode(data)
 
-	response, err := x.s3Client.GetObject(x.ctx, &s3.GetObjectInput{
-		Bucket: aws.String(bucket),
-		Key:    aws.String(key),
-	})
 	if err != nil {
-		return nil, err
+		return findings, err
 	}
 
-	return io.ReadAll(response.Body)
+	log.Printf("Downloaded %d findings", len(findings))
+	return findings, checksum.VerifyRecords(key, len(findings))
 }
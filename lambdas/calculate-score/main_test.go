@@ -7,10 +7,17 @@ import (
 	"errors"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/securityhub"
+	"github.com/aws/aws-sdk-go-v2/service/securityhub/types"
 	"github.com/awsdocs/aws-doc-sdk-examples/gov2/testtools"
 	"github.com/stretchr/testify/assert"
 	"io"
 	"os"
+	"shared/artifact"
+	"shared/finding"
+	"shared/owner"
+	"shared/report"
+	"shared/score"
 	"testing"
 )
 
@@ -22,29 +29,46 @@ func readEvent(path string) Request {
 	return event
 }
 
-func readRawFindings(path string) []*Finding {
+func readRawFindings(path string) []*finding.Finding {
 	file, _ := os.ReadFile(path)
 
-	var findings []*Finding
+	var findings []*finding.Finding
 	_ = json.Unmarshal(file, &findings)
 
 	return findings
 }
 
-func streamFindingData(findings []*Finding) io.ReadCloser {
+func streamFindingData(findings []*finding.Finding) io.ReadCloser {
 	data, _ := json.Marshal(findings)
 	return io.NopCloser(bytes.NewReader(data))
 }
 
+func toChecksum(findings []*finding.Finding) *artifact.Checksum {
+	data, _ := json.Marshal(findings)
+	checksum := artifact.NewChecksum(data, len(findings))
+	return &checksum
+}
+
 func streamControls(controls []string) io.ReadCloser {
 	data, _ := json.Marshal(controls)
 	return io.NopCloser(bytes.NewReader(data))
 }
 
+func getFindingsInput(accountId string) *securityhub.GetFindingsInput {
+	return &securityhub.GetFindingsInput{
+		Filters: &types.AwsSecurityFindingFilters{
+			AwsAccountId: []types.StringFilter{{Comparison: types.StringFilterComparisonEquals, Value: aws.String(accountId)}},
+			RecordState:  []types.StringFilter{{Comparison: types.StringFilterComparisonEquals, Value: aws.String("ACTIVE")}},
+		},
+		MaxResults: aws.Int32(1),
+	}
+}
+
 func TestHandler(t *testing.T) {
 	ctx := context.Background()
 	event := readEvent("../../events/calculate-score.json")
 	source := readRawFindings("../../events/stripped-findings.json")
+	event.Checksum = toChecksum(source[0:4])
 
 	t.Run("Calculate score", func(t *testing.T) {
 		stubber := testtools.NewStubber()
@@ -69,6 +93,7 @@ func TestHandler(t *testing.T) {
 
 		assert.NoError(t, err)
 		assert.Equal(t, event.AccountId, response.AccountId)
+		assert.Equal(t, score.StatusScored, response.Status)
 		assert.Equal(t, float64(50), response.Score)
 		assert.Equal(t, 2, response.ControlCount)
 		assert.Equal(t, 4, response.FindingCount)
@@ -76,6 +101,95 @@ func TestHandler(t *testing.T) {
 		assert.Equal(t, event.Environment, response.Environment)
 	})
 
+	t.Run("Upload the failed controls", func(t *testing.T) {
+		stubber := testtools.NewStubber()
+		lambda := New(*stubber.SdkConfig)
+		stubber.Add(testtools.Stub{
+			OperationName: "GetObject",
+			Input:         &s3.GetObjectInput{Bucket: aws.String("my-sample-bucket"), Key: aws.String("aws-foundational-security-best-practices/111122223333/2023/08/13/111111111111.json")},
+			Output:        &s3.GetObjectOutput{Body: streamFindingData(source[0:4])},
+		})
+		stubber.Add(testtools.Stub{
+			OperationName: "GetObject",
+			Input:         &s3.GetObjectInput{Bucket: aws.String("my-sample-bucket"), Key: aws.String("aws-foundational-security-best-practices/controls/2023/08/13/dfcec91a-9380-11ee-b9d1-0242ac120002.json")},
+			Output:        &s3.GetObjectOutput{Body: streamControls([]string{})},
+		})
+		stubber.Add(testtools.Stub{
+			OperationName: "PutObject",
+			Input: &s3.PutObjectInput{
+				Bucket: aws.String("my-sample-bucket"),
+				Key:    aws.String("aws-foundational-security-best-practices/111122223333/2023/08/13/111111111111.failed-controls.json"),
+			},
+			Output:       &s3.PutObjectOutput{},
+			IgnoreFields: []string{"Body"},
+		})
+
+		request := event
+		request.Options.ControlMetrics = true
+
+		response, err := lambda.Handler(ctx, request)
+		testtools.ExitTest(stubber, t)
+
+		assert.NoError(t, err)
+		assert.Equal(t, score.StatusScored, response.Status)
+		assert.Equal(t, "aws-foundational-security-best-practices/111122223333/2023/08/13/111111111111.failed-controls.json", response.FailedControls)
+	})
+
+	t.Run("Upload the control results", func(t *testing.T) {
+		stubber := testtools.NewStubber()
+		lambda := New(*stubber.SdkConfig)
+		stubber.Add(testtools.Stub{
+			OperationName: "GetObject",
+			Input:         &s3.GetObjectInput{Bucket: aws.String("my-sample-bucket"), Key: aws.String("aws-foundational-security-best-practices/111122223333/2023/08/13/111111111111.json")},
+			Output:        &s3.GetObjectOutput{Body: streamFindingData(source[0:4])},
+		})
+		stubber.Add(testtools.Stub{
+			OperationName: "GetObject",
+			Input:         &s3.GetObjectInput{Bucket: aws.String("my-sample-bucket"), Key: aws.String("aws-foundational-security-best-practices/controls/2023/08/13/dfcec91a-9380-11ee-b9d1-0242ac120002.json")},
+			Output:        &s3.GetObjectOutput{Body: streamControls([]string{})},
+		})
+		stubber.Add(testtools.Stub{
+			OperationName: "PutObject",
+			Input: &s3.PutObjectInput{
+				Bucket: aws.String("my-sample-bucket"),
+				Key:    aws.String("aws-foundational-security-best-practices/111122223333/2023/08/13/111111111111.control-results.json"),
+			},
+			Output:       &s3.PutObjectOutput{},
+			IgnoreFields: []string{"Body"},
+		})
+
+		request := event
+		request.Options.Export = &report.Export{}
+
+		response, err := lambda.Handler(ctx, request)
+		testtools.ExitTest(stubber, t)
+
+		assert.NoError(t, err)
+		assert.Empty(t, response.FailedControls)
+		assert.Equal(t, "aws-foundational-security-best-practices/111122223333/2023/08/13/111111111111.control-results.json", response.ControlResults)
+	})
+
+	t.Run("Fail on checksum mismatch", func(t *testing.T) {
+		stubber := testtools.NewStubber()
+		lambda := New(*stubber.SdkConfig)
+		stubber.Add(testtools.Stub{
+			OperationName: "GetObject",
+			Input:         &s3.GetObjectInput{Bucket: aws.String("my-sample-bucket"), Key: aws.String("aws-foundational-security-best-practices/111122223333/2023/08/13/111111111111.json")},
+			Output:        &s3.GetObjectOutput{Body: streamFindingData(source[0:4])},
+		})
+
+		data, _ := json.Marshal(source[0:4])
+		request := event
+		request.Checksum = &artifact.Checksum{SHA256: artifact.Sum(data), Records: 5}
+
+		_, err := lambda.Handler(ctx, request)
+		testtools.ExitTest(stubber, t)
+
+		var integrityErr *artifact.IntegrityError
+		assert.True(t, errors.As(err, &integrityErr))
+		assert.Equal(t, "Records", integrityErr.Field)
+	})
+
 	t.Run("Fail on findings download", func(t *testing.T) {
 		stubber := testtools.NewStubber()
 		lambda := New(*stubber.SdkConfig)
@@ -111,9 +225,14 @@ func TestHandler(t *testing.T) {
 		testtools.ExitTest(stubber, t)
 	})
 
-	t.Run("No Bucket or Key", func(t *testing.T) {
+	t.Run("No findings", func(t *testing.T) {
 		stubber := testtools.NewStubber()
 		lambda := New(*stubber.SdkConfig)
+		stubber.Add(testtools.Stub{
+			OperationName: "GetFindings",
+			Input:         getFindingsInput("111122223333"),
+			Output:        &securityhub.GetFindingsOutput{},
+		})
 
 		eventModified := event
 		eventModified.Bucket = ""
@@ -122,10 +241,84 @@ func TestHandler(t *testing.T) {
 		response, err := lambda.Handler(ctx, eventModified)
 
 		assert.NoError(t, err)
+		assert.Equal(t, score.StatusNoFindings, response.Status)
 		assert.Equal(t, 0, int(response.Score))
 		assert.Equal(t, 0, response.ControlPassedCount)
 		assert.Equal(t, 0, response.ControlFailedCount)
 		assert.Equal(t, 0, response.ControlCount)
 		testtools.ExitTest(stubber, t)
 	})
+
+	t.Run("Standard not enabled", func(t *testing.T) {
+		stubber := testtools.NewStubber()
+		lambda := New(*stubber.SdkConfig)
+		stubber.Add(testtools.Stub{
+			OperationName: "GetFindings",
+			Input:         getFindingsInput("111122223333"),
+			Output:        &securityhub.GetFindingsOutput{Findings: []types.AwsSecurityFinding{{Id: aws.String("finding-1")}}},
+		})
+
+		eventModified := event
+		eventModified.Key = ""
+
+		response, err := lambda.Handler(ctx, eventModified)
+
+		assert.NoError(t, err)
+		assert.Equal(t, score.StatusStandardNotEnabled, response.Status)
+		testtools.ExitTest(stubber, t)
+	})
+
+	t.Run("Fail on resolving the status", func(t *testing.T) {
+		stubber := testtools.NewStubber()
+		lambda := New(*stubber.SdkConfig)
+		raiseErr := &testtools.StubError{Err: errors.New("failed")}
+		stubber.Add(testtools.Stub{
+			OperationName: "GetFindings",
+			Input:         getFindingsInput("111122223333"),
+			Error:         raiseErr,
+		})
+
+		eventModified := event
+		eventModified.Key = ""
+
+		response, err := lambda.Handler(ctx, eventModified)
+		testtools.VerifyError(err, raiseErr, t)
+		assert.Equal(t, score.StatusError, response.Status)
+		testtools.ExitTest(stubber, t)
+	})
+
+	t.Run("Baselining during the grace period", func(t *testing.T) {
+		stubber := testtools.NewStubber()
+		lambda := New(*stubber.SdkConfig)
+		stubber.Add(testtools.Stub{
+			OperationName: "GetFindings",
+			Input:         getFindingsInput("111122223333"),
+			Output:        &securityhub.GetFindingsOutput{},
+		})
+
+		eventModified := event
+		eventModified.Key = ""
+		eventModified.Baseline = &score.Baseline{Until: 1692525332, RemainingHours: 168}
+
+		response, err := lambda.Handler(ctx, eventModified)
+		assert.NoError(t, err)
+		assert.Equal(t, score.StatusBaselining, response.Status)
+		assert.Equal(t, eventModified.Baseline, response.Baseline)
+		testtools.ExitTest(stubber, t)
+	})
+
+	t.Run("Excluded by the classification file", func(t *testing.T) {
+		stubber := testtools.NewStubber()
+		lambda := New(*stubber.SdkConfig)
+
+		eventModified := event
+		eventModified.Excluded = true
+		eventModified.Owner = owner.Owner{Team: "payments", Email: "payments@example.com"}
+
+		response, err := lambda.Handler(ctx, eventModified)
+		assert.NoError(t, err)
+		assert.Equal(t, score.StatusExcluded, response.Status)
+		assert.Equal(t, eventModified.Owner, response.Owner)
+		testtools.ExitTest(stubber, t)
+	})
 }
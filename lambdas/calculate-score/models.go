@@ -1,34 +1,46 @@
 package main
 
-type Request struct {
-	AccountId   string `json:"AccountId"`
-	AccountName string `json:"AccountName"`
-	Workload    string `json:"Workload"`
-	Environment string `json:"Environment"`
-	Bucket      string `json:"Bucket"`
-	Key         string `json:"Key"`
-	GroupBy     string `json:"GroupBy"`
-	Controls    string `json:"Controls"`
-}
+import (
+	"shared/artifact"
+	"shared/dimension"
+	"shared/owner"
+	"shared/report"
+	"shared/score"
+)
 
-type Finding struct {
-	Id             string `json:"Id"`
-	Status         string `json:"Status"`
-	ProductArn     string `json:"ProductArn"`
-	GeneratorId    string `json:"GeneratorId"`
-	AwsAccountId   string `json:"AwsAccountId"`
-	AwsAccountName string `json:"AwsAccountName"`
-	Title          string `json:"Title"`
+type Request struct {
+	AccountId          string                `json:"AccountId"`
+	AccountName        string                `json:"AccountName"`
+	Workload           string                `json:"Workload"`
+	Environment        string                `json:"Environment"`
+	OrganizationalUnit string                `json:"OrganizationalUnit,omitempty"`
+	Owner              owner.Owner           `json:"Owner"`
+	Excluded           bool                  `json:"Excluded,omitempty"`
+	Baseline           *score.Baseline       `json:"Baseline,omitempty"`
+	Options            report.Options        `json:"Options"`
+	Bucket             string                `json:"Bucket"`
+	Key                string                `json:"Key"`
+	GroupBy            string                `json:"GroupBy"`
+	Controls           string                `json:"Controls"`
+	Checksum           *artifact.Checksum    `json:"Checksum"`
+	Dimensions         []dimension.Dimension `json:"Dimensions"`
 }
 
 type Response struct {
-	AccountId          string  `json:"AccountId"`
-	AccountName        string  `json:"AccountName"`
-	Workload           string  `json:"Workload"`
-	Environment        string  `json:"Environment"`
-	Score              float64 `json:"Score"`
-	ControlCount       int     `json:"ControlCount"`
-	FindingCount       int     `json:"FindingCount"`
-	ControlFailedCount int     `json:"ControlFailedCount"`
-	ControlPassedCount int     `json:"ControlPassedCount"`
+	AccountId          string                `json:"AccountId"`
+	AccountName        string                `json:"AccountName"`
+	Workload           string                `json:"Workload"`
+	Environment        string                `json:"Environment"`
+	OrganizationalUnit string                `json:"OrganizationalUnit,omitempty"`
+	Owner              owner.Owner           `json:"Owner"`
+	Status             score.Status          `json:"Status"`
+	Baseline           *score.Baseline       `json:"Baseline,omitempty"`
+	Score              float64               `json:"Score"`
+	ControlCount       int                   `json:"ControlCount"`
+	FindingCount       int                   `json:"FindingCount"`
+	ControlFailedCount int                   `json:"ControlFailedCount"`
+	ControlPassedCount int                   `json:"ControlPassedCount"`
+	FailedControls     string                `json:"FailedControls,omitempty"`
+	ControlResults     string                `json:"ControlResults,omitempty"`
+	Dimensions         []dimension.Dimension `json:"Dimensions"`
 }
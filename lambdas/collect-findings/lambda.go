@@ -1,32 +1,31 @@
 package main
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
-	"fmt"
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/securityhub"
 	"github.com/aws/aws-sdk-go-v2/service/securityhub/types"
-	"github.com/gofrs/uuid"
 	"log"
 	"os"
-	"path/filepath"
+	"shared/artifact"
+	"shared/blobstore"
+	"shared/finding"
+	"shared/layout"
 	"strconv"
 	"time"
 )
 
 type Lambda struct {
 	ctx               context.Context
-	s3Client          *s3.Client
+	store             blobstore.BlobStore
 	securityHubClient *securityhub.Client
 }
 
 func New(cfg aws.Config) *Lambda {
 	m := new(Lambda)
 	m.securityHubClient = securityhub.NewFromConfig(cfg)
-	m.s3Client = s3.NewFromConfig(cfg)
+	m.store = blobstore.NewFromConfig(cfg)
 	return m
 }
 
@@ -56,9 +55,12 @@ func (x *Lambda) Handler(ctx context.Context, request Request) (Response, error)
 
 	findings, err := json.Marshal(downloadedFindings.Findings)
 
-	objectKey := x.resolveBucketKey("raw", request.Report)
-	err = x.uploadFile(request.Bucket, objectKey, findings)
+	objectKey := layout.Unique(request.Report, "raw")
+	err = x.store.Upload(x.ctx, request.Bucket, objectKey, findings)
 	findingsReferenceList := append(request.Findings, objectKey)
+	checksums := request.Checksums.Merge(artifact.Checksums{
+		objectKey: artifact.NewChecksum(findings, len(downloadedFindings.Findings)),
+	})
 
 	return Response{
 		Report:   request.Report,
@@ -66,12 +68,15 @@ func (x *Lambda) Handler(ctx context.Context, request Request) (Response, error)
 		Filter:   request.Filter,
 		Controls: request.Controls,
 		GroupBy:  request.GroupBy,
+		SplitBy:  request.SplitBy,
+		Options:  request.Options,
 		// Add optional fields for the next iterations
 		Findings:           findingsReferenceList,
 		FindingCount:       len(findingsReferenceList),
 		AggregatedFindings: request.AggregatedFindings,
 		Timestamp:          time.Now().Unix(),
 		NextToken:          downloadedFindings.NextToken,
+		Checksums:          checksums,
 	}, err
 }
 
@@ -97,18 +102,10 @@ func (x *Lambda) downloadFindings(filter *types.AwsSecurityFindingFilters, token
 
 func (x *Lambda) resolveFindings(results *securityhub.GetFindingsOutput) (*DownloadedFinding, error) {
 	var nextToken string
-	var allFindings []*Finding
-
-	for _, finding := range results.Findings {
-		allFindings = append(allFindings, &Finding{
-			Id:             *finding.Id,
-			Status:         string(finding.Compliance.Status),
-			ProductArn:     *finding.ProductArn,
-			GeneratorId:    *finding.GeneratorId,
-			AwsAccountId:   *finding.AwsAccountId,
-			AwsAccountName: *finding.AwsAccountName,
-			Title:          *finding.Title,
-		})
+	var allFindings []*finding.Finding
+
+	for _, result := range results.Findings {
+		allFindings = append(allFindings, finding.FromSecurityHub(result))
 	}
 
 	if results.NextToken != nil {
@@ -120,29 +117,3 @@ func (x *Lambda) resolveFindings(results *securityhub.GetFindingsOutput) (*Downl
 		NextToken: nextToken,
 	}, nil
 }
-
-func (x *Lambda) uploadFile(bucket string, key string, data []byte) error {
-	log.Printf("Upload file to s3://%s/%s", bucket, key)
-
-	_, err := x.s3Client.PutObject(x.ctx, &s3.PutObjectInput{
-		Bucket: aws.String(bucket),
-		Key:    aws.String(key),
-		Body:   bytes.NewReader(data),
-	})
-
-	return err
-}
-
-func (x *Lambda) resolveBucketKey(prefix string, report string) string {
-	t := time.Now()
-	id, _ := uuid.NewV6()
-
-	return filepath.Join(
-		report,
-		prefix,
-		fmt.Sprintf("%d", t.Year()),
-		fmt.Sprintf("%02d", int(t.Month())),
-		fmt.Sprintf("%02d", t.Day()),
-		fmt.Sprintf("%s.json", id.String()),
-	)
-}
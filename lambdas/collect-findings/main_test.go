@@ -12,9 +12,10 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/securityhub/types"
 	"github.com/awsdocs/aws-doc-sdk-examples/gov2/testtools"
 	"github.com/stretchr/testify/assert"
-	"regexp"
-
 	"os"
+	"regexp"
+	"shared/artifact"
+	"shared/finding"
 	"testing"
 )
 
@@ -37,7 +38,7 @@ func readRawFindings(path string) []types.AwsSecurityFinding {
 
 func readStrippedFindings(path string) []byte {
 	file, _ := os.ReadFile(path)
-	var findings []*Finding
+	var findings []*finding.Finding
 	_ = json.Unmarshal(file, &findings)
 	data, _ := json.Marshal(findings)
 
@@ -115,6 +116,8 @@ func TestHandler(t *testing.T) {
 		if regex.FindAllString(response.Findings[0], -1) == nil {
 			t.Errorf("Unexpected object key: %s", response.Findings[0])
 		}
+
+		assert.Equal(t, artifact.NewChecksum(strippedFindings, 7), response.Checksums[response.Findings[0]])
 	})
 
 	t.Run("GetFindings raises error", func(t *testing.T) {
@@ -206,6 +209,7 @@ func TestLambdaFunctionLoop(t *testing.T) {
 		event.NextToken = response.NextToken
 		event.Findings = response.Findings
 		event.Timestamp = response.Timestamp
+		event.Checksums = response.Checksums
 
 		response, err = lambda.Handler(ctx, event)
 		testtools.ExitTest(stubber, t)
@@ -231,13 +235,15 @@ func TestLambdaFunctionLoop(t *testing.T) {
 		event.NextToken = response.NextToken
 		event.Findings = response.Findings
 		event.Timestamp = response.Timestamp
+		event.Checksums = response.Checksums
 
 		response, err = lambda.Handler(ctx, event)
 		testtools.ExitTest(stubber, t)
 		assert.NoError(t, err)
 		assert.Equal(t, event.Report, response.Report)
 		assert.Equal(t, event.Bucket, response.Bucket)
-
+		assert.Equal(t, 3, len(response.Checksums))
+		assert.Equal(t, 1, response.Checksums[response.Findings[2]].Records)
 	})
 
 	t.Run("GetFindings raises error with token", func(t *testing.T) {
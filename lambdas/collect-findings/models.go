@@ -1,6 +1,11 @@
 package main
 
-import "github.com/aws/aws-sdk-go-v2/service/securityhub/types"
+import (
+	"github.com/aws/aws-sdk-go-v2/service/securityhub/types"
+	"shared/artifact"
+	"shared/finding"
+	"shared/report"
+)
 
 type Request struct {
 	Report   string                          `json:"Report"`
@@ -8,13 +13,16 @@ type Request struct {
 	Controls string                          `json:"Controls"`
 	GroupBy  string                          `json:"GroupBy"`
 	Filter   types.AwsSecurityFindingFilters `json:"Filter"`
+	SplitBy  []string                        `json:"SplitBy"`
+	Options  report.Options                  `json:"Options"`
 
 	// Optional: the following 3 fields need to be here when
-	Findings           []string `json:"Findings"`
-	FindingCount       int      `json:"FindingCount"`
-	AggregatedFindings []string `json:"AggregatedFindings"`
-	NextToken          string   `json:"NextToken"`
-	Timestamp          int64    `json:"Timestamp"`
+	Findings           []string           `json:"Findings"`
+	FindingCount       int                `json:"FindingCount"`
+	AggregatedFindings []string           `json:"AggregatedFindings"`
+	NextToken          string             `json:"NextToken"`
+	Timestamp          int64              `json:"Timestamp"`
+	Checksums          artifact.Checksums `json:"Checksums"`
 }
 
 type Response struct {
@@ -23,24 +31,17 @@ type Response struct {
 	Controls           string                          `json:"Controls"`
 	GroupBy            string                          `json:"GroupBy"`
 	Filter             types.AwsSecurityFindingFilters `json:"Filter"`
+	SplitBy            []string                        `json:"SplitBy"`
+	Options            report.Options                  `json:"Options"`
 	Findings           []string                        `json:"Findings"`
 	FindingCount       int                             `json:"FindingCount"`
 	AggregatedFindings []string                        `json:"AggregatedFindings"`
 	NextToken          string                          `json:"NextToken"`
 	Timestamp          int64                           `json:"Timestamp"`
+	Checksums          artifact.Checksums              `json:"Checksums"`
 }
 
 type DownloadedFinding struct {
-	Findings  []*Finding `json:"Findings"`
+	Findings  []*finding.Finding `json:"Findings"`
 	NextToken string
 }
-
-type Finding struct {
-	Id             string `json:"Id"`
-	Status         string `json:"Status"`
-	ProductArn     string `json:"ProductArn"`
-	GeneratorId    string `json:"GeneratorId"`
-	AwsAccountId   string `json:"AwsAccountId"`
-	AwsAccountName string `json:"AwsAccountName"`
-	Title          string `json:"Title"`
-}
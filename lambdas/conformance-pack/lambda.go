@@ -1,31 +1,27 @@
 package main
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
-	"fmt"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/configservice"
-	"github.com/aws/aws-sdk-go-v2/service/s3"
-	"github.com/gofrs/uuid"
 	"log"
-	"path/filepath"
+	"shared/blobstore"
+	"shared/layout"
 	"sort"
 	"strings"
-	"time"
 )
 
 type Lambda struct {
-	ctx      context.Context
-	client   *configservice.Client
-	s3Client *s3.Client
+	ctx    context.Context
+	client *configservice.Client
+	store  blobstore.BlobStore
 }
 
 func New(cfg aws.Config) *Lambda {
 	m := new(Lambda)
 	m.client = configservice.NewFromConfig(cfg)
-	m.s3Client = s3.NewFromConfig(cfg)
+	m.store = blobstore.NewFromConfig(cfg)
 	return m
 }
 
@@ -34,9 +30,11 @@ func (x *Lambda) Handler(ctx context.Context, request Request) (Response, error)
 	response := Response{
 		Report:   request.Report,
 		Bucket:   request.Bucket,
-		Controls: x.resolveBucketKey("controls", request.Report),
+		Controls: layout.Unique(request.Report, "controls"),
 		GroupBy:  "Title",
 		Filter:   request.Filter,
+		SplitBy:  request.SplitBy,
+		Options:  request.Options,
 	}
 
 	log.Printf("Loading Conformance Pack Context: %s", request.ConformancePack)
@@ -47,7 +45,7 @@ func (x *Lambda) Handler(ctx context.Context, request Request) (Response, error)
 		return response, err
 	}
 
-	err = x.uploadFile(request.Bucket, response.Controls, controlsData)
+	err = x.store.Upload(x.ctx, request.Bucket, response.Controls, controlsData)
 	return response, err
 }
 
@@ -82,32 +80,3 @@ func (x *Lambda) resolveConfigRules(conformancePack string) ([]string, error) {
 
 	return controls, nil
 }
-
-func (x *Lambda) resolveBucketKey(prefix string, report string) string {
-	t := time.Now()
-	id, _ := uuid.NewV6()
-
-	return filepath.Join(
-		report,
-		prefix,
-		fmt.Sprintf("%d", t.Year()),
-		fmt.Sprintf("%02d", int(t.Month())),
-		fmt.Sprintf("%02d", t.Day()),
-		fmt.Sprintf("%s.json", id.String()),
-	)
-}
-
-func (x *Lambda) uploadFile(bucket string, key string, data []byte) error {
-	log.Printf("Upload file to s3://%s/%s", bucket, key)
-
-	_, err := x.s3Client.PutObject(x.ctx, &s3.PutObjectInput{
-		Bucket: aws.String(bucket),
-		Key:    aws.String(key),
-		Body:   bytes.NewReader(data),
-	})
-
-	viewData := string(data)
-	log.Printf(viewData)
-
-	return err
-}
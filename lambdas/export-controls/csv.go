@@ -0,0 +1,43 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+)
+
+// WriteCsv writes the account by control matrix of all accounts as CSV, with a header row. A split report has a column
+// per dimension it was split by.
+func WriteCsv(accounts []Account) ([]byte, error) {
+	var buffer bytes.Buffer
+	writer := csv.NewWriter(&buffer)
+	names := dimensionNames(accounts)
+
+	err := writer.Write(withSplitColumns(matrixHeader, names))
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, account := range accounts {
+		err = writer.WriteAll(matrixRows(account, names, joinLines))
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// WriteAll flushes the rows, without accounts the header is still buffered.
+	writer.Flush()
+	err = writer.Error()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+func joinLines(values []string) string {
+	return strings.Join(values, "\n")
+}
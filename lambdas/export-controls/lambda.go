@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"log"
+	"path"
+	"shared/blobstore"
+	"shared/control"
+	"shared/layout"
+	"strings"
+)
+
+// The formats a report can export to.
+const (
+	FormatCsv  = "CSV"
+	FormatXlsx = "XLSX"
+)
+
+type Lambda struct {
+	ctx   context.Context
+	store blobstore.BlobStore
+}
+
+func New(cfg aws.Config) *Lambda {
+	m := new(Lambda)
+	m.store = blobstore.NewFromConfig(cfg)
+	return m
+}
+
+func (x *Lambda) Handler(ctx context.Context, request Request) (Response, error) {
+	x.ctx = ctx
+	response := Response{Files: []string{}}
+	options := request.Options.Export
+
+	if options == nil {
+		log.Printf("Report %s does not export its controls", request.Report)
+		return response, nil
+	}
+
+	formats, err := resolveFormats(options.Formats)
+
+	if err != nil {
+		return response, err
+	}
+
+	var accounts []Account
+	for _, calculated := range request.Accounts {
+		account := Account{CalculatedScore: calculated}
+
+		if calculated.ControlResults != "" {
+			err = x.download(request.Bucket, calculated.ControlResults, &account.Results)
+
+			if err != nil {
+				return response, err
+			}
+		}
+
+		accounts = append(accounts, account)
+	}
+
+	sortAccounts(accounts)
+	prefix := layout.Folder(request.Report, "exports", request.Timestamp)
+
+	for _, format := range formats {
+		var data []byte
+		var key string
+
+		switch format {
+		case FormatCsv:
+			key = path.Join(prefix, "controls.csv")
+			data, err = WriteCsv(accounts)
+		case FormatXlsx:
+			key = path.Join(prefix, "controls.xlsx")
+			data, err = WriteXlsx(accounts)
+		}
+
+		if err != nil {
+			return response, fmt.Errorf("export %s: %w", format, err)
+		}
+
+		err = x.store.Upload(x.ctx, request.Bucket, key, data)
+
+		if err != nil {
+			return response, err
+		}
+
+		log.Printf("Exported the controls of %d accounts to %s", len(accounts), key)
+		response.Files = append(response.Files, key)
+	}
+
+	return response, nil
+}
+
+// resolveFormats validates the formats of the report, without formats both CSV and XLSX are written.
+func resolveFormats(formats []string) ([]string, error) {
+	if len(formats) == 0 {
+		return []string{FormatCsv, FormatXlsx}, nil
+	}
+
+	var resolved []string
+	for _, format := range formats {
+		switch strings.ToUpper(format) {
+		case FormatCsv:
+			resolved = append(resolved, FormatCsv)
+		case FormatXlsx:
+			resolved = append(resolved, FormatXlsx)
+		default:
+			return nil, fmt.Errorf("export format %s is not supported, expected %s or %s", format, FormatCsv, FormatXlsx)
+		}
+	}
+
+	return resolved, nil
+}
+
+func (x *Lambda) download(bucket string, key string, v *[]control.Result) error {
+	data, err := x.store.Download(x.ctx, bucket, key)
+
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, v)
+}
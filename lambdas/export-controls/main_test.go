@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/awsdocs/aws-doc-sdk-examples/gov2/testtools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+	"os"
+	"path/filepath"
+	"shared/blobstore/blobtest"
+	"shared/dimension"
+	"shared/layout"
+	"shared/report"
+	"testing"
+)
+
+func readEvent(path string) Request {
+	file, _ := os.ReadFile(path)
+
+	var event Request
+	_ = json.Unmarshal(file, &event)
+	return event
+}
+
+// newLocalLambda returns a lambda on the local storage backend, seeded with the control results of the event.
+func newLocalLambda(t *testing.T) (*Lambda, *blobtest.Store) {
+	store := blobtest.New(t)
+
+	store.Seed("aws-foundational-security-best-practices/accounts/2023/08/13/111122223333.control-results.json", `[
+		{"Control":"IAM.1","Title":"IAM policies should not allow full administrative privileges","Status":"FAILED",
+			"FailingResources":["arn:aws:iam::111122223333:policy/admin","arn:aws:iam::111122223333:policy/ops"],
+			"FindingIds":["finding-1","finding-2"]},
+		{"Control":"S3.1","Title":"S3 Block Public Access should be enabled","Status":"PASSED","FindingIds":["finding-3"]},
+		{"Control":"S3.2","Status":"NO_FINDINGS"}]`)
+	store.Seed("aws-foundational-security-best-practices/accounts/2023/08/13/333322221111.control-results.json", `[
+		{"Control":"IAM.1","Title":"IAM policies should not allow full administrative privileges","Status":"PASSED","FindingIds":["finding-4"]},
+		{"Control":"S3.1","Title":"S3 Block Public Access should be enabled","Status":"FAILED",
+			"FailingResources":["arn:aws:s3:::my-bucket"],"FindingIds":["finding-5"]}]`)
+
+	stubber := testtools.NewStubber()
+	lambda := New(*stubber.SdkConfig)
+	lambda.store = store
+	return lambda, store
+}
+
+func TestHandler(t *testing.T) {
+	ctx := context.Background()
+	event := readEvent("../../events/export-controls.json")
+	prefix := layout.Folder("aws-foundational-security-best-practices", "exports", 1691920532)
+
+	t.Run("Export the controls", func(t *testing.T) {
+		lambda, store := newLocalLambda(t)
+
+		response, err := lambda.Handler(ctx, event)
+		require.NoError(t, err)
+		assert.Equal(t, []string{prefix + "/controls.csv", prefix + "/controls.xlsx"}, response.Files)
+
+		assert.Equal(t, `Account Id,Account Name,Workload,Environment,Control,Title,Status,Failing Resources,Finding Ids
+111122223333,my-workload-production,my-workload,production,IAM.1,IAM policies should not allow full administrative privileges,FAILED,"arn:aws:iam::111122223333:policy/admin
+arn:aws:iam::111122223333:policy/ops","finding-1
+finding-2"
+111122223333,my-workload-production,my-workload,production,S3.1,S3 Block Public Access should be enabled,PASSED,,finding-3
+111122223333,my-workload-production,my-workload,production,S3.2,,NO_FINDINGS,,
+333322221111,my-workload-test,my-workload,test,IAM.1,IAM policies should not allow full administrative privileges,PASSED,,finding-4
+333322221111,my-workload-test,my-workload,test,S3.1,S3 Block Public Access should be enabled,FAILED,arn:aws:s3:::my-bucket,finding-5
+`, string(store.Read(prefix+"/controls.csv")))
+
+		workbook, err := excelize.OpenReader(bytes.NewReader(store.Read(prefix + "/controls.xlsx")))
+		require.NoError(t, err)
+		defer workbook.Close()
+
+		assert.Equal(t, []string{"Summary", "production", "test"}, workbook.GetSheetList())
+
+		summary, err := workbook.GetRows("Summary")
+		require.NoError(t, err)
+		assert.Equal(t, [][]string{
+			summaryHeader,
+			{"111122223333", "my-workload-production", "my-workload", "production", "SCORED", "66.67", "3", "1", "1", "1"},
+			{"444455556666", "payments-production", "payments", "production", "NO_FINDINGS"},
+			{"333322221111", "my-workload-test", "my-workload", "test", "SCORED", "50", "2", "1", "1", "0"},
+		}, summary)
+
+		production, err := workbook.GetRows("production")
+		require.NoError(t, err)
+		assert.Equal(t, 4, len(production))
+		assert.Equal(t, matrixHeader, production[0])
+		assert.Equal(t, "arn:aws:iam::111122223333:policy/admin\narn:aws:iam::111122223333:policy/ops", production[1][7])
+
+		test, err := workbook.GetRows("test")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"333322221111", "my-workload-test", "my-workload", "test", "S3.1", "S3 Block Public Access should be enabled", "FAILED", "arn:aws:s3:::my-bucket", "finding-5"}, test[2])
+	})
+
+	t.Run("Export a column per split dimension", func(t *testing.T) {
+		lambda, store := newLocalLambda(t)
+		store.Seed("split/111122223333.control-results.json", `[{"Control":"S3.1","Title":"S3 Block Public Access should be enabled","Status":"PASSED"}]`)
+
+		request := readEvent("../../events/export-controls.json")
+		request.Accounts = []*CalculatedScore{
+			{AccountId: "111122223333", AccountName: "my-workload-production", Workload: "my-workload", Environment: "production", Status: "SCORED", Score: 100,
+				ControlResults: "split/111122223333.control-results.json", Dimensions: []dimension.Dimension{{Name: "Region", Value: "eu-west-1"}}},
+			{AccountId: "111122223333", AccountName: "my-workload-production", Workload: "my-workload", Environment: "production", Status: "NO_FINDINGS",
+				Dimensions: []dimension.Dimension{{Name: "Region", Value: "us-east-1"}}},
+		}
+
+		_, err := lambda.Handler(ctx, request)
+		require.NoError(t, err)
+
+		assert.Equal(t, `Account Id,Account Name,Workload,Environment,Region,Control,Title,Status,Failing Resources,Finding Ids
+111122223333,my-workload-production,my-workload,production,eu-west-1,S3.1,S3 Block Public Access should be enabled,PASSED,,
+`, string(store.Read(prefix+"/controls.csv")))
+
+		workbook, err := excelize.OpenReader(bytes.NewReader(store.Read(prefix + "/controls.xlsx")))
+		require.NoError(t, err)
+		defer workbook.Close()
+
+		summary, err := workbook.GetRows("Summary")
+		require.NoError(t, err)
+		assert.Equal(t, [][]string{
+			{"Account Id", "Account Name", "Workload", "Environment", "Region", "Status", "Score", "Controls", "Passed", "Failed", "No Findings"},
+			{"111122223333", "my-workload-production", "my-workload", "production", "eu-west-1", "SCORED", "100", "1", "1", "0", "0"},
+			{"111122223333", "my-workload-production", "my-workload", "production", "us-east-1", "NO_FINDINGS"},
+		}, summary)
+
+		production, err := workbook.GetRows("production")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"111122223333", "my-workload-production", "my-workload", "production", "eu-west-1", "S3.1", "S3 Block Public Access should be enabled", "PASSED"}, production[1])
+	})
+
+	t.Run("Export only the CSV", func(t *testing.T) {
+		lambda, store := newLocalLambda(t)
+
+		request := readEvent("../../events/export-controls.json")
+		request.Options.Export.Formats = []string{"csv"}
+
+		response, err := lambda.Handler(ctx, request)
+		require.NoError(t, err)
+		assert.Equal(t, []string{prefix + "/controls.csv"}, response.Files)
+		assert.NoFileExists(t, filepath.Join(store.Root, blobtest.Bucket, prefix, "controls.xlsx"))
+	})
+
+	t.Run("Export the header without accounts", func(t *testing.T) {
+		lambda, store := newLocalLambda(t)
+
+		request := readEvent("../../events/export-controls.json")
+		request.Accounts = nil
+		request.Options.Export.Formats = []string{"CSV"}
+
+		_, err := lambda.Handler(ctx, request)
+		require.NoError(t, err)
+		assert.Equal(t, "Account Id,Account Name,Workload,Environment,Control,Title,Status,Failing Resources,Finding Ids\n", string(store.Read(prefix+"/controls.csv")))
+	})
+
+	t.Run("Unsupported format", func(t *testing.T) {
+		lambda, _ := newLocalLambda(t)
+
+		request := readEvent("../../events/export-controls.json")
+		request.Options.Export.Formats = []string{"PDF"}
+
+		_, err := lambda.Handler(ctx, request)
+		assert.EqualError(t, err, "export format PDF is not supported, expected CSV or XLSX")
+	})
+
+	t.Run("Skip a report without an export", func(t *testing.T) {
+		stubber := testtools.NewStubber()
+		lambda := New(*stubber.SdkConfig)
+
+		request := readEvent("../../events/export-controls.json")
+		request.Options = report.Options{}
+
+		response, err := lambda.Handler(ctx, request)
+		testtools.ExitTest(stubber, t)
+		assert.NoError(t, err)
+		assert.Empty(t, response.Files)
+	})
+}
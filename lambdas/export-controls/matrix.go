@@ -0,0 +1,146 @@
+package main
+
+import (
+	"math"
+	"shared/control"
+	"shared/score"
+	"sort"
+)
+
+// Account is an account of the export with the results of its controls, accounts of which the results were not recorded
+// have no results.
+type Account struct {
+	*CalculatedScore
+	Results []control.Result
+}
+
+// Counts returns the number of passed, failed and controls without findings of the account.
+func (a Account) Counts() (passed int, failed int, noFindings int) {
+	for _, result := range a.Results {
+		switch result.Status {
+		case control.StatusPassed:
+			passed++
+		case control.StatusFailed:
+			failed++
+		case control.StatusNoFindings:
+			noFindings++
+		}
+	}
+
+	return passed, failed, noFindings
+}
+
+// matrixHeader are the columns of the account by control matrix.
+var matrixHeader = []string{"Account Id", "Account Name", "Workload", "Environment", "Control", "Title", "Status", "Failing Resources", "Finding Ids"}
+
+// summaryHeader are the columns of the summary of the accounts.
+var summaryHeader = []string{"Account Id", "Account Name", "Workload", "Environment", "Status", "Score", "Controls", "Passed", "Failed", "No Findings"}
+
+// splitColumn is the index of the columns of the split dimensions, after the environment in both headers.
+const splitColumn = 4
+
+// dimensionNames returns the names of the dimensions the accounts were split by, in the order they are first seen. The
+// export has a column for each of them.
+func dimensionNames(accounts []Account) []string {
+	var names []string
+	seen := map[string]bool{}
+
+	for _, account := range accounts {
+		for _, split := range account.Dimensions {
+			if !seen[split.Name] {
+				seen[split.Name] = true
+				names = append(names, split.Name)
+			}
+		}
+	}
+
+	return names
+}
+
+// withSplitColumns inserts the values of the split dimensions after the environment.
+func withSplitColumns[T any](values []T, splits []T) []T {
+	if len(splits) == 0 {
+		return values
+	}
+
+	columns := make([]T, 0, len(values)+len(splits))
+	columns = append(columns, values[:splitColumn]...)
+	columns = append(columns, splits...)
+	return append(columns, values[splitColumn:]...)
+}
+
+// splitValues returns the values of the named dimensions of the account, a dimension the account was not split by is
+// left empty.
+func splitValues(account Account, names []string) []string {
+	values := make([]string, len(names))
+
+	for i, name := range names {
+		for _, split := range account.Dimensions {
+			if split.Name == name {
+				values[i] = split.Value
+			}
+		}
+	}
+
+	return values
+}
+
+// sortAccounts sorts the accounts by environment and account id, the order of the rows of the export.
+func sortAccounts(accounts []Account) {
+	sort.SliceStable(accounts, func(i, j int) bool {
+		if accounts[i].Environment != accounts[j].Environment {
+			return accounts[i].Environment < accounts[j].Environment
+		}
+		return accounts[i].AccountId < accounts[j].AccountId
+	})
+}
+
+// matrixRows returns a row for every control of the account, multiple resources and findings are listed one per line.
+func matrixRows(account Account, names []string, join func([]string) string) [][]string {
+	rows := make([][]string, 0, len(account.Results))
+	splits := splitValues(account, names)
+
+	for _, result := range account.Results {
+		rows = append(rows, withSplitColumns([]string{
+			account.AccountId,
+			account.AccountName,
+			account.Workload,
+			account.Environment,
+			result.Control,
+			result.Title,
+			string(result.Status),
+			join(result.FailingResources),
+			join(result.FindingIds),
+		}, splits))
+	}
+
+	return rows
+}
+
+// summaryRow returns the status and control counts of the account, the score is left empty when the controls of the
+// account were not recorded.
+func summaryRow(account Account, names []string) []any {
+	row := []any{account.AccountId, account.AccountName, account.Workload, account.Environment, string(score.Resolve(account.Status))}
+
+	if account.ControlResults == "" {
+		row = append(row, "", "", "", "", "")
+	} else {
+		passed, failed, noFindings := account.Counts()
+		row = append(row, roundScore(account.Score), len(account.Results), passed, failed, noFindings)
+	}
+
+	return withSplitColumns(row, cells(splitValues(account, names)))
+}
+
+func roundScore(value float64) float64 {
+	return math.Round(value*100) / 100
+}
+
+// environmentName returns the environment of the account, accounts without an environment are grouped under None.
+func environmentName(account Account) string {
+	if account.Environment == "" {
+		return "None"
+	}
+
+	return account.Environment
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"shared/dimension"
+	"shared/report"
+	"shared/score"
+)
+
+// CalculatedScore is the part of the result of calculate-score the export is written from.
+type CalculatedScore struct {
+	AccountId      string       `json:"AccountId"`
+	AccountName    string       `json:"AccountName"`
+	Workload       string       `json:"Workload"`
+	Environment    string       `json:"Environment"`
+	Status         score.Status `json:"Status"`
+	Score          float64      `json:"Score"`
+	ControlResults string       `json:"ControlResults,omitempty"`
+	// Dimensions are the values the score of a split report was calculated for.
+	Dimensions []dimension.Dimension `json:"Dimensions"`
+}
+
+type Request struct {
+	Report    string             `json:"Report"`
+	Timestamp int64              `json:"Timestamp"`
+	Bucket    string             `json:"Bucket"`
+	Accounts  []*CalculatedScore `json:"Accounts"`
+	Options   report.Options     `json:"Options"`
+}
+
+type Response struct {
+	// Files are the keys of the written exports.
+	Files []string `json:"Files"`
+}
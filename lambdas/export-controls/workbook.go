@@ -0,0 +1,214 @@
+package main
+
+import (
+	"fmt"
+	"github.com/xuri/excelize/v2"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// SummarySheet is the first sheet of the workbook, with a row for every account.
+const SummarySheet = "Summary"
+
+// unsafeSheetCharacters are not allowed in the name of a sheet.
+var unsafeSheetCharacters = regexp.MustCompile(`[:\\/?*\[\]]+`)
+
+// matrixWidths and summaryWidths are the widths of the columns of the sheets, in characters, splitWidth is the width of
+// the column of a split dimension.
+var matrixWidths = []float64{15, 30, 20, 15, 60, 60, 12, 60, 60}
+var summaryWidths = []float64{15, 30, 20, 15, 22, 10, 10, 10, 10, 12}
+
+const splitWidth = 20
+
+// WriteXlsx writes a workbook with a summary sheet of all accounts, and a sheet per environment with the account by
+// control matrix of its accounts. A split report has a column per dimension it was split by on every sheet.
+func WriteXlsx(accounts []Account) ([]byte, error) {
+	file := excelize.NewFile()
+	defer file.Close()
+
+	splits := dimensionNames(accounts)
+	splitWidths := make([]float64, len(splits))
+	for i := range splitWidths {
+		splitWidths[i] = splitWidth
+	}
+
+	wrap, err := file.NewStyle(&excelize.Style{Alignment: &excelize.Alignment{Vertical: "top", WrapText: true}})
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = file.SetSheetName(file.GetSheetName(0), SummarySheet)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var summary [][]any
+	for _, account := range accounts {
+		summary = append(summary, summaryRow(account, splits))
+	}
+
+	err = writeSheet(file, SummarySheet, withSplitColumns(summaryHeader, splits), withSplitColumns(summaryWidths, splitWidths), summary, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var environments []string
+	byEnvironment := map[string][][]any{}
+
+	for _, account := range accounts {
+		environment := environmentName(account)
+
+		if _, ok := byEnvironment[environment]; !ok {
+			environments = append(environments, environment)
+		}
+
+		for _, row := range matrixRows(account, splits, joinCell) {
+			byEnvironment[environment] = append(byEnvironment[environment], cells(row))
+		}
+	}
+
+	// Sheet names are not case-sensitive, they are compared in lower case.
+	names := map[string]bool{strings.ToLower(SummarySheet): true}
+	for _, environment := range environments {
+		if len(byEnvironment[environment]) == 0 {
+			continue
+		}
+
+		name := sheetName(environment, names)
+		names[strings.ToLower(name)] = true
+
+		_, err = file.NewSheet(name)
+
+		if err != nil {
+			return nil, err
+		}
+
+		err = writeSheet(file, name, withSplitColumns(matrixHeader, splits), withSplitColumns(matrixWidths, splitWidths), byEnvironment[environment], wrap)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	buffer, err := file.WriteToBuffer()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// writeSheet writes the rows below a frozen header as a table, so they can be sorted and filtered.
+func writeSheet(file *excelize.File, sheet string, header []string, widths []float64, rows [][]any, style int) error {
+	writer, err := file.NewStreamWriter(sheet)
+
+	if err != nil {
+		return err
+	}
+
+	for i, width := range widths {
+		err = writer.SetColWidth(i+1, i+1, width)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	err = writer.SetPanes(&excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"})
+
+	if err != nil {
+		return err
+	}
+
+	err = writer.SetRow("A1", cells(header))
+
+	if err != nil {
+		return err
+	}
+
+	for i, row := range rows {
+		if style != 0 {
+			for j, value := range row {
+				row[j] = excelize.Cell{StyleID: style, Value: value}
+			}
+		}
+
+		err = writer.SetRow(fmt.Sprintf("A%d", i+2), row)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(rows) > 0 {
+		last, err := excelize.CoordinatesToCellName(len(header), len(rows)+1)
+
+		if err != nil {
+			return err
+		}
+
+		err = writer.AddTable(&excelize.Table{Range: "A1:" + last, StyleName: "TableStyleLight9"})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return writer.Flush()
+}
+
+// sheetName returns a valid name for the sheet of an environment that is not used yet. Sheet names are at most 31
+// characters, without :\/?*[] and not starting or ending with a quote.
+func sheetName(environment string, used map[string]bool) string {
+	base := strings.Trim(unsafeSheetCharacters.ReplaceAllString(environment, "-"), "'")
+	if base == "" {
+		base = "None"
+	}
+
+	name := truncate(base, excelize.MaxSheetNameLength)
+	for i := 2; used[strings.ToLower(name)]; i++ {
+		suffix := fmt.Sprintf(" (%d)", i)
+		name = truncate(base, excelize.MaxSheetNameLength-len(suffix)) + suffix
+	}
+
+	return name
+}
+
+// joinCell lists the values one per line, values that do not fit in a cell are counted instead.
+func joinCell(values []string) string {
+	lines := make([]string, 0, len(values))
+	length := 0
+
+	for i, value := range values {
+		remaining := fmt.Sprintf("… and %d more", len(values)-i)
+		length += utf8.RuneCountInString(value) + 1
+
+		// Keep room for the count of the values that do not fit.
+		if length+utf8.RuneCountInString(remaining) > excelize.TotalCellChars {
+			lines = append(lines, remaining)
+			break
+		}
+
+		lines = append(lines, value)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func truncate(value string, length int) string {
+	runes := []rune(value)
+	return string(runes[:min(length, len(runes))])
+}
+
+func cells(values []string) []any {
+	row := make([]any, len(values))
+	for i, value := range values {
+		row[i] = value
+	}
+	return row
+}
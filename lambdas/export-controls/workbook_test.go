@@ -0,0 +1,36 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/xuri/excelize/v2"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSheetName(t *testing.T) {
+	used := map[string]bool{"summary": true}
+
+	assert.Equal(t, "production", sheetName("production", used))
+	assert.Equal(t, "eu-prod-", sheetName("eu/prod*", used))
+	assert.Equal(t, "None", sheetName("''", used))
+	assert.Equal(t, "Summary (2)", sheetName("Summary", used))
+	assert.Equal(t, strings.Repeat("a", 31), sheetName(strings.Repeat("a", 40), used))
+
+	used[strings.Repeat("a", 31)] = true
+	assert.Equal(t, strings.Repeat("a", 27)+" (2)", sheetName(strings.Repeat("a", 40), used))
+}
+
+func TestJoinCell(t *testing.T) {
+	assert.Equal(t, "", joinCell(nil))
+	assert.Equal(t, "finding-1\nfinding-2", joinCell([]string{"finding-1", "finding-2"}))
+
+	values := make([]string, 1000)
+	for i := range values {
+		values[i] = strings.Repeat("f", 99)
+	}
+
+	joined := joinCell(values)
+	assert.LessOrEqual(t, utf8.RuneCountInString(joined), excelize.TotalCellChars)
+	assert.True(t, strings.HasSuffix(joined, "\n… and 673 more"), joined[len(joined)-20:])
+}
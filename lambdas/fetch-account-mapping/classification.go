@@ -0,0 +1,19 @@
+package main
+
+import (
+	"os"
+	"shared/classification"
+)
+
+// loadClassification returns the classification file from CLASSIFICATION_KEY in CLASSIFICATION_BUCKET, or nil when it
+// is not configured. It is read once per run, workload-context gets the override of its account with the account.
+func (x *Lambda) loadClassification() (*classification.File, error) {
+	bucket := os.Getenv("CLASSIFICATION_BUCKET")
+	key := os.Getenv("CLASSIFICATION_KEY")
+
+	if bucket == "" || key == "" {
+		return nil, nil
+	}
+
+	return classification.Load(x.ctx, x.store, bucket, key)
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/organizations"
+	"github.com/aws/aws-sdk-go-v2/service/organizations/types"
+	"log"
+	"os"
+	"shared/inventory"
+	"time"
+)
+
+const (
+	defaultInventoryTTL = time.Hour
+	// rootPath is the OU path of an account that is placed directly below the organization root.
+	rootPath = "Root"
+)
+
+// loadInventory returns the account inventory from the bucket, it is refreshed from Organizations when it is missing
+// or older than INVENTORY_TTL. The other reports of the same schedule reuse the refreshed snapshot.
+func (x *Lambda) loadInventory(bucket string) (*inventory.Snapshot, error) {
+	ttl, err := resolveInventoryTTL()
+
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot, err := inventory.Load(x.ctx, x.store, bucket)
+
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if snapshot != nil && !snapshot.Stale(ttl, now) {
+		log.Printf("Using the account inventory of %s", time.Unix(snapshot.Timestamp, 0).UTC().Format(time.RFC3339))
+		return snapshot, nil
+	}
+
+	log.Printf("Refreshing the account inventory")
+	accounts, err := x.resolveAccounts()
+
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot = inventory.New(accounts, now)
+	return snapshot, inventory.Save(x.ctx, x.store, bucket, snapshot)
+}
+
+func resolveInventoryTTL() (time.Duration, error) {
+	value := os.Getenv("INVENTORY_TTL")
+
+	if value == "" {
+		return defaultInventoryTTL, nil
+	}
+
+	return time.ParseDuration(value)
+}
+
+// resolveAccounts reads every account of the organization, with its tags and OU path.
+func (x *Lambda) resolveAccounts() ([]inventory.Account, error) {
+	paginator := organizations.NewListAccountsPaginator(x.client, &organizations.ListAccountsInput{
+		MaxResults: aws.Int32(20),
+	})
+
+	var accounts []inventory.Account
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(x.ctx)
+		if err != nil {
+			return accounts, err
+		}
+		for _, account := range output.Accounts {
+			var joined int64
+			if account.JoinedTimestamp != nil {
+				joined = account.JoinedTimestamp.Unix()
+			}
+			accounts = append(accounts, inventory.Account{
+				Id:              aws.ToString(account.Id),
+				Name:            aws.ToString(account.Name),
+				Status:          string(account.Status),
+				JoinedTimestamp: joined,
+			})
+		}
+	}
+
+	paths := map[string]string{}
+	for i := range accounts {
+		tags, err := x.resolveTags(accounts[i].Id)
+
+		if err != nil {
+			return accounts, err
+		}
+
+		path, err := x.resolveOrganizationalUnitPath(accounts[i].Id, paths)
+
+		if err != nil {
+			return accounts, err
+		}
+
+		accounts[i].Tags = tags
+		accounts[i].OrganizationalUnit = path
+	}
+
+	return accounts, nil
+}
+
+// resolveTags returns the tags of the account, workload-context prefers these over the account name.
+func (x *Lambda) resolveTags(accountId string) (map[string]string, error) {
+	paginator := organizations.NewListTagsForResourcePaginator(x.client, &organizations.ListTagsForResourceInput{
+		ResourceId: aws.String(accountId),
+	})
+
+	tags := map[string]string{}
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(x.ctx)
+		if err != nil {
+			return tags, err
+		}
+		for _, tag := range output.Tags {
+			tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+		}
+	}
+
+	return tags, nil
+}
+
+// resolveOrganizationalUnitPath walks up from a child to the root, for example `Workloads/Prod`. The paths of the
+// OUs are remembered in paths, so every OU is only described once.
+func (x *Lambda) resolveOrganizationalUnitPath(childId string, paths map[string]string) (string, error) {
+	output, err := x.client.ListParents(x.ctx, &organizations.ListParentsInput{
+		ChildId: aws.String(childId),
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	if len(output.Parents) == 0 || output.Parents[0].Type == types.ParentTypeRoot {
+		return rootPath, nil
+	}
+
+	unitId := aws.ToString(output.Parents[0].Id)
+	if path, ok := paths[unitId]; ok {
+		return path, nil
+	}
+
+	unit, err := x.client.DescribeOrganizationalUnit(x.ctx, &organizations.DescribeOrganizationalUnitInput{
+		OrganizationalUnitId: aws.String(unitId),
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	parentPath, err := x.resolveOrganizationalUnitPath(unitId, paths)
+
+	if err != nil {
+		return "", err
+	}
+
+	path := aws.ToString(unit.OrganizationalUnit.Name)
+	if parentPath != rootPath {
+		path = parentPath + "/" + path
+	}
+
+	paths[unitId] = path
+	return path, nil
+}
@@ -2,18 +2,38 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/organizations"
+	"github.com/aws/aws-sdk-go-v2/service/organizations/types"
+	"github.com/aws/aws-sdk-go-v2/service/securityhub"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"log"
+	"shared/blobstore"
+	"shared/dimension"
+	"shared/inventory"
+	"shared/layout"
+	"shared/membership"
+	"shared/score"
+	"sort"
+	"strings"
 )
 
 type Lambda struct {
-	ctx    context.Context
-	client *organizations.Client
+	ctx               context.Context
+	client            *organizations.Client
+	securityHubClient *securityhub.Client
+	stsClient         *sts.Client
+	store             blobstore.BlobStore
 }
 
 func New(cfg aws.Config) *Lambda {
 	m := new(Lambda)
 	m.client = organizations.NewFromConfig(cfg)
+	m.securityHubClient = securityhub.NewFromConfig(cfg)
+	m.stsClient = sts.NewFromConfig(cfg)
+	m.store = blobstore.NewFromConfig(cfg)
 	return m
 }
 
@@ -25,57 +45,232 @@ func (x *Lambda) Handler(ctx context.Context, request Request) (Response, error)
 		Bucket:    request.Bucket,
 		Timestamp: request.Timestamp,
 		Accounts:  []Account{},
+		Options:   request.Options,
 	}
 
+	handling, err := membership.ResolveHandling(request.Options.NonOrganizationAccounts)
+
+	if err != nil {
+		return response, err
+	}
+
+	response.NonOrganization = membership.Summary{Handling: handling}
 	controls := request.Accounts[0].Controls
-	mapping, err := x.resolveMapping()
+	snapshot, err := x.loadInventory(request.Bucket)
 
 	if err != nil {
 		return response, err
 	}
 
-	for accountId, accountName := range mapping {
+	mapping := snapshot.Mapping()
+	file, err := x.loadClassification()
+
+	if err != nil {
+		return response, err
+	}
+
+	members, err := x.resolveMembers()
+
+	if err != nil {
+		return response, err
+	}
+
+	hubAccountId, err := x.resolveHubAccountId()
+
+	if err != nil {
+		return response, err
+	}
+
+	var exclusions []Exclusion
+
+	for _, accountId := range sortedKeys(mapping) {
+		organizationAccount := mapping[accountId]
+		accountName := organizationAccount.Name
+		reason := x.resolveExclusionReason(organizationAccount, members, hubAccountId)
+
+		if reason != "" {
+			exclusions = append(exclusions, Exclusion{AccountId: accountId, AccountName: accountName, Reason: reason})
+			continue
+		}
+
+		baseline := score.NewBaseline(organizationAccount.JoinedTimestamp, request.Options.GracePeriodDays, request.Timestamp)
+
+		if baseline != nil {
+			log.Printf("Account %s joined recently, baselining for another %d hours", accountId, baseline.RemainingHours)
+		}
+
 		found := false
+		// An account has more than one entry when the findings are split by additional dimensions.
 		for _, account := range request.Accounts {
 			if account.AccountId == accountId {
 				if account.AccountName == "" {
 					account.AccountName = accountName
 				}
+				account.Tags = organizationAccount.Tags
+				account.OrganizationalUnit = organizationAccount.OrganizationalUnit
+				account.Membership = membership.Organization
+				account.Baseline = baseline
 				found = true
 				response.Accounts = append(response.Accounts, account)
-				break
 			}
 		}
 
 		if !found {
 			response.Accounts = append(response.Accounts, Account{
-				AccountId:   accountId,
-				AccountName: accountName,
-				Controls:    controls,
+				AccountId:          accountId,
+				AccountName:        accountName,
+				Controls:           controls,
+				Tags:               organizationAccount.Tags,
+				OrganizationalUnit: organizationAccount.OrganizationalUnit,
+				Membership:         membership.Organization,
+				Baseline:           baseline,
+			})
+		}
+	}
+
+	// Findings of accounts outside the organization are scored, tagged or dropped, as configured for the report.
+	counted := map[string]bool{}
+	for _, account := range request.Accounts {
+		if _, ok := mapping[account.AccountId]; ok {
+			continue
+		}
+
+		accountMembership := membership.Unknown
+		if _, ok := members[account.AccountId]; ok {
+			accountMembership = membership.Invited
+		}
+
+		if !counted[account.AccountId] {
+			counted[account.AccountId] = true
+			response.NonOrganization.Add(accountMembership)
+
+			if handling == membership.Drop {
+				exclusions = append(exclusions, Exclusion{
+					AccountId:   account.AccountId,
+					AccountName: account.AccountName,
+					Reason:      nonOrganizationReason(accountMembership),
+				})
+			}
+		}
+
+		if handling == membership.Drop {
+			continue
+		}
+
+		account.Membership = accountMembership
+		if handling == membership.Tag {
+			account.Dimensions = append(append([]dimension.Dimension{}, account.Dimensions...), dimension.Dimension{
+				Name:  dimension.Membership,
+				Value: string(accountMembership),
 			})
 		}
+		response.Accounts = append(response.Accounts, account)
 	}
 
-	return response, nil
+	// The options of the report are not part of the items of the Map state, so they are passed along with every account,
+	// as is the override of the account in the classification file.
+	for i := range response.Accounts {
+		response.Accounts[i].Override = file.Override(response.Accounts[i].AccountId)
+		response.Accounts[i].Options = request.Options
+	}
+
+	response.Exclusions, err = x.uploadExclusions(request, exclusions)
+
+	return response, err
 }
 
-func (x *Lambda) resolveMapping() (map[string]string, error) {
-	paginator := organizations.NewListAccountsPaginator(x.client, &organizations.ListAccountsInput{
-		MaxResults: aws.Int32(20),
+// resolveMembers returns the member status of every account that is known to the Security Hub administrator.
+func (x *Lambda) resolveMembers() (map[string]string, error) {
+	paginator := securityhub.NewListMembersPaginator(x.securityHubClient, &securityhub.ListMembersInput{
+		OnlyAssociated: aws.Bool(false),
+		MaxResults:     aws.Int32(50),
 	})
 
-	mapping := map[string]string{}
-	pageNum := 0
+	members := map[string]string{}
 	for paginator.HasMorePages() {
-		output, err := paginator.NextPage(context.TODO())
+		output, err := paginator.NextPage(x.ctx)
 		if err != nil {
-			return mapping, err
+			return members, err
 		}
-		for _, account := range output.Accounts {
-			mapping[*account.Id] = *account.Name
+		for _, member := range output.Members {
+			members[aws.ToString(member.AccountId)] = aws.ToString(member.MemberStatus)
 		}
-		pageNum++
 	}
 
-	return mapping, nil
+	return members, nil
+}
+
+// resolveHubAccountId returns the account the solution runs in, the Security Hub administrator is not a member of itself.
+func (x *Lambda) resolveHubAccountId() (string, error) {
+	output, err := x.stsClient.GetCallerIdentity(x.ctx, &sts.GetCallerIdentityInput{})
+
+	if err != nil {
+		return "", err
+	}
+
+	return aws.ToString(output.Account), nil
+}
+
+// resolveExclusionReason returns why an account is not scored, or an empty string when it is.
+func (x *Lambda) resolveExclusionReason(account inventory.Account, members map[string]string, hubAccountId string) string {
+	accountId := account.Id
+
+	if account.Status != string(types.AccountStatusActive) {
+		return fmt.Sprintf("Organizations status is %s", account.Status)
+	}
+
+	if accountId == hubAccountId {
+		return ""
+	}
+
+	status, ok := members[accountId]
+
+	if !ok {
+		return "Not a Security Hub member"
+	}
+
+	switch strings.ToUpper(status) {
+	case "ENABLED", "ASSOCIATED":
+		return ""
+	}
+
+	return fmt.Sprintf("Security Hub member status is %s", status)
+}
+
+func nonOrganizationReason(accountMembership membership.Membership) string {
+	if accountMembership == membership.Invited {
+		return "Invited Security Hub member, not part of the organization"
+	}
+
+	return "Not part of the organization"
+}
+
+func (x *Lambda) uploadExclusions(request Request, exclusions []Exclusion) (string, error) {
+	if exclusions == nil {
+		exclusions = []Exclusion{}
+	}
+
+	for _, exclusion := range exclusions {
+		log.Printf("Excluded %s (%s): %s", exclusion.AccountId, exclusion.AccountName, exclusion.Reason)
+	}
+
+	data, err := json.Marshal(exclusions)
+
+	if err != nil {
+		return "", err
+	}
+
+	key := layout.Timestamped(request.Report, "excluded", request.Timestamp)
+	return key, x.store.Upload(x.ctx, request.Bucket, key, data)
+}
+
+func sortedKeys(mapping map[string]inventory.Account) []string {
+	var keys []string
+
+	for key := range mapping {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+	return keys
 }
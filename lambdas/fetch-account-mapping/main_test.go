@@ -1,15 +1,29 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/organizations"
 	"github.com/aws/aws-sdk-go-v2/service/organizations/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3Types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/aws-sdk-go-v2/service/securityhub"
+	securityHubTypes "github.com/aws/aws-sdk-go-v2/service/securityhub/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
 	"github.com/awsdocs/aws-doc-sdk-examples/gov2/testtools"
 	"github.com/stretchr/testify/assert"
+	"io"
 	"os"
+	"shared/classification"
+	"shared/dimension"
+	"shared/inventory"
+	"shared/membership"
+	"shared/score"
+	"strings"
 	"testing"
+	"time"
 )
 
 func readEvent(path string) Request {
@@ -20,6 +34,116 @@ func readEvent(path string) Request {
 	return event
 }
 
+func addMembersStub(stubber *testtools.AwsmStubber, accountIds ...string) {
+	var members []securityHubTypes.Member
+
+	for _, accountId := range accountIds {
+		members = append(members, securityHubTypes.Member{
+			AccountId:    aws.String(accountId),
+			MemberStatus: aws.String("Enabled"),
+		})
+	}
+
+	stubber.Add(testtools.Stub{
+		OperationName: "ListMembers",
+		Input: &securityhub.ListMembersInput{
+			OnlyAssociated: aws.Bool(false),
+			MaxResults:     aws.Int32(50),
+		},
+		Output: &securityhub.ListMembersOutput{Members: members},
+	})
+}
+
+func addCallerIdentityStub(stubber *testtools.AwsmStubber) {
+	stubber.Add(testtools.Stub{
+		OperationName: "GetCallerIdentity",
+		Input:         &sts.GetCallerIdentityInput{},
+		Output:        &sts.GetCallerIdentityOutput{Account: aws.String("999999999999")},
+	})
+}
+
+func addTagsStub(stubber *testtools.AwsmStubber, accountId string, tags map[string]string) {
+	var resourceTags []types.Tag
+
+	for key, value := range tags {
+		resourceTags = append(resourceTags, types.Tag{Key: aws.String(key), Value: aws.String(value)})
+	}
+
+	stubber.Add(testtools.Stub{
+		OperationName: "ListTagsForResource",
+		Input: &organizations.ListTagsForResourceInput{
+			ResourceId: aws.String(accountId),
+		},
+		Output: &organizations.ListTagsForResourceOutput{Tags: resourceTags},
+	})
+}
+
+func addInventoryStubs(stubber *testtools.AwsmStubber, tags map[string]map[string]string, accounts []types.Account) {
+	stubber.Add(testtools.Stub{
+		OperationName: "GetObject",
+		Input:         &s3.GetObjectInput{Bucket: aws.String("my-sample-bucket"), Key: aws.String("inventory/accounts.json")},
+		Error:         &testtools.StubError{Err: &s3Types.NoSuchKey{}, ContinueAfter: true},
+	})
+	stubber.Add(testtools.Stub{
+		OperationName: "ListAccounts",
+		Input: &organizations.ListAccountsInput{
+			MaxResults: aws.Int32(20),
+		},
+		Output: &organizations.ListAccountsOutput{Accounts: accounts},
+	})
+
+	for _, account := range accounts {
+		addTagsStub(stubber, aws.ToString(account.Id), tags[aws.ToString(account.Id)])
+		addParentStub(stubber, aws.ToString(account.Id), "r-abcd", types.ParentTypeRoot)
+	}
+
+	stubber.Add(testtools.Stub{
+		OperationName: "PutObject",
+		Input: &s3.PutObjectInput{
+			Bucket: aws.String("my-sample-bucket"),
+			Key:    aws.String("inventory/accounts.json"),
+		},
+		Output:       &s3.PutObjectOutput{},
+		IgnoreFields: []string{"Body"},
+	})
+}
+
+func addParentStub(stubber *testtools.AwsmStubber, childId string, parentId string, parentType types.ParentType) {
+	stubber.Add(testtools.Stub{
+		OperationName: "ListParents",
+		Input:         &organizations.ListParentsInput{ChildId: aws.String(childId)},
+		Output: &organizations.ListParentsOutput{
+			Parents: []types.Parent{{Id: aws.String(parentId), Type: parentType}},
+		},
+	})
+}
+
+func addSnapshotStub(stubber *testtools.AwsmStubber, snapshot *inventory.Snapshot) {
+	data, _ := json.Marshal(snapshot)
+	stubber.Add(testtools.Stub{
+		OperationName: "GetObject",
+		Input:         &s3.GetObjectInput{Bucket: aws.String("my-sample-bucket"), Key: aws.String("inventory/accounts.json")},
+		Output:        &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(data))},
+	})
+}
+
+func addExclusionsStub(stubber *testtools.AwsmStubber, exclusions []Exclusion) {
+	if exclusions == nil {
+		exclusions = []Exclusion{}
+	}
+	data, _ := json.Marshal(exclusions)
+
+	stubber.Add(testtools.Stub{
+		OperationName: "PutObject",
+		Input: &s3.PutObjectInput{
+			Bucket: aws.String("my-sample-bucket"),
+			Key:    aws.String("aws-foundational-security-best-practices/excluded/2023/08/13/1691920532.json"),
+			Body:   bytes.NewReader(data),
+		},
+		Output: &s3.PutObjectOutput{},
+	})
+}
+
 func TestHandler(t *testing.T) {
 	ctx := context.Background()
 	event := readEvent("../../events/fetch-account-mapping.json")
@@ -28,37 +152,38 @@ func TestHandler(t *testing.T) {
 		stubber := testtools.NewStubber()
 		lambda := New(*stubber.SdkConfig)
 
-		stubber.Add(testtools.Stub{
-			OperationName: "ListAccounts",
-			Input: &organizations.ListAccountsInput{
-				MaxResults: aws.Int32(20),
+		addInventoryStubs(stubber, map[string]map[string]string{"111122223333": {"workload": "workload", "environment": "development"}}, []types.Account{
+			{
+				Id:     aws.String("111111111111"),
+				Status: types.AccountStatusActive,
+				Name:   aws.String("acme-workload-build"),
+			},
+			{
+				Id:     aws.String("111122223333"),
+				Status: types.AccountStatusActive,
+				Name:   aws.String("acme-workload-development"),
 			},
-			Output: &organizations.ListAccountsOutput{
-				Accounts: []types.Account{
-					{
-						Id:   aws.String("111111111111"),
-						Name: aws.String("acme-workload-build"),
-					},
-					{
-						Id:   aws.String("111122223333"),
-						Name: aws.String("acme-workload-development"),
-					},
-					{
-						Id:   aws.String("111111111113"),
-						Name: aws.String("acme-workload-test"),
-					},
-					{
-						Id:   aws.String("111111111114"),
-						Name: aws.String("acme-workload-acceptance"),
-					},
-					{
-						Id:   aws.String("111111111115"),
-						Name: aws.String("acme-workload-production"),
-					},
-				},
+			{
+				Id:     aws.String("111111111113"),
+				Status: types.AccountStatusActive,
+				Name:   aws.String("acme-workload-test"),
+			},
+			{
+				Id:     aws.String("111111111114"),
+				Status: types.AccountStatusActive,
+				Name:   aws.String("acme-workload-acceptance"),
+			},
+			{
+				Id:     aws.String("111111111115"),
+				Status: types.AccountStatusActive,
+				Name:   aws.String("acme-workload-production"),
 			},
 		})
 
+		addMembersStub(stubber, "111111111111", "111122223333", "111111111113", "111111111114", "111111111115")
+		addCallerIdentityStub(stubber)
+		addExclusionsStub(stubber, nil)
+
 		response, err := lambda.Handler(ctx, event)
 		testtools.ExitTest(stubber, t)
 		assert.NoError(t, err)
@@ -70,6 +195,7 @@ func TestHandler(t *testing.T) {
 			}
 			if account.AccountId == "111122223333" {
 				assert.Equal(t, "acme-workload-development", account.AccountName)
+				assert.Equal(t, map[string]string{"workload": "workload", "environment": "development"}, account.Tags)
 			}
 			if account.AccountId == "111111111113" {
 				assert.Equal(t, "acme-workload-test", account.AccountName)
@@ -82,4 +208,382 @@ func TestHandler(t *testing.T) {
 			}
 		}
 	})
+
+	t.Run("Keep every entry of a split account", func(t *testing.T) {
+		stubber := testtools.NewStubber()
+		lambda := New(*stubber.SdkConfig)
+
+		addInventoryStubs(stubber, nil, []types.Account{
+			{
+				Id:     aws.String("111122223333"),
+				Status: types.AccountStatusActive,
+				Name:   aws.String("acme-workload-development"),
+			},
+		})
+
+		addMembersStub(stubber, "111122223333")
+		addCallerIdentityStub(stubber)
+		addExclusionsStub(stubber, nil)
+
+		request := event
+		request.Accounts = []Account{
+			{AccountId: "111122223333", Dimensions: []dimension.Dimension{{Name: "Region", Value: "eu-west-1"}}},
+			{AccountId: "111122223333", Dimensions: []dimension.Dimension{{Name: "Region", Value: "eu-central-1"}}},
+		}
+
+		response, err := lambda.Handler(ctx, request)
+		testtools.ExitTest(stubber, t)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, len(response.Accounts))
+		assert.Equal(t, "acme-workload-development", response.Accounts[1].AccountName)
+		assert.Equal(t, "eu-central-1", response.Accounts[1].Dimensions[0].Value)
+	})
+
+	t.Run("Exclude suspended, non-member and unknown accounts", func(t *testing.T) {
+		stubber := testtools.NewStubber()
+		lambda := New(*stubber.SdkConfig)
+
+		addInventoryStubs(stubber, nil, []types.Account{
+			{
+				Id:     aws.String("999999999999"),
+				Status: types.AccountStatusActive,
+				Name:   aws.String("acme-security"),
+			},
+			{
+				Id:     aws.String("111122223333"),
+				Status: types.AccountStatusActive,
+				Name:   aws.String("acme-workload-development"),
+			},
+			{
+				Id:     aws.String("111111111113"),
+				Status: types.AccountStatusSuspended,
+				Name:   aws.String("acme-workload-test"),
+			},
+			{
+				Id:     aws.String("111111111114"),
+				Status: types.AccountStatusActive,
+				Name:   aws.String("acme-workload-acceptance"),
+			},
+		})
+		addMembersStub(stubber, "111122223333", "111111111113")
+		addCallerIdentityStub(stubber)
+		addExclusionsStub(stubber, []Exclusion{
+			{AccountId: "111111111113", AccountName: "acme-workload-test", Reason: "Organizations status is SUSPENDED"},
+			{AccountId: "111111111114", AccountName: "acme-workload-acceptance", Reason: "Not a Security Hub member"},
+			{AccountId: "444455556666", Reason: "Not part of the organization"},
+		})
+
+		request := event
+		request.Accounts = append(request.Accounts, Account{AccountId: "444455556666"})
+
+		response, err := lambda.Handler(ctx, request)
+		testtools.ExitTest(stubber, t)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, len(response.Accounts))
+		assert.Equal(t, "111122223333", response.Accounts[0].AccountId)
+		assert.Equal(t, "999999999999", response.Accounts[1].AccountId)
+		assert.Equal(t, "aws-foundational-security-best-practices/excluded/2023/08/13/1691920532.json", response.Exclusions)
+	})
+}
+
+func TestInventory(t *testing.T) {
+	ctx := context.Background()
+	event := readEvent("../../events/fetch-account-mapping.json")
+
+	t.Run("Reuse a fresh inventory", func(t *testing.T) {
+		stubber := testtools.NewStubber()
+		lambda := New(*stubber.SdkConfig)
+
+		addSnapshotStub(stubber, inventory.New([]inventory.Account{
+			{
+				Id:                 "111122223333",
+				Name:               "acme-workload-development",
+				Status:             "ACTIVE",
+				Tags:               map[string]string{"owner": "payments"},
+				OrganizationalUnit: "Workloads/NonProd",
+			},
+		}, time.Now().Add(-30*time.Minute)))
+		addMembersStub(stubber, "111122223333")
+		addCallerIdentityStub(stubber)
+		addExclusionsStub(stubber, nil)
+
+		response, err := lambda.Handler(ctx, event)
+		testtools.ExitTest(stubber, t)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, len(response.Accounts))
+		assert.Equal(t, "acme-workload-development", response.Accounts[0].AccountName)
+		assert.Equal(t, "payments", response.Accounts[0].Tags["owner"])
+		assert.Equal(t, "Workloads/NonProd", response.Accounts[0].OrganizationalUnit)
+	})
+
+	t.Run("Refresh a stale inventory", func(t *testing.T) {
+		_ = os.Setenv("INVENTORY_TTL", "15m")
+		defer func() { _ = os.Setenv("INVENTORY_TTL", "") }()
+
+		stubber := testtools.NewStubber()
+		lambda := New(*stubber.SdkConfig)
+
+		addSnapshotStub(stubber, inventory.New([]inventory.Account{
+			{Id: "111122223333", Name: "acme-old-name", Status: "ACTIVE"},
+		}, time.Now().Add(-30*time.Minute)))
+		stubber.Add(testtools.Stub{
+			OperationName: "ListAccounts",
+			Input:         &organizations.ListAccountsInput{MaxResults: aws.Int32(20)},
+			Output: &organizations.ListAccountsOutput{Accounts: []types.Account{
+				{Id: aws.String("111122223333"), Status: types.AccountStatusActive, Name: aws.String("acme-workload-development")},
+				{Id: aws.String("333322221111"), Status: types.AccountStatusActive, Name: aws.String("acme-workload-production")},
+			}},
+		})
+		addTagsStub(stubber, "111122223333", nil)
+		addParentStub(stubber, "111122223333", "ou-abcd-nonprod", types.ParentTypeOrganizationalUnit)
+		stubber.Add(testtools.Stub{
+			OperationName: "DescribeOrganizationalUnit",
+			Input:         &organizations.DescribeOrganizationalUnitInput{OrganizationalUnitId: aws.String("ou-abcd-nonprod")},
+			Output: &organizations.DescribeOrganizationalUnitOutput{
+				OrganizationalUnit: &types.OrganizationalUnit{Id: aws.String("ou-abcd-nonprod"), Name: aws.String("NonProd")},
+			},
+		})
+		addParentStub(stubber, "ou-abcd-nonprod", "ou-abcd-workloads", types.ParentTypeOrganizationalUnit)
+		stubber.Add(testtools.Stub{
+			OperationName: "DescribeOrganizationalUnit",
+			Input:         &organizations.DescribeOrganizationalUnitInput{OrganizationalUnitId: aws.String("ou-abcd-workloads")},
+			Output: &organizations.DescribeOrganizationalUnitOutput{
+				OrganizationalUnit: &types.OrganizationalUnit{Id: aws.String("ou-abcd-workloads"), Name: aws.String("Workloads")},
+			},
+		})
+		addParentStub(stubber, "ou-abcd-workloads", "r-abcd", types.ParentTypeRoot)
+		addTagsStub(stubber, "333322221111", nil)
+		// The path of the Workloads OU is known by now, it is not described again.
+		addParentStub(stubber, "333322221111", "ou-abcd-workloads", types.ParentTypeOrganizationalUnit)
+		stubber.Add(testtools.Stub{
+			OperationName: "PutObject",
+			Input:         &s3.PutObjectInput{Bucket: aws.String("my-sample-bucket"), Key: aws.String("inventory/accounts.json")},
+			Output:        &s3.PutObjectOutput{},
+			IgnoreFields:  []string{"Body"},
+		})
+		addMembersStub(stubber, "111122223333", "333322221111")
+		addCallerIdentityStub(stubber)
+		addExclusionsStub(stubber, nil)
+
+		response, err := lambda.Handler(ctx, event)
+		testtools.ExitTest(stubber, t)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, len(response.Accounts))
+		assert.Equal(t, "acme-workload-development", response.Accounts[0].AccountName)
+		assert.Equal(t, "Workloads/NonProd", response.Accounts[0].OrganizationalUnit)
+		assert.Equal(t, "Workloads", response.Accounts[1].OrganizationalUnit)
+	})
+
+	t.Run("Invalid TTL", func(t *testing.T) {
+		_ = os.Setenv("INVENTORY_TTL", "one hour")
+		defer func() { _ = os.Setenv("INVENTORY_TTL", "") }()
+
+		stubber := testtools.NewStubber()
+		lambda := New(*stubber.SdkConfig)
+
+		_, err := lambda.Handler(ctx, event)
+		testtools.ExitTest(stubber, t)
+		assert.Error(t, err)
+	})
+}
+
+func TestGracePeriod(t *testing.T) {
+	ctx := context.Background()
+	event := readEvent("../../events/fetch-account-mapping.json")
+	joined := time.Unix(event.Timestamp, 0).Add(-36 * time.Hour)
+
+	addStubs := func(stubber *testtools.AwsmStubber) {
+		addSnapshotStub(stubber, inventory.New([]inventory.Account{
+			{
+				Id:              "111122223333",
+				Name:            "acme-workload-development",
+				Status:          "ACTIVE",
+				JoinedTimestamp: joined.Unix(),
+			},
+		}, time.Now()))
+		addMembersStub(stubber, "111122223333")
+		addCallerIdentityStub(stubber)
+		addExclusionsStub(stubber, nil)
+	}
+
+	t.Run("Baseline a newly joined account", func(t *testing.T) {
+		stubber := testtools.NewStubber()
+		lambda := New(*stubber.SdkConfig)
+		addStubs(stubber)
+
+		request := event
+		request.Options.GracePeriodDays = 7
+
+		response, err := lambda.Handler(ctx, request)
+		testtools.ExitTest(stubber, t)
+		assert.NoError(t, err)
+		assert.Equal(t, &score.Baseline{Until: joined.Add(7 * 24 * time.Hour).Unix(), RemainingHours: 132}, response.Accounts[0].Baseline)
+	})
+
+	t.Run("Score once the grace period has passed", func(t *testing.T) {
+		stubber := testtools.NewStubber()
+		lambda := New(*stubber.SdkConfig)
+		addStubs(stubber)
+
+		request := event
+		request.Options.GracePeriodDays = 1
+
+		response, err := lambda.Handler(ctx, request)
+		testtools.ExitTest(stubber, t)
+		assert.NoError(t, err)
+		assert.Nil(t, response.Accounts[0].Baseline)
+	})
+
+	t.Run("Score without a grace period", func(t *testing.T) {
+		stubber := testtools.NewStubber()
+		lambda := New(*stubber.SdkConfig)
+		addStubs(stubber)
+
+		response, err := lambda.Handler(ctx, event)
+		testtools.ExitTest(stubber, t)
+		assert.NoError(t, err)
+		assert.Nil(t, response.Accounts[0].Baseline)
+	})
+}
+
+func addClassificationStub(stubber *testtools.AwsmStubber, body string) {
+	stubber.Add(testtools.Stub{
+		OperationName: "GetObject",
+		Input:         &s3.GetObjectInput{Bucket: aws.String("my-config-bucket"), Key: aws.String("classification.yaml")},
+		Output:        &s3.GetObjectOutput{Body: io.NopCloser(strings.NewReader(body))},
+	})
+}
+
+func TestClassificationFile(t *testing.T) {
+	ctx := context.Background()
+	event := readEvent("../../events/fetch-account-mapping.json")
+	_ = os.Setenv("CLASSIFICATION_BUCKET", "my-config-bucket")
+	_ = os.Setenv("CLASSIFICATION_KEY", "classification.yaml")
+	defer func() {
+		_ = os.Setenv("CLASSIFICATION_BUCKET", "")
+		_ = os.Setenv("CLASSIFICATION_KEY", "")
+	}()
+
+	snapshot := inventory.New([]inventory.Account{
+		{Id: "111122223333", Name: "acme-workload-development", Status: "ACTIVE"},
+		{Id: "333322221111", Name: "acme-workload-production", Status: "ACTIVE"},
+	}, time.Now())
+
+	t.Run("Pass the override of every account", func(t *testing.T) {
+		stubber := testtools.NewStubber()
+		lambda := New(*stubber.SdkConfig)
+		addSnapshotStub(stubber, snapshot)
+		addClassificationStub(stubber, `
+Version: 1
+Accounts:
+  "111122223333":
+    Workload: payments
+    Exclude: true
+`)
+		addMembersStub(stubber, "111122223333", "333322221111")
+		addCallerIdentityStub(stubber)
+		addExclusionsStub(stubber, nil)
+
+		response, err := lambda.Handler(ctx, event)
+		testtools.ExitTest(stubber, t)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, len(response.Accounts))
+		assert.Equal(t, &classification.Override{Workload: "payments", Exclude: true}, response.Accounts[0].Override)
+		assert.Nil(t, response.Accounts[1].Override)
+	})
+
+	t.Run("Unsupported version", func(t *testing.T) {
+		stubber := testtools.NewStubber()
+		lambda := New(*stubber.SdkConfig)
+		addSnapshotStub(stubber, snapshot)
+		addClassificationStub(stubber, `{"Version": 2, "Accounts": {}}`)
+
+		_, err := lambda.Handler(ctx, event)
+		testtools.ExitTest(stubber, t)
+		assert.ErrorContains(t, err, "version 2 is not supported")
+	})
+}
+
+func TestNonOrganizationAccounts(t *testing.T) {
+	ctx := context.Background()
+	event := readEvent("../../events/fetch-account-mapping.json")
+	event.Accounts = []Account{
+		{AccountId: "111122223333"},
+		{AccountId: "444455556666", Dimensions: []dimension.Dimension{{Name: "Region", Value: "eu-west-1"}}},
+		{AccountId: "444455556666", Dimensions: []dimension.Dimension{{Name: "Region", Value: "eu-central-1"}}},
+		{AccountId: "777788889999"},
+	}
+
+	addStubs := func(stubber *testtools.AwsmStubber, exclusions []Exclusion) {
+		addInventoryStubs(stubber, nil, []types.Account{
+			{Id: aws.String("111122223333"), Status: types.AccountStatusActive, Name: aws.String("acme-workload-development")},
+		})
+		addMembersStub(stubber, "111122223333", "444455556666")
+		addCallerIdentityStub(stubber)
+		addExclusionsStub(stubber, exclusions)
+	}
+
+	t.Run("Drop by default", func(t *testing.T) {
+		stubber := testtools.NewStubber()
+		lambda := New(*stubber.SdkConfig)
+		addStubs(stubber, []Exclusion{
+			{AccountId: "444455556666", Reason: "Invited Security Hub member, not part of the organization"},
+			{AccountId: "777788889999", Reason: "Not part of the organization"},
+		})
+
+		response, err := lambda.Handler(ctx, event)
+		testtools.ExitTest(stubber, t)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, len(response.Accounts))
+		assert.Equal(t, membership.Organization, response.Accounts[0].Membership)
+		assert.Equal(t, membership.Summary{Handling: membership.Drop, Invited: 1, Unknown: 1}, response.NonOrganization)
+	})
+
+	t.Run("Score", func(t *testing.T) {
+		stubber := testtools.NewStubber()
+		lambda := New(*stubber.SdkConfig)
+		addStubs(stubber, nil)
+
+		request := event
+		request.Options.NonOrganizationAccounts = membership.Score
+		response, err := lambda.Handler(ctx, request)
+		testtools.ExitTest(stubber, t)
+		assert.NoError(t, err)
+		assert.Equal(t, 4, len(response.Accounts))
+		assert.Equal(t, membership.Invited, response.Accounts[1].Membership)
+		assert.Equal(t, 1, len(response.Accounts[1].Dimensions))
+		assert.Equal(t, membership.Unknown, response.Accounts[3].Membership)
+		assert.Equal(t, membership.Summary{Handling: membership.Score, Invited: 1, Unknown: 1}, response.NonOrganization)
+	})
+
+	t.Run("Tag", func(t *testing.T) {
+		stubber := testtools.NewStubber()
+		lambda := New(*stubber.SdkConfig)
+		addStubs(stubber, nil)
+
+		request := event
+		request.Options.NonOrganizationAccounts = membership.Tag
+		response, err := lambda.Handler(ctx, request)
+		testtools.ExitTest(stubber, t)
+		assert.NoError(t, err)
+		assert.Equal(t, 4, len(response.Accounts))
+		assert.Equal(t, 0, len(response.Accounts[0].Dimensions))
+		assert.Equal(t, []dimension.Dimension{
+			{Name: "Region", Value: "eu-central-1"},
+			{Name: "Membership", Value: "INVITED"},
+		}, response.Accounts[2].Dimensions)
+		assert.Equal(t, []dimension.Dimension{{Name: "Membership", Value: "UNKNOWN"}}, response.Accounts[3].Dimensions)
+		assert.Equal(t, 1, len(event.Accounts[2].Dimensions), "the request is not modified")
+	})
+
+	t.Run("Unknown handling", func(t *testing.T) {
+		stubber := testtools.NewStubber()
+		lambda := New(*stubber.SdkConfig)
+
+		request := event
+		request.Options.NonOrganizationAccounts = "IGNORE"
+		_, err := lambda.Handler(ctx, request)
+		testtools.ExitTest(stubber, t)
+		assert.Error(t, err)
+	})
 }
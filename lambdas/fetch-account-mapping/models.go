@@ -1,24 +1,53 @@
 package main
 
+import (
+	"shared/artifact"
+	"shared/classification"
+	"shared/dimension"
+	"shared/membership"
+	"shared/report"
+	"shared/score"
+)
+
 type Request struct {
-	Report    string    `json:"Report"`
-	Timestamp int64     `json:"Timestamp"`
-	Bucket    string    `json:"Bucket"`
-	Accounts  []Account `json:"Accounts"`
+	Report    string         `json:"Report"`
+	Timestamp int64          `json:"Timestamp"`
+	Bucket    string         `json:"Bucket"`
+	Accounts  []Account      `json:"Accounts"`
+	Options   report.Options `json:"Options"`
 }
 
 type Account struct {
+	AccountId          string                   `json:"AccountId"`
+	AccountName        string                   `json:"AccountName"`
+	Bucket             string                   `json:"Bucket"`
+	Key                string                   `json:"Key"`
+	GroupBy            string                   `json:"GroupBy"`
+	Controls           string                   `json:"Controls"`
+	Checksum           *artifact.Checksum       `json:"Checksum"`
+	Dimensions         []dimension.Dimension    `json:"Dimensions"`
+	Tags               map[string]string        `json:"Tags,omitempty"`
+	OrganizationalUnit string                   `json:"OrganizationalUnit,omitempty"`
+	Override           *classification.Override `json:"Override,omitempty"`
+	Membership         membership.Membership    `json:"Membership,omitempty"`
+	Baseline           *score.Baseline          `json:"Baseline,omitempty"`
+	Options            report.Options           `json:"Options"`
+}
+
+// Exclusion records why an account is not scored, the exclusions of a run are written to the bucket.
+type Exclusion struct {
 	AccountId   string `json:"AccountId"`
 	AccountName string `json:"AccountName"`
-	Bucket      string `json:"Bucket"`
-	Key         string `json:"Key"`
-	GroupBy     string `json:"GroupBy"`
-	Controls    string `json:"Controls"`
+	Reason      string `json:"Reason"`
 }
 
 type Response struct {
-	Report    string    `json:"Report"`
-	Timestamp int64     `json:"Timestamp"`
-	Bucket    string    `json:"Bucket"`
-	Accounts  []Account `json:"Accounts"`
+	Report     string         `json:"Report"`
+	Timestamp  int64          `json:"Timestamp"`
+	Bucket     string         `json:"Bucket"`
+	Accounts   []Account      `json:"Accounts"`
+	Exclusions string         `json:"Exclusions"`
+	Options    report.Options `json:"Options"`
+	// NonOrganization counts the accounts outside the organization, it is published by publish-metrics.
+	NonOrganization membership.Summary `json:"NonOrganization"`
 }
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/securityhub/types"
+	"shared/dimension"
+	"strings"
+	"time"
+)
+
+const (
+	// maxDescription and maxProductField are the lengths Security Hub accepts for these fields of a finding.
+	maxDescription  = 1024
+	maxProductField = 2048
+)
+
+// FindingInput is everything a regression finding of an account is made of.
+type FindingInput struct {
+	Report         string
+	Region         string
+	Score          *CalculatedScore
+	Regression     *Regression
+	FailedControls []string
+	// CreatedAt is when the account regressed, UpdatedAt is the timestamp of the run.
+	CreatedAt int64
+	UpdatedAt int64
+}
+
+// FindingId is the same in every run, so an import updates the finding of the account instead of adding one. The key
+// is the dimension.Key of the score, a split account has a finding for every partition.
+func FindingId(report string, key string) string {
+	return fmt.Sprintf("aws-security-posture/%s/%s/score-regression", report, key)
+}
+
+// ProductArn is the default product of the account, which every account can import its own findings into.
+func ProductArn(region string, accountId string) string {
+	return fmt.Sprintf("arn:aws:securityhub:%s:%s:product/%s/default", region, accountId, accountId)
+}
+
+// NewFinding returns the active finding of a regressed account, it lists the controls the account fails.
+func NewFinding(input FindingInput) types.AwsSecurityFinding {
+	finding := baseFinding(input.Report, input.Region, input.Score, input.CreatedAt, input.UpdatedAt)
+	finding.RecordState = types.RecordStateActive
+	finding.Severity = &types.Severity{Label: input.Regression.Severity}
+	finding.Title = aws.String(fmt.Sprintf("Security score of %s regressed to %s%%", input.Report, formatScore(input.Score.Score)))
+	finding.Description = aws.String(describe(input))
+	finding.ProductFields["aws-security-posture/FailedControls"] = truncate(strings.Join(input.FailedControls, ","), maxProductField)
+	finding.Remediation = &types.Remediation{Recommendation: &types.Recommendation{
+		Text: aws.String("Resolve the failed controls of the account, the finding is archived once the score recovers."),
+		Url:  aws.String(fmt.Sprintf("https://%s.console.aws.amazon.com/securityhub/home?region=%s#/controls", input.Region, input.Region)),
+	}}
+
+	return finding
+}
+
+// ArchivedFinding returns the finding of an account of which the score recovered, Security Hub archives it on import.
+func ArchivedFinding(report string, region string, calculated *CalculatedScore, createdAt int64, updatedAt int64) types.AwsSecurityFinding {
+	finding := baseFinding(report, region, calculated, createdAt, updatedAt)
+	finding.RecordState = types.RecordStateArchived
+	finding.Severity = &types.Severity{Label: types.SeverityLabelInformational}
+	finding.Title = aws.String(fmt.Sprintf("Security score of %s recovered to %s%%", report, formatScore(calculated.Score)))
+	finding.Description = aws.String(fmt.Sprintf("The score of %s%% is no longer a regression.", formatScore(calculated.Score)))
+
+	return finding
+}
+
+func baseFinding(report string, region string, calculated *CalculatedScore, createdAt int64, updatedAt int64) types.AwsSecurityFinding {
+	finding := types.AwsSecurityFinding{
+		SchemaVersion: aws.String("2018-10-08"),
+		Id:            aws.String(FindingId(report, dimension.Key(calculated.AccountId, calculated.Dimensions))),
+		ProductArn:    aws.String(ProductArn(region, calculated.AccountId)),
+		GeneratorId:   aws.String("aws-security-posture/" + report),
+		AwsAccountId:  aws.String(calculated.AccountId),
+		Types:         []string{"Software and Configuration Checks/AWS Security Best Practices"},
+		CreatedAt:     aws.String(formatTime(createdAt)),
+		UpdatedAt:     aws.String(formatTime(updatedAt)),
+		Resources: []types.Resource{{
+			Type:      aws.String("AwsAccount"),
+			Id:        aws.String("AWS::::Account:" + calculated.AccountId),
+			Partition: types.PartitionAws,
+			Region:    aws.String(region),
+		}},
+		ProductFields: map[string]string{
+			"aws-security-posture/Report":      report,
+			"aws-security-posture/Score":       formatScore(calculated.Score),
+			"aws-security-posture/Workload":    calculated.Workload,
+			"aws-security-posture/Environment": calculated.Environment,
+		},
+	}
+
+	if len(calculated.Dimensions) > 0 {
+		var values []string
+		for _, d := range calculated.Dimensions {
+			values = append(values, d.Name+"="+d.Value)
+		}
+		finding.ProductFields["aws-security-posture/Dimensions"] = strings.Join(values, ",")
+	}
+
+	return finding
+}
+
+// describe lists the reasons of the regression and as many failed controls as fit in the description.
+func describe(input FindingInput) string {
+	description := strings.Join(input.Regression.Reasons, " ")
+
+	if len(input.FailedControls) == 0 {
+		return truncate(fmt.Sprintf("%s %d controls failed.", description, input.Score.ControlFailedCount), maxDescription)
+	}
+
+	return truncate(fmt.Sprintf("%s Failed controls: %s.", description, strings.Join(input.FailedControls, ", ")), maxDescription)
+}
+
+func truncate(value string, length int) string {
+	if len(value) <= length {
+		return value
+	}
+
+	return value[:length-3] + "..."
+}
+
+func formatTime(timestamp int64) string {
+	return time.Unix(timestamp, 0).UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/securityhub"
+	"github.com/aws/aws-sdk-go-v2/service/securityhub/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"log"
+	"os"
+	"shared/blobstore"
+	"shared/dimension"
+	"shared/score"
+	"shared/statefile"
+)
+
+// stateVersion is the newest version of the state format this function understands.
+const stateVersion = 1
+
+type Lambda struct {
+	ctx       context.Context
+	cfg       aws.Config
+	client    *securityhub.Client
+	stsClient *sts.Client
+	store     blobstore.BlobStore
+}
+
+func New(cfg aws.Config) *Lambda {
+	m := new(Lambda)
+	m.cfg = cfg
+	m.client = securityhub.NewFromConfig(cfg)
+	m.stsClient = sts.NewFromConfig(cfg)
+	m.store = blobstore.NewFromConfig(cfg)
+	return m
+}
+
+func (x *Lambda) Handler(ctx context.Context, request Request) (Response, error) {
+	x.ctx = ctx
+	response := Response{}
+	options := request.Options.Regressions
+
+	if options == nil {
+		log.Printf("Report %s does not import regressions", request.Report)
+		return response, nil
+	}
+
+	err := validateRegressions(options)
+
+	if err != nil {
+		return response, err
+	}
+
+	state, err := statefile.Load[State](x.ctx, x.store, request.Bucket, statefile.Key(request.Report, "regressions"), stateVersion)
+
+	if err != nil {
+		return response, err
+	}
+
+	if state.Accounts == nil {
+		state.Accounts = map[string]AccountState{}
+	}
+
+	identity, err := x.stsClient.GetCallerIdentity(x.ctx, &sts.GetCallerIdentityInput{})
+
+	if err != nil {
+		return response, err
+	}
+
+	hubAccountId := aws.ToString(identity.Account)
+	var errs []error
+
+	for _, calculated := range request.Accounts {
+		// Only a calculated score can regress, the state of the other accounts is kept for their next score.
+		if score.Resolve(calculated.Status) != score.StatusScored {
+			continue
+		}
+
+		key := dimension.Key(calculated.AccountId, calculated.Dimensions)
+		previous, known := state.Accounts[key]
+		next := AccountState{Score: calculated.Score, Timestamp: request.Timestamp}
+
+		var last *AccountState
+		if known {
+			last = &previous
+		}
+
+		switch regression := Detect(options, calculated, last); {
+		case regression != nil:
+			next.RegressedSince = request.Timestamp
+			if previous.RegressedSince != 0 {
+				next.RegressedSince = previous.RegressedSince
+			}
+
+			controls, err := x.failedControls(request.Bucket, calculated.FailedControls)
+
+			if err == nil {
+				err = x.importFinding(hubAccountId, NewFinding(FindingInput{
+					Report:         request.Report,
+					Region:         x.cfg.Region,
+					Score:          calculated,
+					Regression:     regression,
+					FailedControls: controls,
+					CreatedAt:      next.RegressedSince,
+					UpdatedAt:      request.Timestamp,
+				}))
+			}
+
+			if err != nil {
+				errs = append(errs, fmt.Errorf("account %s: %w", key, err))
+				continue
+			}
+
+			log.Printf("Imported the regression of account %s: %v", key, regression.Reasons)
+			response.Imported++
+		case previous.RegressedSince != 0:
+			err := x.importFinding(hubAccountId, ArchivedFinding(request.Report, x.cfg.Region, calculated, previous.RegressedSince, request.Timestamp))
+
+			if err != nil {
+				errs = append(errs, fmt.Errorf("account %s: %w", key, err))
+				continue
+			}
+
+			log.Printf("Archived the regression of account %s, the score recovered to %v", key, calculated.Score)
+			response.Archived++
+		}
+
+		state.Accounts[key] = next
+	}
+
+	// The state is saved for the accounts that were imported, a failed account is retried in the next run.
+	err = statefile.Save(x.ctx, x.store, request.Bucket, statefile.Key(request.Report, "regressions"), stateVersion, state)
+
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	return response, errors.Join(errs...)
+}
+
+// importFinding imports the finding into the Security Hub of its account. Security Hub only accepts findings of the
+// default product from the account itself, so the function assumes REGRESSION_ROLE_NAME in the other accounts.
+func (x *Lambda) importFinding(hubAccountId string, finding types.AwsSecurityFinding) error {
+	client := x.client
+	accountId := aws.ToString(finding.AwsAccountId)
+
+	if accountId != hubAccountId {
+		roleName := os.Getenv("REGRESSION_ROLE_NAME")
+
+		if roleName == "" {
+			return fmt.Errorf("importing a finding into account %s needs REGRESSION_ROLE_NAME", accountId)
+		}
+
+		output, err := x.stsClient.AssumeRole(x.ctx, &sts.AssumeRoleInput{
+			RoleArn:         aws.String(fmt.Sprintf("arn:aws:iam::%s:role/%s", accountId, roleName)),
+			RoleSessionName: aws.String("import-regressions"),
+		})
+
+		if err != nil {
+			return err
+		}
+
+		client = securityhub.NewFromConfig(x.cfg, func(o *securityhub.Options) {
+			o.Credentials = aws.NewCredentialsCache(credentials.NewStaticCredentialsProvider(
+				aws.ToString(output.Credentials.AccessKeyId),
+				aws.ToString(output.Credentials.SecretAccessKey),
+				aws.ToString(output.Credentials.SessionToken),
+			))
+		})
+	}
+
+	output, err := client.BatchImportFindings(x.ctx, &securityhub.BatchImportFindingsInput{
+		Findings: []types.AwsSecurityFinding{finding},
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if aws.ToInt32(output.FailedCount) > 0 && len(output.FailedFindings) > 0 {
+		failed := output.FailedFindings[0]
+		return fmt.Errorf("finding %s was not imported: %s %s", aws.ToString(failed.Id), aws.ToString(failed.ErrorCode), aws.ToString(failed.ErrorMessage))
+	}
+
+	return nil
+}
+
+// failedControls reads the controls calculate-score recorded for the account, without a key only the count is known.
+func (x *Lambda) failedControls(bucket string, key string) ([]string, error) {
+	if key == "" {
+		return nil, nil
+	}
+
+	data, err := x.store.Download(x.ctx, bucket, key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var controls []string
+	return controls, json.Unmarshal(data, &controls)
+}
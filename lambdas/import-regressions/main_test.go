@@ -0,0 +1,326 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3Types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/aws-sdk-go-v2/service/securityhub"
+	"github.com/aws/aws-sdk-go-v2/service/securityhub/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	stsTypes "github.com/aws/aws-sdk-go-v2/service/sts/types"
+	"github.com/awsdocs/aws-doc-sdk-examples/gov2/testtools"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"os"
+	"shared/dimension"
+	"shared/report"
+	"shared/statefile"
+	"testing"
+)
+
+const stateKeyFixture = "aws-foundational-security-best-practices/regressions/state.json"
+
+func readEvent(path string) Request {
+	file, _ := os.ReadFile(path)
+
+	var event Request
+	_ = json.Unmarshal(file, &event)
+	return event
+}
+
+func newLambda() (*testtools.AwsmStubber, *Lambda) {
+	stubber := testtools.NewStubber()
+	stubber.SdkConfig.Region = "eu-west-1"
+	return stubber, New(*stubber.SdkConfig)
+}
+
+func addStateStub(stubber *testtools.AwsmStubber, state *State) {
+	if state == nil {
+		stubber.Add(testtools.Stub{
+			OperationName: "GetObject",
+			Input:         &s3.GetObjectInput{Bucket: aws.String("my-sample-bucket"), Key: aws.String(stateKeyFixture)},
+			Error:         &testtools.StubError{Err: &s3Types.NoSuchKey{}, ContinueAfter: true},
+		})
+		return
+	}
+
+	data, _ := json.Marshal(state)
+	stubber.Add(testtools.Stub{
+		OperationName: "GetObject",
+		Input:         &s3.GetObjectInput{Bucket: aws.String("my-sample-bucket"), Key: aws.String(stateKeyFixture)},
+		Output:        &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(data))},
+	})
+}
+
+func addSaveStateStub(stubber *testtools.AwsmStubber, state State) {
+	data, _ := json.Marshal(state)
+	stubber.Add(testtools.Stub{
+		OperationName: "PutObject",
+		Input: &s3.PutObjectInput{
+			Bucket: aws.String("my-sample-bucket"),
+			Key:    aws.String(stateKeyFixture),
+			Body:   bytes.NewReader(data),
+		},
+		Output: &s3.PutObjectOutput{},
+	})
+}
+
+func addCallerIdentityStub(stubber *testtools.AwsmStubber) {
+	stubber.Add(testtools.Stub{
+		OperationName: "GetCallerIdentity",
+		Input:         &sts.GetCallerIdentityInput{},
+		Output:        &sts.GetCallerIdentityOutput{Account: aws.String("999999999999")},
+	})
+}
+
+func addAssumeRoleStub(stubber *testtools.AwsmStubber, accountId string) {
+	stubber.Add(testtools.Stub{
+		OperationName: "AssumeRole",
+		Input: &sts.AssumeRoleInput{
+			RoleArn:         aws.String("arn:aws:iam::" + accountId + ":role/SecurityPostureRegressions"),
+			RoleSessionName: aws.String("import-regressions"),
+		},
+		Output: &sts.AssumeRoleOutput{Credentials: &stsTypes.Credentials{
+			AccessKeyId:     aws.String("AKIAEXAMPLE"),
+			SecretAccessKey: aws.String("secret"),
+			SessionToken:    aws.String("token"),
+		}},
+	})
+}
+
+func addImportStub(stubber *testtools.AwsmStubber, finding types.AwsSecurityFinding) {
+	stubber.Add(testtools.Stub{
+		OperationName: "BatchImportFindings",
+		Input:         &securityhub.BatchImportFindingsInput{Findings: []types.AwsSecurityFinding{finding}},
+		Output:        &securityhub.BatchImportFindingsOutput{FailedCount: aws.Int32(0), SuccessCount: aws.Int32(1)},
+	})
+}
+
+func TestHandler(t *testing.T) {
+	ctx := context.Background()
+	event := readEvent("../../events/import-regressions.json")
+
+	t.Run("Import and archive regressions", func(t *testing.T) {
+		_ = os.Setenv("REGRESSION_ROLE_NAME", "SecurityPostureRegressions")
+		defer func() { _ = os.Setenv("REGRESSION_ROLE_NAME", "") }()
+		stubber, lambda := newLambda()
+
+		addStateStub(stubber, &State{Header: statefile.Header{Version: 1}, Accounts: map[string]AccountState{
+			"111122223333": {Score: 98, Timestamp: 1691316000},
+			"333322221111": {Score: 80, Timestamp: 1691316000, RegressedSince: 1690711200},
+		}})
+		addCallerIdentityStub(stubber)
+
+		// The hub account is below the threshold of its environment, it imports into its own Security Hub.
+		stubber.Add(testtools.Stub{
+			OperationName: "GetObject",
+			Input: &s3.GetObjectInput{
+				Bucket: aws.String("my-sample-bucket"),
+				Key:    aws.String("aws-foundational-security-best-practices/accounts/2023/08/13/999999999999.failed-controls.json"),
+			},
+			Output: &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader([]byte(`["IAM.1","S3.1"]`)))},
+		})
+		addImportStub(stubber, types.AwsSecurityFinding{
+			SchemaVersion: aws.String("2018-10-08"),
+			Id:            aws.String("aws-security-posture/aws-foundational-security-best-practices/999999999999/score-regression"),
+			ProductArn:    aws.String("arn:aws:securityhub:eu-west-1:999999999999:product/999999999999/default"),
+			GeneratorId:   aws.String("aws-security-posture/aws-foundational-security-best-practices"),
+			AwsAccountId:  aws.String("999999999999"),
+			Types:         []string{"Software and Configuration Checks/AWS Security Best Practices"},
+			CreatedAt:     aws.String("2023-08-13T09:55:32Z"),
+			UpdatedAt:     aws.String("2023-08-13T09:55:32Z"),
+			Resources: []types.Resource{{
+				Type:      aws.String("AwsAccount"),
+				Id:        aws.String("AWS::::Account:999999999999"),
+				Partition: types.PartitionAws,
+				Region:    aws.String("eu-west-1"),
+			}},
+			ProductFields: map[string]string{
+				"aws-security-posture/Report":         "aws-foundational-security-best-practices",
+				"aws-security-posture/Score":          "60",
+				"aws-security-posture/Workload":       "security",
+				"aws-security-posture/Environment":    "development",
+				"aws-security-posture/FailedControls": "IAM.1,S3.1",
+			},
+			RecordState: types.RecordStateActive,
+			Severity:    &types.Severity{Label: types.SeverityLabelHigh},
+			Title:       aws.String("Security score of aws-foundational-security-best-practices regressed to 60%"),
+			Description: aws.String("The score of 60% is below the threshold of 70% for environment development. Failed controls: IAM.1, S3.1."),
+			Remediation: &types.Remediation{Recommendation: &types.Recommendation{
+				Text: aws.String("Resolve the failed controls of the account, the finding is archived once the score recovers."),
+				Url:  aws.String("https://eu-west-1.console.aws.amazon.com/securityhub/home?region=eu-west-1#/controls"),
+			}},
+		})
+
+		// The test account dropped more than MaxDrop points, the finding is imported with the role in the account.
+		addAssumeRoleStub(stubber, "111122223333")
+		addImportStub(stubber, NewFinding(FindingInput{
+			Report: "aws-foundational-security-best-practices",
+			Region: "eu-west-1",
+			Score:  event.Accounts[1],
+			Regression: &Regression{
+				Reasons:  []string{"The score dropped 13 points from 98%, more than the maximum of 10."},
+				Severity: types.SeverityLabelMedium,
+			},
+			CreatedAt: 1691920532,
+			UpdatedAt: 1691920532,
+		}))
+
+		// The production account recovered, its finding keeps the moment it regressed and is archived.
+		addAssumeRoleStub(stubber, "333322221111")
+		addImportStub(stubber, ArchivedFinding("aws-foundational-security-best-practices", "eu-west-1", event.Accounts[2], 1690711200, 1691920532))
+
+		addSaveStateStub(stubber, State{Header: statefile.Header{Version: 1}, Accounts: map[string]AccountState{
+			"999999999999": {Score: 60, Timestamp: 1691920532, RegressedSince: 1691920532},
+			"111122223333": {Score: 85, Timestamp: 1691920532, RegressedSince: 1691920532},
+			"333322221111": {Score: 95, Timestamp: 1691920532},
+		}})
+
+		response, err := lambda.Handler(ctx, event)
+		testtools.ExitTest(stubber, t)
+		assert.NoError(t, err)
+		assert.Equal(t, Response{Imported: 2, Archived: 1}, response)
+	})
+
+	t.Run("Keep the state of an account that failed to import", func(t *testing.T) {
+		_ = os.Setenv("REGRESSION_ROLE_NAME", "")
+		stubber, lambda := newLambda()
+
+		request := readEvent("../../events/import-regressions.json")
+		request.Accounts[0].FailedControls = ""
+		request.Accounts[1].Score = 60
+
+		addStateStub(stubber, nil)
+		addCallerIdentityStub(stubber)
+		addImportStub(stubber, NewFinding(FindingInput{
+			Report: "aws-foundational-security-best-practices",
+			Region: "eu-west-1",
+			Score:  request.Accounts[0],
+			Regression: &Regression{
+				Reasons:  []string{"The score of 60% is below the threshold of 70% for environment development."},
+				Severity: types.SeverityLabelHigh,
+			},
+			CreatedAt: 1691920532,
+			UpdatedAt: 1691920532,
+		}))
+
+		// Without a role the regression of the test account cannot be imported, it is detected again in the next run.
+		addSaveStateStub(stubber, State{Header: statefile.Header{Version: 1}, Accounts: map[string]AccountState{
+			"999999999999": {Score: 60, Timestamp: 1691920532, RegressedSince: 1691920532},
+			"333322221111": {Score: 95, Timestamp: 1691920532},
+		}})
+
+		response, err := lambda.Handler(ctx, request)
+		testtools.ExitTest(stubber, t)
+		assert.EqualError(t, err, "account 111122223333: importing a finding into account 111122223333 needs REGRESSION_ROLE_NAME")
+		assert.Equal(t, Response{Imported: 1}, response)
+	})
+
+	t.Run("Fail on a rejected finding", func(t *testing.T) {
+		stubber, lambda := newLambda()
+
+		request := readEvent("../../events/import-regressions.json")
+		request.Accounts = request.Accounts[:1]
+		request.Accounts[0].FailedControls = ""
+
+		addStateStub(stubber, nil)
+		addCallerIdentityStub(stubber)
+		stubber.Add(testtools.Stub{
+			OperationName: "BatchImportFindings",
+			Input: &securityhub.BatchImportFindingsInput{Findings: []types.AwsSecurityFinding{NewFinding(FindingInput{
+				Report: "aws-foundational-security-best-practices",
+				Region: "eu-west-1",
+				Score:  request.Accounts[0],
+				Regression: &Regression{
+					Reasons:  []string{"The score of 60% is below the threshold of 70% for environment development."},
+					Severity: types.SeverityLabelHigh,
+				},
+				CreatedAt: 1691920532,
+				UpdatedAt: 1691920532,
+			})}},
+			Output: &securityhub.BatchImportFindingsOutput{
+				FailedCount:  aws.Int32(1),
+				SuccessCount: aws.Int32(0),
+				FailedFindings: []types.ImportFindingsError{{
+					Id:           aws.String("aws-security-posture/aws-foundational-security-best-practices/999999999999/score-regression"),
+					ErrorCode:    aws.String("InvalidInput"),
+					ErrorMessage: aws.String("Finding does not adhere to Amazon Finding Format."),
+				}},
+			},
+		})
+		addSaveStateStub(stubber, State{Header: statefile.Header{Version: 1}, Accounts: map[string]AccountState{}})
+
+		_, err := lambda.Handler(ctx, request)
+		testtools.ExitTest(stubber, t)
+		assert.EqualError(t, err, "account 999999999999: finding aws-security-posture/aws-foundational-security-best-practices/999999999999/score-regression was not imported: InvalidInput Finding does not adhere to Amazon Finding Format.")
+	})
+
+	t.Run("Keep the partitions of a split account apart", func(t *testing.T) {
+		stubber, lambda := newLambda()
+
+		west := []dimension.Dimension{{Name: "Region", Value: "eu-west-1"}}
+		central := []dimension.Dimension{{Name: "Region", Value: "eu-central-1"}}
+		request := readEvent("../../events/import-regressions.json")
+		request.Accounts = []*CalculatedScore{
+			{AccountId: "999999999999", Environment: "development", Status: "SCORED", Score: 95, Dimensions: west},
+			{AccountId: "999999999999", Environment: "development", Status: "SCORED", Score: 60, Dimensions: central},
+		}
+
+		addStateStub(stubber, &State{Header: statefile.Header{Version: 1}, Accounts: map[string]AccountState{
+			dimension.Key("999999999999", west): {Score: 50, Timestamp: 1691316000, RegressedSince: 1690711200},
+		}})
+		addCallerIdentityStub(stubber)
+
+		// The recovered partition archives its own finding, not the one of the partition that regresses.
+		archived := ArchivedFinding("aws-foundational-security-best-practices", "eu-west-1", request.Accounts[0], 1690711200, 1691920532)
+		assert.Equal(t, "aws-security-posture/aws-foundational-security-best-practices/999999999999/"+dimension.Id(west)+"/score-regression", aws.ToString(archived.Id))
+		assert.Equal(t, "Region=eu-west-1", archived.ProductFields["aws-security-posture/Dimensions"])
+		addImportStub(stubber, archived)
+		addImportStub(stubber, NewFinding(FindingInput{
+			Report: "aws-foundational-security-best-practices",
+			Region: "eu-west-1",
+			Score:  request.Accounts[1],
+			Regression: &Regression{
+				Reasons:  []string{"The score of 60% is below the threshold of 70% for environment development."},
+				Severity: types.SeverityLabelHigh,
+			},
+			CreatedAt: 1691920532,
+			UpdatedAt: 1691920532,
+		}))
+		addSaveStateStub(stubber, State{Header: statefile.Header{Version: 1}, Accounts: map[string]AccountState{
+			dimension.Key("999999999999", west):    {Score: 95, Timestamp: 1691920532},
+			dimension.Key("999999999999", central): {Score: 60, Timestamp: 1691920532, RegressedSince: 1691920532},
+		}})
+
+		response, err := lambda.Handler(ctx, request)
+		testtools.ExitTest(stubber, t)
+		assert.NoError(t, err)
+		assert.Equal(t, Response{Imported: 1, Archived: 1}, response)
+	})
+
+	t.Run("Skip a report without regressions", func(t *testing.T) {
+		stubber, lambda := newLambda()
+
+		request := readEvent("../../events/import-regressions.json")
+		request.Options = report.Options{}
+
+		response, err := lambda.Handler(ctx, request)
+		testtools.ExitTest(stubber, t)
+		assert.NoError(t, err)
+		assert.Equal(t, Response{}, response)
+	})
+
+	t.Run("Invalid threshold", func(t *testing.T) {
+		stubber, lambda := newLambda()
+
+		request := readEvent("../../events/import-regressions.json")
+		request.Options.Regressions.Thresholds["production"] = 120
+
+		_, err := lambda.Handler(ctx, request)
+		testtools.ExitTest(stubber, t)
+		assert.EqualError(t, err, "threshold 120 of environment `production` is not between 0 and 100")
+	})
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"shared/dimension"
+	"shared/report"
+	"shared/score"
+	"shared/statefile"
+)
+
+// CalculatedScore is the part of the result of calculate-score a regression is detected with.
+type CalculatedScore struct {
+	AccountId          string       `json:"AccountId"`
+	AccountName        string       `json:"AccountName"`
+	Workload           string       `json:"Workload"`
+	Environment        string       `json:"Environment"`
+	Status             score.Status `json:"Status"`
+	Score              float64      `json:"Score"`
+	ControlFailedCount int          `json:"ControlFailedCount"`
+	FailedControls     string       `json:"FailedControls,omitempty"`
+	// Dimensions are the values of the split dimensions, an account has a score for every combination of values.
+	Dimensions []dimension.Dimension `json:"Dimensions"`
+}
+
+type Request struct {
+	Report    string             `json:"Report"`
+	Timestamp int64              `json:"Timestamp"`
+	Bucket    string             `json:"Bucket"`
+	Accounts  []*CalculatedScore `json:"Accounts"`
+	Options   report.Options     `json:"Options"`
+}
+
+type Response struct {
+	// Imported is the number of active regression findings, Archived the number of findings archived in this run.
+	Imported int `json:"Imported"`
+	Archived int `json:"Archived"`
+}
+
+// AccountState is the last score of an account, and since when it regressed.
+type AccountState struct {
+	Score     float64 `json:"Score"`
+	Timestamp int64   `json:"Timestamp"`
+	// RegressedSince is the timestamp of the run that imported the active finding, zero when there is none.
+	RegressedSince int64 `json:"RegressedSince,omitempty"`
+}
+
+// State is kept in the bucket between the runs of a report, to detect drops and recoveries. The accounts are keyed
+// by dimension.Key, every partition of a split account regresses on its own.
+type State struct {
+	statefile.Header
+	Accounts map[string]AccountState `json:"Accounts"`
+}
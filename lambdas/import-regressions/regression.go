@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/service/securityhub/types"
+	"math"
+	"shared/report"
+	"sort"
+	"strconv"
+)
+
+// defaultThreshold is the key of the threshold that applies to the environments without a threshold of their own.
+const defaultThreshold = "*"
+
+// Regression tells why the score of an account regressed.
+type Regression struct {
+	Reasons  []string
+	Severity types.SeverityLabel
+}
+
+// validateRegressions checks the options before anything is imported.
+func validateRegressions(options *report.Regressions) error {
+	environments := make([]string, 0, len(options.Thresholds))
+	for environment := range options.Thresholds {
+		environments = append(environments, environment)
+	}
+	sort.Strings(environments)
+
+	for _, environment := range environments {
+		threshold := options.Thresholds[environment]
+
+		if threshold < 0 || threshold > 100 {
+			return fmt.Errorf("threshold %s of environment `%s` is not between 0 and 100", formatScore(threshold), environment)
+		}
+	}
+
+	if options.MaxDrop < 0 {
+		return fmt.Errorf("MaxDrop %s is negative", formatScore(options.MaxDrop))
+	}
+
+	if len(options.Thresholds) == 0 && options.MaxDrop == 0 {
+		return fmt.Errorf("regressions need Thresholds or a MaxDrop")
+	}
+
+	return nil
+}
+
+// Detect returns the regression of the score, or nil when the score is acceptable. A score below the threshold of its
+// environment is a HIGH regression, a drop of more than MaxDrop points since the previous run a MEDIUM regression.
+func Detect(options *report.Regressions, calculated *CalculatedScore, previous *AccountState) *Regression {
+	var regression Regression
+
+	threshold, ok := options.Thresholds[calculated.Environment]
+	if !ok {
+		threshold, ok = options.Thresholds[defaultThreshold]
+	}
+
+	if ok && calculated.Score < threshold {
+		regression.Severity = types.SeverityLabelHigh
+		regression.Reasons = append(regression.Reasons, fmt.Sprintf("The score of %s%% is below the threshold of %s%% for environment %s.",
+			formatScore(calculated.Score), formatScore(threshold), calculated.Environment))
+	}
+
+	if options.MaxDrop > 0 && previous != nil && previous.Score-calculated.Score > options.MaxDrop {
+		if regression.Severity == "" {
+			regression.Severity = types.SeverityLabelMedium
+		}
+		regression.Reasons = append(regression.Reasons, fmt.Sprintf("The score dropped %s points from %s%%, more than the maximum of %s.",
+			formatScore(previous.Score-calculated.Score), formatScore(previous.Score), formatScore(options.MaxDrop)))
+	}
+
+	if len(regression.Reasons) == 0 {
+		return nil
+	}
+
+	return &regression
+}
+
+// formatScore rounds to two decimals, a difference of two scores is not exact.
+func formatScore(value float64) string {
+	return strconv.FormatFloat(math.Round(value*100)/100, 'f', -1, 64)
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"github.com/aws/aws-sdk-go-v2/service/securityhub/types"
+	"github.com/stretchr/testify/assert"
+	"shared/report"
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	options := &report.Regressions{
+		Thresholds: map[string]float64{"production": 90, "*": 70},
+		MaxDrop:    10,
+	}
+
+	tests := []struct {
+		name        string
+		environment string
+		score       float64
+		previous    *AccountState
+		expected    *Regression
+	}{
+		{"Above the threshold", "production", 95, nil, nil},
+		{"Equal to the threshold", "production", 90, nil, nil},
+		{"Below the threshold", "production", 89.5, nil, &Regression{
+			Reasons:  []string{"The score of 89.5% is below the threshold of 90% for environment production."},
+			Severity: types.SeverityLabelHigh,
+		}},
+		{"Below the default threshold", "development", 65, nil, &Regression{
+			Reasons:  []string{"The score of 65% is below the threshold of 70% for environment development."},
+			Severity: types.SeverityLabelHigh,
+		}},
+		{"Drop within the maximum", "development", 80, &AccountState{Score: 90}, nil},
+		{"Drop of more than the maximum", "development", 79.9, &AccountState{Score: 90}, &Regression{
+			Reasons:  []string{"The score dropped 10.1 points from 90%, more than the maximum of 10."},
+			Severity: types.SeverityLabelMedium,
+		}},
+		{"Drop below the threshold", "production", 80, &AccountState{Score: 95}, &Regression{
+			Reasons: []string{
+				"The score of 80% is below the threshold of 90% for environment production.",
+				"The score dropped 15 points from 95%, more than the maximum of 10.",
+			},
+			Severity: types.SeverityLabelHigh,
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			calculated := &CalculatedScore{Environment: test.environment, Score: test.score}
+			assert.Equal(t, test.expected, Detect(options, calculated, test.previous))
+		})
+	}
+
+	t.Run("Without a default threshold", func(t *testing.T) {
+		options := &report.Regressions{Thresholds: map[string]float64{"production": 90}}
+		assert.Nil(t, Detect(options, &CalculatedScore{Environment: "development", Score: 10}, nil))
+	})
+}
+
+func TestValidateRegressions(t *testing.T) {
+	assert.NoError(t, validateRegressions(&report.Regressions{MaxDrop: 5}))
+	assert.EqualError(t, validateRegressions(&report.Regressions{Thresholds: map[string]float64{"*": -1}}),
+		"threshold -1 of environment `*` is not between 0 and 100")
+	assert.EqualError(t, validateRegressions(&report.Regressions{MaxDrop: -5}), "MaxDrop -5 is negative")
+	assert.EqualError(t, validateRegressions(&report.Regressions{}), "regressions need Thresholds or a MaxDrop")
+}
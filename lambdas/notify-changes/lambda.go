@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"log"
+	"net/http"
+	"shared/blobstore"
+	"shared/dimension"
+	"shared/score"
+	"shared/statefile"
+	"sort"
+	"time"
+)
+
+// stateVersion is the newest version of the state format this function understands.
+const stateVersion = 1
+
+type Lambda struct {
+	ctx        context.Context
+	store      blobstore.BlobStore
+	httpClient *http.Client
+}
+
+func New(cfg aws.Config) *Lambda {
+	m := new(Lambda)
+	m.store = blobstore.NewFromConfig(cfg)
+	m.httpClient = &http.Client{Timeout: 10 * time.Second}
+	return m
+}
+
+func (x *Lambda) Handler(ctx context.Context, request Request) (Response, error) {
+	x.ctx = ctx
+	response := Response{}
+	rules := request.Options.Notifications
+
+	if len(rules) == 0 {
+		log.Printf("Report %s has no notification rules", request.Report)
+		return response, nil
+	}
+
+	webhooks, err := loadWebhooks()
+
+	if err != nil {
+		return response, err
+	}
+
+	err = validateRules(rules, webhooks)
+
+	if err != nil {
+		return response, err
+	}
+
+	state, err := statefile.Load[State](x.ctx, x.store, request.Bucket, statefile.Key(request.Report, "notifications"), stateVersion)
+
+	if err != nil {
+		return response, err
+	}
+
+	next := State{Accounts: map[string]AccountState{}, Alerts: map[string]map[string]SentAlert{}}
+	scored := map[string]bool{}
+	pending := map[string][]Alert{}
+
+	for _, calculated := range request.Accounts {
+		if score.Resolve(calculated.Status) != score.StatusScored {
+			continue
+		}
+
+		accountKey := dimension.Key(calculated.AccountId, calculated.Dimensions)
+		scored[accountKey] = true
+		previous, known := state.Accounts[accountKey]
+
+		var last *AccountState
+		if known {
+			last = &previous
+		}
+
+		failed, err := x.failedControls(request.Bucket, calculated.FailedControls)
+
+		if err != nil {
+			return response, err
+		}
+
+		for _, rule := range rules {
+			if !Matches(rule, request.Report, calculated) {
+				continue
+			}
+
+			for _, alert := range Evaluate(rule, request.Report, calculated, last, failed) {
+				key := alert.Key()
+
+				// An alert is sent to a webhook when it starts firing, and not again until it cleared. The controls an
+				// account fails when it is seen for the first time are its baseline, they did not start failing.
+				for _, webhook := range rule.Webhooks {
+					if sent, ok := state.Alerts[webhook][key]; ok {
+						next.sent(webhook, key, sent)
+					} else if alert.Kind == AlertFailingControl && !known {
+						next.sent(webhook, key, SentAlert{AccountId: alert.AccountId, Dimensions: alert.Dimensions, Since: request.Timestamp})
+					} else {
+						pending[webhook] = append(pending[webhook], alert)
+					}
+				}
+			}
+		}
+
+		next.Accounts[accountKey] = AccountState{Score: calculated.Score}
+	}
+
+	// The accounts that are not scored in this run keep their state, so their alerts are not sent again.
+	for accountKey, account := range state.Accounts {
+		if !scored[accountKey] {
+			next.Accounts[accountKey] = account
+		}
+	}
+
+	for webhook, alerts := range state.Alerts {
+		for key, sent := range alerts {
+			if !scored[dimension.Key(sent.AccountId, sent.Dimensions)] {
+				next.sent(webhook, key, sent)
+			}
+		}
+	}
+
+	// An alert that did not reach a webhook is sent to that webhook again in the next run.
+	delivered, errs := x.deliver(request, webhooks, pending)
+
+	for webhook, alerts := range delivered {
+		for _, alert := range alerts {
+			next.sent(webhook, alert.Key(), SentAlert{AccountId: alert.AccountId, Dimensions: alert.Dimensions, Since: request.Timestamp})
+			response.Sent++
+		}
+	}
+
+	// A drop is measured against the score of the previous run, so a partition with an undelivered drop keeps that
+	// score until the drop reached every webhook. Otherwise the next run compares against the dropped score.
+	for webhook, alerts := range pending {
+		for _, alert := range alerts {
+			if alert.Kind != AlertScoreDrop {
+				continue
+			}
+
+			if _, ok := next.Alerts[webhook][alert.Key()]; ok {
+				continue
+			}
+
+			accountKey := dimension.Key(alert.AccountId, alert.Dimensions)
+			if previous, ok := state.Accounts[accountKey]; ok {
+				next.Accounts[accountKey] = previous
+			}
+		}
+	}
+
+	for _, alerts := range next.Alerts {
+		response.Active += len(alerts)
+	}
+
+	err = statefile.Save(x.ctx, x.store, request.Bucket, statefile.Key(request.Report, "notifications"), stateVersion, &next)
+
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	return response, errors.Join(errs...)
+}
+
+// deliver sends the pending alerts of every webhook in as few messages as possible. It returns the alerts that were
+// delivered, by webhook.
+func (x *Lambda) deliver(request Request, webhooks map[string]Webhook, pending map[string][]Alert) (map[string][]Alert, []error) {
+	names := make([]string, 0, len(pending))
+	for name := range pending {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	delivered := map[string][]Alert{}
+	var errs []error
+
+	for _, name := range names {
+		sent, err := x.send(request, webhooks[name], pending[name])
+		delivered[name] = sent
+
+		if err != nil {
+			log.Printf("Sending %d of %d alerts to webhook %s failed: %s", len(pending[name])-len(sent), len(pending[name]), name, err)
+			errs = append(errs, fmt.Errorf("webhook %s: %w", name, err))
+			continue
+		}
+
+		log.Printf("Sent %d alerts to webhook %s", len(sent), name)
+	}
+
+	return delivered, errs
+}
+
+// send posts the alerts in messages of at most maxAlertsPerMessage alerts. It returns the alerts of the messages that
+// were delivered, a message that failed does not stop the others.
+func (x *Lambda) send(request Request, webhook Webhook, alerts []Alert) ([]Alert, error) {
+	var sent []Alert
+	var errs []error
+
+	for _, chunk := range chunkAlerts(alerts) {
+		message, err := EncodeMessage(webhook.Format, request.Report, request.Timestamp, chunk)
+
+		if err == nil {
+			err = post(x.ctx, x.httpClient, webhook.Url, message)
+		}
+
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		sent = append(sent, chunk...)
+	}
+
+	return sent, errors.Join(errs...)
+}
+
+// failedControls reads the controls calculate-score recorded for the account, without a key there are none.
+func (x *Lambda) failedControls(bucket string, key string) ([]string, error) {
+	if key == "" {
+		return nil, nil
+	}
+
+	data, err := x.store.Download(x.ctx, bucket, key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var controls []string
+	return controls, json.Unmarshal(data, &controls)
+}
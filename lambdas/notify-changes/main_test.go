@@ -0,0 +1,421 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3Types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/awsdocs/aws-doc-sdk-examples/gov2/testtools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"shared/dimension"
+	"shared/report"
+	"shared/statefile"
+	"testing"
+)
+
+const stateKeyFixture = "aws-foundational-security-best-practices/notifications/state.json"
+
+func readEvent(path string) Request {
+	file, _ := os.ReadFile(path)
+
+	var event Request
+	_ = json.Unmarshal(file, &event)
+	return event
+}
+
+// newWebhook returns a server that records the bodies it receives, and answers with the status.
+func newWebhook(t *testing.T, status int) (*httptest.Server, *[][]byte) {
+	var bodies [][]byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, body)
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &bodies
+}
+
+func setWebhooks(t *testing.T, slack string, teams string, audit string) {
+	_ = os.Setenv("NOTIFICATION_WEBHOOKS", fmt.Sprintf(`{
+		"platform-slack": {"Url": "%s", "Format": "Slack"},
+		"platform-teams": {"Url": "%s", "Format": "Teams"},
+		"audit": {"Url": "%s"}
+	}`, slack, teams, audit))
+	t.Cleanup(func() { _ = os.Setenv("NOTIFICATION_WEBHOOKS", "") })
+}
+
+func addStateStub(stubber *testtools.AwsmStubber, state *State) {
+	if state == nil {
+		stubber.Add(testtools.Stub{
+			OperationName: "GetObject",
+			Input:         &s3.GetObjectInput{Bucket: aws.String("my-sample-bucket"), Key: aws.String(stateKeyFixture)},
+			Error:         &testtools.StubError{Err: &s3Types.NoSuchKey{}, ContinueAfter: true},
+		})
+		return
+	}
+
+	data, _ := json.Marshal(state)
+	stubber.Add(testtools.Stub{
+		OperationName: "GetObject",
+		Input:         &s3.GetObjectInput{Bucket: aws.String("my-sample-bucket"), Key: aws.String(stateKeyFixture)},
+		Output:        &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(data))},
+	})
+}
+
+func addFailedControlsStub(stubber *testtools.AwsmStubber, controls ...string) {
+	data, _ := json.Marshal(controls)
+	stubber.Add(testtools.Stub{
+		OperationName: "GetObject",
+		Input: &s3.GetObjectInput{
+			Bucket: aws.String("my-sample-bucket"),
+			Key:    aws.String("aws-foundational-security-best-practices/accounts/2023/08/13/111122223333.failed-controls.json"),
+		},
+		Output: &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(data))},
+	})
+}
+
+func addSaveStateStub(stubber *testtools.AwsmStubber, state State) {
+	data, _ := json.Marshal(state)
+	stubber.Add(testtools.Stub{
+		OperationName: "PutObject",
+		Input: &s3.PutObjectInput{
+			Bucket: aws.String("my-sample-bucket"),
+			Key:    aws.String(stateKeyFixture),
+			Body:   bytes.NewReader(data),
+		},
+		Output: &s3.PutObjectOutput{},
+	})
+}
+
+func previousState() *State {
+	drops := map[string]SentAlert{
+		"drops/444455556666/ScoreDrop": {AccountId: "444455556666", Since: 1691316000},
+		"drops/333322221111/ScoreDrop": {AccountId: "333322221111", Since: 1691316000},
+	}
+
+	return &State{Header: statefile.Header{Version: 1},
+		Accounts: map[string]AccountState{
+			"111122223333": {Score: 85},
+			"333322221111": {Score: 91},
+			"444455556666": {Score: 50},
+		},
+		Alerts: map[string]map[string]SentAlert{
+			"platform-slack": {
+				"production-target/111122223333/FailingControl/S3.1": {AccountId: "111122223333", Since: 1691316000},
+			},
+			"platform-teams": drops,
+			"audit":          drops,
+		},
+	}
+}
+
+func TestHandler(t *testing.T) {
+	ctx := context.Background()
+	event := readEvent("../../events/notify-changes.json")
+
+	t.Run("Send the alerts that started firing", func(t *testing.T) {
+		slack, slackBodies := newWebhook(t, http.StatusOK)
+		teams, teamsBodies := newWebhook(t, http.StatusOK)
+		audit, auditBodies := newWebhook(t, http.StatusAccepted)
+		setWebhooks(t, slack.URL, teams.URL, audit.URL)
+
+		stubber := testtools.NewStubber()
+		lambda := New(*stubber.SdkConfig)
+
+		addStateStub(stubber, previousState())
+		addFailedControlsStub(stubber, "EC2.2", "IAM.1", "S3.1")
+
+		// The drop alert of the test account cleared, the alerts of the account that was not scored are kept.
+		drops := map[string]SentAlert{
+			"drops/111122223333/ScoreDrop": {AccountId: "111122223333", Since: 1691920532},
+			"drops/444455556666/ScoreDrop": {AccountId: "444455556666", Since: 1691316000},
+		}
+		addSaveStateStub(stubber, State{Header: statefile.Header{Version: 1},
+			Accounts: map[string]AccountState{
+				"111122223333": {Score: 72},
+				"333322221111": {Score: 90},
+				"444455556666": {Score: 50},
+			},
+			Alerts: map[string]map[string]SentAlert{
+				"platform-slack": {
+					"production-target/111122223333/BelowTarget":          {AccountId: "111122223333", Since: 1691920532},
+					"production-target/111122223333/FailingControl/IAM.1": {AccountId: "111122223333", Since: 1691920532},
+					"production-target/111122223333/FailingControl/S3.1":  {AccountId: "111122223333", Since: 1691316000},
+				},
+				"platform-teams": drops,
+				"audit":          drops,
+			},
+		})
+
+		response, err := lambda.Handler(ctx, event)
+		testtools.ExitTest(stubber, t)
+		require.NoError(t, err)
+		assert.Equal(t, Response{Sent: 4, Active: 7}, response)
+
+		require.Len(t, *slackBodies, 1)
+		var slackMessage SlackMessage
+		require.NoError(t, json.Unmarshal((*slackBodies)[0], &slackMessage))
+		assert.Equal(t, "2 security posture alerts for aws-foundational-security-best-practices", slackMessage.Text)
+		assert.Equal(t, []SlackBlock{
+			{Type: "section", Text: SlackText{Type: "mrkdwn", Text: "*BelowTarget* (rule `production-target`)\nThe score of my-workload-production (my-workload, production) is 72%, below the target of 80%."}},
+			{Type: "section", Text: SlackText{Type: "mrkdwn", Text: "*FailingControl* (rule `production-target`)\nControl IAM.1 is failing in my-workload-production (my-workload, production)."}},
+		}, slackMessage.Blocks)
+
+		require.Len(t, *teamsBodies, 1)
+		var teamsMessage TeamsMessage
+		require.NoError(t, json.Unmarshal((*teamsBodies)[0], &teamsMessage))
+		assert.Equal(t, "The score of my-workload-production (my-workload, production) dropped from 85% to 72%, more than 5 points.",
+			teamsMessage.Attachments[0].Content.Body[1].Text)
+
+		require.Len(t, *auditBodies, 1)
+		var generic GenericMessage
+		require.NoError(t, json.Unmarshal((*auditBodies)[0], &generic))
+		assert.Equal(t, GenericMessage{
+			Report:    "aws-foundational-security-best-practices",
+			Timestamp: 1691920532,
+			Alerts: []Alert{{
+				Rule:          "drops",
+				Kind:          AlertScoreDrop,
+				Report:        "aws-foundational-security-best-practices",
+				AccountId:     "111122223333",
+				AccountName:   "my-workload-production",
+				Workload:      "my-workload",
+				Environment:   "production",
+				Score:         72,
+				PreviousScore: aws.Float64(85),
+				Message:       "The score of my-workload-production (my-workload, production) dropped from 85% to 72%, more than 5 points.",
+			}},
+		}, generic)
+	})
+
+	t.Run("Do not repeat the alerts that were sent", func(t *testing.T) {
+		slack, slackBodies := newWebhook(t, http.StatusOK)
+		teams, teamsBodies := newWebhook(t, http.StatusOK)
+		audit, auditBodies := newWebhook(t, http.StatusOK)
+		setWebhooks(t, slack.URL, teams.URL, audit.URL)
+
+		stubber := testtools.NewStubber()
+		lambda := New(*stubber.SdkConfig)
+
+		state := State{Header: statefile.Header{Version: 1},
+			Accounts: map[string]AccountState{
+				"111122223333": {Score: 72},
+				"333322221111": {Score: 90},
+			},
+			Alerts: map[string]map[string]SentAlert{
+				"platform-slack": {
+					"production-target/111122223333/BelowTarget":          {AccountId: "111122223333", Since: 1691316000},
+					"production-target/111122223333/FailingControl/IAM.1": {AccountId: "111122223333", Since: 1691316000},
+				},
+			},
+		}
+		addStateStub(stubber, &state)
+		addFailedControlsStub(stubber, "IAM.1")
+		addSaveStateStub(stubber, state)
+
+		response, err := lambda.Handler(ctx, event)
+		testtools.ExitTest(stubber, t)
+		require.NoError(t, err)
+		assert.Equal(t, Response{Sent: 0, Active: 2}, response)
+		assert.Empty(t, *slackBodies)
+		assert.Empty(t, *teamsBodies)
+		assert.Empty(t, *auditBodies)
+	})
+
+	t.Run("Take the failed controls of a new account as its baseline", func(t *testing.T) {
+		slack, slackBodies := newWebhook(t, http.StatusOK)
+		setWebhooks(t, slack.URL, slack.URL, slack.URL)
+
+		stubber := testtools.NewStubber()
+		lambda := New(*stubber.SdkConfig)
+
+		addStateStub(stubber, nil)
+		addFailedControlsStub(stubber, "IAM.1")
+		addSaveStateStub(stubber, State{Header: statefile.Header{Version: 1},
+			Accounts: map[string]AccountState{
+				"111122223333": {Score: 72},
+				"333322221111": {Score: 90},
+			},
+			Alerts: map[string]map[string]SentAlert{
+				"platform-slack": {
+					"production-target/111122223333/BelowTarget":          {AccountId: "111122223333", Since: 1691920532},
+					"production-target/111122223333/FailingControl/IAM.1": {AccountId: "111122223333", Since: 1691920532},
+				},
+			},
+		})
+
+		response, err := lambda.Handler(ctx, event)
+		testtools.ExitTest(stubber, t)
+		require.NoError(t, err)
+		assert.Equal(t, Response{Sent: 1, Active: 2}, response)
+		assert.Len(t, *slackBodies, 1)
+	})
+
+	t.Run("Send undelivered alerts again in the next run", func(t *testing.T) {
+		slack, _ := newWebhook(t, http.StatusOK)
+		teams, _ := newWebhook(t, http.StatusInternalServerError)
+		audit, auditBodies := newWebhook(t, http.StatusOK)
+		setWebhooks(t, slack.URL, teams.URL, audit.URL)
+
+		stubber := testtools.NewStubber()
+		lambda := New(*stubber.SdkConfig)
+
+		addStateStub(stubber, previousState())
+		addFailedControlsStub(stubber, "S3.1")
+		// The drop reached the audit webhook, only the Teams webhook gets it again in the next run. The account keeps
+		// the score it dropped from, so the next run still sees the drop.
+		addSaveStateStub(stubber, State{Header: statefile.Header{Version: 1},
+			Accounts: map[string]AccountState{
+				"111122223333": {Score: 85},
+				"333322221111": {Score: 90},
+				"444455556666": {Score: 50},
+			},
+			Alerts: map[string]map[string]SentAlert{
+				"platform-slack": {
+					"production-target/111122223333/BelowTarget":         {AccountId: "111122223333", Since: 1691920532},
+					"production-target/111122223333/FailingControl/S3.1": {AccountId: "111122223333", Since: 1691316000},
+				},
+				"platform-teams": {
+					"drops/444455556666/ScoreDrop": {AccountId: "444455556666", Since: 1691316000},
+				},
+				"audit": {
+					"drops/111122223333/ScoreDrop": {AccountId: "111122223333", Since: 1691920532},
+					"drops/444455556666/ScoreDrop": {AccountId: "444455556666", Since: 1691316000},
+				},
+			},
+		})
+
+		response, err := lambda.Handler(ctx, event)
+		testtools.ExitTest(stubber, t)
+		assert.EqualError(t, err, "webhook platform-teams: webhook returned 500 Internal Server Error: ")
+		assert.Equal(t, Response{Sent: 2, Active: 5}, response)
+		assert.Len(t, *auditBodies, 1)
+	})
+
+	t.Run("Record the delivery of every message", func(t *testing.T) {
+		var requests int
+		audit := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			if requests == 2 {
+				w.WriteHeader(http.StatusBadGateway)
+			}
+		}))
+		t.Cleanup(audit.Close)
+		setWebhooks(t, audit.URL, audit.URL, audit.URL)
+
+		stubber := testtools.NewStubber()
+		lambda := New(*stubber.SdkConfig)
+
+		request := readEvent("../../events/notify-changes.json")
+		request.Accounts = request.Accounts[:1]
+		request.Options.Notifications = []report.NotificationRule{{Name: "controls", FailingControls: []string{"*"}, Webhooks: []string{"audit"}}}
+
+		var controls []string
+		for i := 1; i <= 45; i++ {
+			controls = append(controls, fmt.Sprintf("S3.%d", i))
+		}
+
+		addStateStub(stubber, &State{Header: statefile.Header{Version: 1}, Accounts: map[string]AccountState{"111122223333": {Score: 85}}})
+		addFailedControlsStub(stubber, controls...)
+
+		// The first message with 40 alerts was delivered, the second one with the other 5 is sent again in the next run.
+		sent := map[string]SentAlert{}
+		for _, control := range controls[:40] {
+			sent["controls/111122223333/FailingControl/"+control] = SentAlert{AccountId: "111122223333", Since: 1691920532}
+		}
+		addSaveStateStub(stubber, State{Header: statefile.Header{Version: 1},
+			Accounts: map[string]AccountState{"111122223333": {Score: request.Accounts[0].Score}},
+			Alerts:   map[string]map[string]SentAlert{"audit": sent},
+		})
+
+		response, err := lambda.Handler(ctx, request)
+		testtools.ExitTest(stubber, t)
+		assert.ErrorContains(t, err, "webhook audit: webhook returned 502 Bad Gateway")
+		assert.Equal(t, Response{Sent: 40, Active: 40}, response)
+		assert.Equal(t, 2, requests)
+	})
+
+	t.Run("Keep the partitions of a split account apart", func(t *testing.T) {
+		slack, slackBodies := newWebhook(t, http.StatusOK)
+		setWebhooks(t, slack.URL, slack.URL, slack.URL)
+
+		stubber := testtools.NewStubber()
+		lambda := New(*stubber.SdkConfig)
+
+		west := []dimension.Dimension{{Name: "Region", Value: "eu-west-1"}}
+		central := []dimension.Dimension{{Name: "Region", Value: "eu-central-1"}}
+		request := readEvent("../../events/notify-changes.json")
+		request.Accounts = []*CalculatedScore{
+			{AccountId: "333322221111", Workload: "my-workload", Environment: "test", Status: "SCORED", Score: 70, Dimensions: west},
+			{AccountId: "333322221111", Workload: "my-workload", Environment: "test", Status: "SCORED", Score: 95, Dimensions: central},
+		}
+
+		// Only the partition in eu-west-1 dropped, the drop of eu-central-1 in the previous run has cleared.
+		addStateStub(stubber, &State{Header: statefile.Header{Version: 1},
+			Accounts: map[string]AccountState{
+				dimension.Key("333322221111", west):    {Score: 90},
+				dimension.Key("333322221111", central): {Score: 60},
+			},
+			Alerts: map[string]map[string]SentAlert{
+				"platform-teams": {
+					"drops/" + dimension.Key("333322221111", central) + "/ScoreDrop": {AccountId: "333322221111", Dimensions: central, Since: 1691316000},
+				},
+			},
+		})
+
+		drop := map[string]SentAlert{
+			"drops/" + dimension.Key("333322221111", west) + "/ScoreDrop": {AccountId: "333322221111", Dimensions: west, Since: 1691920532},
+		}
+		addSaveStateStub(stubber, State{Header: statefile.Header{Version: 1},
+			Accounts: map[string]AccountState{
+				dimension.Key("333322221111", west):    {Score: 70},
+				dimension.Key("333322221111", central): {Score: 95},
+			},
+			Alerts: map[string]map[string]SentAlert{"platform-teams": drop, "audit": drop},
+		})
+
+		response, err := lambda.Handler(ctx, request)
+		testtools.ExitTest(stubber, t)
+		require.NoError(t, err)
+		assert.Equal(t, Response{Sent: 2, Active: 2}, response)
+
+		require.Len(t, *slackBodies, 2)
+		var generic GenericMessage
+		require.NoError(t, json.Unmarshal((*slackBodies)[0], &generic), "the audit webhook is sent to first")
+		assert.Equal(t, "The score of 333322221111 (my-workload, test, Region=eu-west-1) dropped from 90% to 70%, more than 5 points.", generic.Alerts[0].Message)
+	})
+
+	t.Run("Unknown webhook", func(t *testing.T) {
+		_ = os.Setenv("NOTIFICATION_WEBHOOKS", `{"platform-slack": {"Url": "https://hooks.slack.com/services/T000/B000/XXXX", "Format": "Slack"}}`)
+		defer func() { _ = os.Setenv("NOTIFICATION_WEBHOOKS", "") }()
+
+		stubber := testtools.NewStubber()
+		lambda := New(*stubber.SdkConfig)
+
+		_, err := lambda.Handler(ctx, event)
+		testtools.ExitTest(stubber, t)
+		assert.EqualError(t, err, "notification rule `drops` uses webhook `platform-teams`, which is not configured in NOTIFICATION_WEBHOOKS")
+	})
+
+	t.Run("Skip a report without rules", func(t *testing.T) {
+		stubber := testtools.NewStubber()
+		lambda := New(*stubber.SdkConfig)
+
+		request := readEvent("../../events/notify-changes.json")
+		request.Options.Notifications = nil
+
+		response, err := lambda.Handler(ctx, request)
+		testtools.ExitTest(stubber, t)
+		assert.NoError(t, err)
+		assert.Equal(t, Response{}, response)
+	})
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"shared/dimension"
+	"shared/report"
+	"shared/score"
+	"shared/statefile"
+)
+
+// CalculatedScore is the part of the result of calculate-score the rules are evaluated with.
+type CalculatedScore struct {
+	AccountId      string       `json:"AccountId"`
+	AccountName    string       `json:"AccountName"`
+	Workload       string       `json:"Workload"`
+	Environment    string       `json:"Environment"`
+	Status         score.Status `json:"Status"`
+	Score          float64      `json:"Score"`
+	FailedControls string       `json:"FailedControls,omitempty"`
+	// Dimensions are the values of the split dimensions, an account has a score for every combination of values.
+	Dimensions []dimension.Dimension `json:"Dimensions"`
+}
+
+type Request struct {
+	Report    string             `json:"Report"`
+	Timestamp int64              `json:"Timestamp"`
+	Bucket    string             `json:"Bucket"`
+	Accounts  []*CalculatedScore `json:"Accounts"`
+	Options   report.Options     `json:"Options"`
+}
+
+type Response struct {
+	// Sent is the number of alerts that were delivered, Active the number of alerts that are still firing. Both count
+	// an alert once for every webhook of its rule.
+	Sent   int `json:"Sent"`
+	Active int `json:"Active"`
+}
+
+// Alert is a condition of a rule that fires for an account.
+type Alert struct {
+	Rule          string                `json:"Rule"`
+	Kind          string                `json:"Kind"`
+	Report        string                `json:"Report"`
+	AccountId     string                `json:"AccountId"`
+	AccountName   string                `json:"AccountName"`
+	Workload      string                `json:"Workload"`
+	Environment   string                `json:"Environment"`
+	Dimensions    []dimension.Dimension `json:"Dimensions,omitempty"`
+	Score         float64               `json:"Score"`
+	PreviousScore *float64              `json:"PreviousScore,omitempty"`
+	Target        float64               `json:"Target,omitempty"`
+	Control       string                `json:"Control,omitempty"`
+	Message       string                `json:"Message"`
+}
+
+// AccountState is the score of an account in the previous run.
+type AccountState struct {
+	Score float64 `json:"Score"`
+}
+
+// State is kept in the bucket between the runs of a report, to detect changes and to send every alert once.
+type State struct {
+	statefile.Header
+	// Accounts are keyed by dimension.Key, every partition of a split account is evaluated on its own.
+	Accounts map[string]AccountState `json:"Accounts"`
+	// Alerts are the firing alerts that were sent, by webhook and by the key of the alert.
+	Alerts map[string]map[string]SentAlert `json:"Alerts"`
+}
+
+// SentAlert is an alert that is not sent to the webhook again while it keeps firing.
+type SentAlert struct {
+	AccountId  string                `json:"AccountId"`
+	Dimensions []dimension.Dimension `json:"Dimensions,omitempty"`
+	Since      int64                 `json:"Since"`
+}
+
+// sent records that the webhook received the alert.
+func (s *State) sent(webhook string, key string, alert SentAlert) {
+	if s.Alerts[webhook] == nil {
+		s.Alerts[webhook] = map[string]SentAlert{}
+	}
+
+	s.Alerts[webhook][key] = alert
+}
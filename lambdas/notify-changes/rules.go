@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"shared/dimension"
+	"shared/report"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// The kinds of alerts a rule raises.
+const (
+	AlertBelowTarget    = "BelowTarget"
+	AlertScoreDrop      = "ScoreDrop"
+	AlertFailingControl = "FailingControl"
+)
+
+// anyControl matches every control in the FailingControls of a rule.
+const anyControl = "*"
+
+// validateRules checks the rules of the report against the configured webhooks, before anything is sent.
+func validateRules(rules []report.NotificationRule, webhooks map[string]Webhook) error {
+	seen := map[string]bool{}
+
+	for i, rule := range rules {
+		if rule.Name == "" {
+			return fmt.Errorf("notification rule %d has no name", i)
+		}
+
+		if seen[rule.Name] {
+			return fmt.Errorf("notification rule `%s` is given twice", rule.Name)
+		}
+		seen[rule.Name] = true
+
+		if rule.BelowTarget == 0 && rule.MaxDrop == 0 && len(rule.FailingControls) == 0 {
+			return fmt.Errorf("notification rule `%s` has no BelowTarget, MaxDrop or FailingControls", rule.Name)
+		}
+
+		if rule.BelowTarget < 0 || rule.BelowTarget > 100 {
+			return fmt.Errorf("notification rule `%s`: BelowTarget %s is not between 0 and 100", rule.Name, formatScore(rule.BelowTarget))
+		}
+
+		if rule.MaxDrop < 0 {
+			return fmt.Errorf("notification rule `%s`: MaxDrop %s is negative", rule.Name, formatScore(rule.MaxDrop))
+		}
+
+		if len(rule.Webhooks) == 0 {
+			return fmt.Errorf("notification rule `%s` has no webhooks", rule.Name)
+		}
+
+		for _, name := range rule.Webhooks {
+			if _, ok := webhooks[name]; !ok {
+				return fmt.Errorf("notification rule `%s` uses webhook `%s`, which is not configured in NOTIFICATION_WEBHOOKS", rule.Name, name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Matches reports whether the account is one of the accounts of the rule.
+func Matches(rule report.NotificationRule, reportName string, calculated *CalculatedScore) bool {
+	return matchesValue(rule.Reports, reportName) &&
+		matchesValue(rule.Workloads, calculated.Workload) &&
+		matchesValue(rule.Environments, calculated.Environment)
+}
+
+// Evaluate returns the alerts of the rule that fire for the account. The previous state is nil for an account that was
+// not scored before, failed are the controls the account fails in this run.
+func Evaluate(rule report.NotificationRule, reportName string, calculated *CalculatedScore, previous *AccountState, failed []string) []Alert {
+	var alerts []Alert
+
+	newAlert := func(kind string, message string) Alert {
+		return Alert{
+			Rule:        rule.Name,
+			Kind:        kind,
+			Report:      reportName,
+			AccountId:   calculated.AccountId,
+			AccountName: calculated.AccountName,
+			Workload:    calculated.Workload,
+			Environment: calculated.Environment,
+			Dimensions:  calculated.Dimensions,
+			Score:       calculated.Score,
+			Message:     message,
+		}
+	}
+
+	if rule.BelowTarget > 0 && calculated.Score < rule.BelowTarget {
+		alert := newAlert(AlertBelowTarget, fmt.Sprintf("The score of %s is %s%%, below the target of %s%%.",
+			describeAccount(calculated), formatScore(calculated.Score), formatScore(rule.BelowTarget)))
+		alert.Target = rule.BelowTarget
+		alerts = append(alerts, alert)
+	}
+
+	if rule.MaxDrop > 0 && previous != nil && previous.Score-calculated.Score > rule.MaxDrop {
+		alert := newAlert(AlertScoreDrop, fmt.Sprintf("The score of %s dropped from %s%% to %s%%, more than %s points.",
+			describeAccount(calculated), formatScore(previous.Score), formatScore(calculated.Score), formatScore(rule.MaxDrop)))
+		alert.PreviousScore = &previous.Score
+		alerts = append(alerts, alert)
+	}
+
+	for _, control := range failed {
+		if !slices.Contains(rule.FailingControls, control) && !slices.Contains(rule.FailingControls, anyControl) {
+			continue
+		}
+
+		alert := newAlert(AlertFailingControl, fmt.Sprintf("Control %s is failing in %s.", control, describeAccount(calculated)))
+		alert.Control = control
+		alerts = append(alerts, alert)
+	}
+
+	return alerts
+}
+
+// Key identifies the alert between runs, an alert with a key that was already sent is not sent again. The partitions
+// of a split account have alerts of their own.
+func (a Alert) Key() string {
+	key := a.Rule + "/" + dimension.Key(a.AccountId, a.Dimensions) + "/" + a.Kind
+
+	if a.Control != "" {
+		key += "/" + a.Control
+	}
+
+	return key
+}
+
+func matchesValue(values []string, value string) bool {
+	return len(values) == 0 || slices.Contains(values, value)
+}
+
+func describeAccount(calculated *CalculatedScore) string {
+	name := calculated.AccountName
+	if name == "" {
+		name = calculated.AccountId
+	}
+
+	details := []string{calculated.Workload, calculated.Environment}
+	for _, d := range calculated.Dimensions {
+		details = append(details, d.Name+"="+d.Value)
+	}
+
+	return fmt.Sprintf("%s (%s)", name, strings.Join(details, ", "))
+}
+
+// formatScore rounds to two decimals, a difference of two scores is not exact.
+func formatScore(value float64) string {
+	return strconv.FormatFloat(math.Round(value*100)/100, 'f', -1, 64)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"shared/dimension"
+	"shared/report"
+	"testing"
+)
+
+func TestMatches(t *testing.T) {
+	calculated := &CalculatedScore{Workload: "my-workload", Environment: "production"}
+
+	assert.True(t, Matches(report.NotificationRule{}, "cis", calculated))
+	assert.True(t, Matches(report.NotificationRule{Reports: []string{"cis"}, Environments: []string{"test", "production"}}, "cis", calculated))
+	assert.False(t, Matches(report.NotificationRule{Reports: []string{"pci"}}, "cis", calculated))
+	assert.False(t, Matches(report.NotificationRule{Workloads: []string{"other-workload"}}, "cis", calculated))
+}
+
+func TestEvaluate(t *testing.T) {
+	calculated := &CalculatedScore{AccountId: "111122223333", Workload: "my-workload", Environment: "production", Score: 79.5}
+
+	t.Run("Below target", func(t *testing.T) {
+		alerts := Evaluate(report.NotificationRule{Name: "target", BelowTarget: 80}, "cis", calculated, nil, nil)
+		assert.Len(t, alerts, 1)
+		assert.Equal(t, "target/111122223333/BelowTarget", alerts[0].Key())
+		assert.Equal(t, "The score of 111122223333 (my-workload, production) is 79.5%, below the target of 80%.", alerts[0].Message)
+		assert.Equal(t, 80.0, alerts[0].Target)
+	})
+
+	t.Run("Drop within the maximum", func(t *testing.T) {
+		alerts := Evaluate(report.NotificationRule{Name: "drops", MaxDrop: 5}, "cis", calculated, &AccountState{Score: 84.5}, nil)
+		assert.Empty(t, alerts)
+	})
+
+	t.Run("Drop without a previous score", func(t *testing.T) {
+		alerts := Evaluate(report.NotificationRule{Name: "drops", MaxDrop: 5}, "cis", calculated, nil, nil)
+		assert.Empty(t, alerts)
+	})
+
+	t.Run("Drop of more than the maximum", func(t *testing.T) {
+		alerts := Evaluate(report.NotificationRule{Name: "drops", MaxDrop: 5}, "cis", calculated, &AccountState{Score: 84.6}, nil)
+		assert.Len(t, alerts, 1)
+		assert.Equal(t, "The score of 111122223333 (my-workload, production) dropped from 84.6% to 79.5%, more than 5 points.", alerts[0].Message)
+	})
+
+	t.Run("Failing controls", func(t *testing.T) {
+		failed := []string{"EC2.2", "IAM.1"}
+
+		alerts := Evaluate(report.NotificationRule{Name: "critical", FailingControls: []string{"IAM.1", "S3.1"}}, "cis", calculated, nil, failed)
+		assert.Len(t, alerts, 1)
+		assert.Equal(t, "critical/111122223333/FailingControl/IAM.1", alerts[0].Key())
+
+		alerts = Evaluate(report.NotificationRule{Name: "all", FailingControls: []string{"*"}}, "cis", calculated, nil, failed)
+		assert.Len(t, alerts, 2)
+	})
+
+	t.Run("Partition of a split account", func(t *testing.T) {
+		partition := *calculated
+		partition.Dimensions = []dimension.Dimension{{Name: "Region", Value: "eu-west-1"}}
+
+		alerts := Evaluate(report.NotificationRule{Name: "target", BelowTarget: 80}, "cis", &partition, nil, nil)
+		assert.Len(t, alerts, 1)
+		assert.Equal(t, "target/111122223333/"+dimension.Id(partition.Dimensions)+"/BelowTarget", alerts[0].Key())
+		assert.Equal(t, "The score of 111122223333 (my-workload, production, Region=eu-west-1) is 79.5%, below the target of 80%.", alerts[0].Message)
+	})
+}
+
+func TestValidateRules(t *testing.T) {
+	webhooks := map[string]Webhook{"slack": {Url: "https://hooks.slack.com/services/T000/B000/XXXX", Format: FormatSlack}}
+
+	tests := []struct {
+		name  string
+		rules []report.NotificationRule
+		err   string
+	}{
+		{"Valid", []report.NotificationRule{{Name: "target", BelowTarget: 80, Webhooks: []string{"slack"}}}, ""},
+		{"Without a name", []report.NotificationRule{{BelowTarget: 80, Webhooks: []string{"slack"}}}, "notification rule 0 has no name"},
+		{"Given twice", []report.NotificationRule{
+			{Name: "target", BelowTarget: 80, Webhooks: []string{"slack"}},
+			{Name: "target", MaxDrop: 5, Webhooks: []string{"slack"}},
+		}, "notification rule `target` is given twice"},
+		{"Without a condition", []report.NotificationRule{{Name: "target", Webhooks: []string{"slack"}}},
+			"notification rule `target` has no BelowTarget, MaxDrop or FailingControls"},
+		{"Invalid target", []report.NotificationRule{{Name: "target", BelowTarget: 120, Webhooks: []string{"slack"}}},
+			"notification rule `target`: BelowTarget 120 is not between 0 and 100"},
+		{"Negative drop", []report.NotificationRule{{Name: "drops", MaxDrop: -1, Webhooks: []string{"slack"}}},
+			"notification rule `drops`: MaxDrop -1 is negative"},
+		{"Without webhooks", []report.NotificationRule{{Name: "target", BelowTarget: 80}}, "notification rule `target` has no webhooks"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := validateRules(test.rules, webhooks)
+
+			if test.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, test.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// The formats a webhook receives the alerts in.
+const (
+	FormatGeneric = "Generic"
+	FormatSlack   = "Slack"
+	FormatTeams   = "Teams"
+)
+
+// maxAlertsPerMessage keeps a Slack message within its 50 blocks, and a Teams card within a readable size.
+const maxAlertsPerMessage = 40
+
+// Webhook is an endpoint configured on the function, rules refer to it by name so the url stays out of the payload.
+type Webhook struct {
+	Url    string `json:"Url"`
+	Format string `json:"Format,omitempty"`
+}
+
+// GenericMessage is the body of the Generic format.
+type GenericMessage struct {
+	Report    string  `json:"Report"`
+	Timestamp int64   `json:"Timestamp"`
+	Alerts    []Alert `json:"Alerts"`
+}
+
+// The Slack incoming webhook message, with a section block for every alert.
+type (
+	SlackMessage struct {
+		Text   string       `json:"text"`
+		Blocks []SlackBlock `json:"blocks"`
+	}
+	SlackBlock struct {
+		Type string    `json:"type"`
+		Text SlackText `json:"text"`
+	}
+	SlackText struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+)
+
+// The Microsoft Teams message with an Adaptive Card, as accepted by the workflow and connector webhooks.
+type (
+	TeamsMessage struct {
+		Type        string            `json:"type"`
+		Attachments []TeamsAttachment `json:"attachments"`
+	}
+	TeamsAttachment struct {
+		ContentType string    `json:"contentType"`
+		Content     TeamsCard `json:"content"`
+	}
+	TeamsCard struct {
+		Schema  string         `json:"$schema"`
+		Type    string         `json:"type"`
+		Version string         `json:"version"`
+		Body    []TeamsElement `json:"body"`
+	}
+	TeamsElement struct {
+		Type   string      `json:"type"`
+		Text   string      `json:"text,omitempty"`
+		Weight string      `json:"weight,omitempty"`
+		Size   string      `json:"size,omitempty"`
+		Wrap   bool        `json:"wrap,omitempty"`
+		Facts  []TeamsFact `json:"facts,omitempty"`
+	}
+	TeamsFact struct {
+		Title string `json:"title"`
+		Value string `json:"value"`
+	}
+)
+
+// loadWebhooks reads the webhooks from NOTIFICATION_WEBHOOKS, a JSON object of webhooks by name.
+func loadWebhooks() (map[string]Webhook, error) {
+	webhooks := map[string]Webhook{}
+	value := os.Getenv("NOTIFICATION_WEBHOOKS")
+
+	if value == "" {
+		return webhooks, nil
+	}
+
+	err := json.Unmarshal([]byte(value), &webhooks)
+
+	if err != nil {
+		return nil, fmt.Errorf("NOTIFICATION_WEBHOOKS is not valid JSON: %w", err)
+	}
+
+	for name, webhook := range webhooks {
+		if webhook.Url == "" {
+			return nil, fmt.Errorf("webhook `%s` has no Url", name)
+		}
+
+		switch webhook.Format {
+		case "":
+			webhook.Format = FormatGeneric
+			webhooks[name] = webhook
+		case FormatGeneric, FormatSlack, FormatTeams:
+		default:
+			return nil, fmt.Errorf("webhook `%s`: format `%s` is not supported, use %s, %s or %s", name, webhook.Format, FormatGeneric, FormatSlack, FormatTeams)
+		}
+	}
+
+	return webhooks, nil
+}
+
+// EncodeMessages returns the bodies the alerts are sent in, at most maxAlertsPerMessage alerts per body.
+func EncodeMessages(format string, report string, timestamp int64, alerts []Alert) ([][]byte, error) {
+	var messages [][]byte
+
+	for _, chunk := range chunkAlerts(alerts) {
+		body, err := EncodeMessage(format, report, timestamp, chunk)
+
+		if err != nil {
+			return nil, err
+		}
+
+		messages = append(messages, body)
+	}
+
+	return messages, nil
+}
+
+// EncodeMessage returns the body of a single message with the alerts.
+func EncodeMessage(format string, report string, timestamp int64, alerts []Alert) ([]byte, error) {
+	var message any
+	switch format {
+	case FormatSlack:
+		message = EncodeSlack(report, alerts)
+	case FormatTeams:
+		message = EncodeTeams(report, timestamp, alerts)
+	default:
+		message = GenericMessage{Report: report, Timestamp: timestamp, Alerts: alerts}
+	}
+
+	return json.Marshal(message)
+}
+
+// chunkAlerts splits the alerts in the chunks of a message, at most maxAlertsPerMessage alerts per chunk.
+func chunkAlerts(alerts []Alert) [][]Alert {
+	var chunks [][]Alert
+
+	for start := 0; start < len(alerts); start += maxAlertsPerMessage {
+		chunks = append(chunks, alerts[start:min(start+maxAlertsPerMessage, len(alerts))])
+	}
+
+	return chunks
+}
+
+// EncodeSlack returns a message with the number of alerts as fallback text, and a section for every alert.
+func EncodeSlack(report string, alerts []Alert) SlackMessage {
+	message := SlackMessage{Text: fmt.Sprintf("%d security posture alerts for %s", len(alerts), report)}
+
+	for _, alert := range alerts {
+		message.Blocks = append(message.Blocks, SlackBlock{Type: "section", Text: SlackText{
+			Type: "mrkdwn",
+			Text: fmt.Sprintf("*%s* (rule `%s`)\n%s", alert.Kind, slackEscape(alert.Rule), slackEscape(alert.Message)),
+		}})
+	}
+
+	return message
+}
+
+// EncodeTeams returns an Adaptive Card with a title, and the message and facts of every alert.
+func EncodeTeams(report string, timestamp int64, alerts []Alert) TeamsMessage {
+	card := TeamsCard{
+		Schema:  "http://adaptivecards.io/schemas/adaptive-card.json",
+		Type:    "AdaptiveCard",
+		Version: "1.4",
+		Body: []TeamsElement{{
+			Type:   "TextBlock",
+			Text:   fmt.Sprintf("%d security posture alerts for %s", len(alerts), report),
+			Weight: "Bolder",
+			Size:   "Medium",
+			Wrap:   true,
+		}},
+	}
+
+	for _, alert := range alerts {
+		account := alert.AccountId
+		if alert.AccountName != "" {
+			account = fmt.Sprintf("%s (%s)", alert.AccountName, alert.AccountId)
+		}
+
+		card.Body = append(card.Body,
+			TeamsElement{Type: "TextBlock", Text: alert.Message, Wrap: true},
+			TeamsElement{Type: "FactSet", Facts: []TeamsFact{
+				{Title: "Rule", Value: alert.Rule},
+				{Title: "Alert", Value: alert.Kind},
+				{Title: "Account", Value: account},
+				{Title: "Score", Value: formatScore(alert.Score) + "%"},
+				{Title: "Run", Value: time.Unix(timestamp, 0).UTC().Format(time.RFC3339)},
+			}},
+		)
+	}
+
+	return TeamsMessage{
+		Type:        "message",
+		Attachments: []TeamsAttachment{{ContentType: "application/vnd.microsoft.card.adaptive", Content: card}},
+	}
+}
+
+// post sends a message to the webhook, any status other than 2xx is an error.
+func post(ctx context.Context, client *http.Client, url string, body []byte) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+
+	if err != nil {
+		return err
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "aws-security-posture")
+
+	response, err := client.Do(request)
+
+	if err != nil {
+		return err
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode/100 != 2 {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+		return fmt.Errorf("webhook returned %s: %s", response.Status, bytes.TrimSpace(message))
+	}
+
+	return nil
+}
+
+// slackEscape escapes the characters Slack uses for links and mentions.
+func slackEscape(value string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(value)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"testing"
+)
+
+func TestLoadWebhooks(t *testing.T) {
+	t.Run("Default format", func(t *testing.T) {
+		_ = os.Setenv("NOTIFICATION_WEBHOOKS", `{"audit": {"Url": "https://example.com/alerts"}}`)
+		defer func() { _ = os.Setenv("NOTIFICATION_WEBHOOKS", "") }()
+
+		webhooks, err := loadWebhooks()
+		require.NoError(t, err)
+		assert.Equal(t, map[string]Webhook{"audit": {Url: "https://example.com/alerts", Format: FormatGeneric}}, webhooks)
+	})
+
+	t.Run("Not configured", func(t *testing.T) {
+		_ = os.Setenv("NOTIFICATION_WEBHOOKS", "")
+
+		webhooks, err := loadWebhooks()
+		require.NoError(t, err)
+		assert.Empty(t, webhooks)
+	})
+
+	t.Run("Unsupported format", func(t *testing.T) {
+		_ = os.Setenv("NOTIFICATION_WEBHOOKS", `{"chat": {"Url": "https://example.com/alerts", "Format": "Mattermost"}}`)
+		defer func() { _ = os.Setenv("NOTIFICATION_WEBHOOKS", "") }()
+
+		_, err := loadWebhooks()
+		assert.EqualError(t, err, "webhook `chat`: format `Mattermost` is not supported, use Generic, Slack or Teams")
+	})
+
+	t.Run("Without url", func(t *testing.T) {
+		_ = os.Setenv("NOTIFICATION_WEBHOOKS", `{"chat": {"Format": "Slack"}}`)
+		defer func() { _ = os.Setenv("NOTIFICATION_WEBHOOKS", "") }()
+
+		_, err := loadWebhooks()
+		assert.EqualError(t, err, "webhook `chat` has no Url")
+	})
+}
+
+func TestEncodeMessages(t *testing.T) {
+	var alerts []Alert
+	for i := 0; i < 45; i++ {
+		alerts = append(alerts, Alert{Rule: "all", Kind: AlertFailingControl, Control: fmt.Sprintf("IAM.%d", i), Message: "<b> & more"})
+	}
+
+	t.Run("Split into messages", func(t *testing.T) {
+		messages, err := EncodeMessages(FormatSlack, "cis", 1691920532, alerts)
+		require.NoError(t, err)
+		require.Len(t, messages, 2)
+
+		var message SlackMessage
+		require.NoError(t, json.Unmarshal(messages[1], &message))
+		assert.Equal(t, "5 security posture alerts for cis", message.Text)
+		assert.Len(t, message.Blocks, 5)
+		assert.Equal(t, "*FailingControl* (rule `all`)\n&lt;b&gt; &amp; more", message.Blocks[0].Text.Text)
+	})
+
+	t.Run("Teams card", func(t *testing.T) {
+		messages, err := EncodeMessages(FormatTeams, "cis", 1691920532, alerts[:1])
+		require.NoError(t, err)
+
+		var message TeamsMessage
+		require.NoError(t, json.Unmarshal(messages[0], &message))
+		assert.Equal(t, "application/vnd.microsoft.card.adaptive", message.Attachments[0].ContentType)
+
+		card := message.Attachments[0].Content
+		assert.Equal(t, "AdaptiveCard", card.Type)
+		assert.Len(t, card.Body, 3)
+		assert.Equal(t, "1 security posture alerts for cis", card.Body[0].Text)
+		assert.Contains(t, card.Body[2].Facts, TeamsFact{Title: "Run", Value: "2023-08-13T09:55:32Z"})
+	})
+}
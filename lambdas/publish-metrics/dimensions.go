@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"shared/dimension"
+	"strings"
+)
+
+const (
+	defaultNamespace = "SecurityPosture"
+	// maxDimensions is the CloudWatch limit of dimensions per metric, one is kept for the Status dimension.
+	maxDimensions = 30
+)
+
+// resolveNamespace returns the namespace of the report, the AWS/ prefix is reserved for the metrics of AWS services.
+func resolveNamespace(namespace string) (string, error) {
+	if namespace == "" {
+		return defaultNamespace, nil
+	}
+
+	if strings.HasPrefix(namespace, "AWS/") {
+		return "", fmt.Errorf("namespace `%s` is reserved for AWS services", namespace)
+	}
+
+	return namespace, nil
+}
+
+// validateDimensionSets checks the dimension sets of the report, without sets the default dimensions are published.
+func validateDimensionSets(sets [][]string) error {
+	for _, set := range sets {
+		if len(set) == 0 {
+			return fmt.Errorf("dimension set is empty")
+		}
+
+		if len(set) >= maxDimensions {
+			return fmt.Errorf("dimension set %v has more than %d dimensions", set, maxDimensions-1)
+		}
+
+		seen := map[string]bool{}
+		for _, name := range set {
+			if name == "" || name == dimension.Status {
+				return fmt.Errorf("dimension set %v: `%s` is not a valid dimension", set, name)
+			}
+
+			if seen[name] {
+				return fmt.Errorf("dimension set %v: `%s` is given twice", set, name)
+			}
+			seen[name] = true
+		}
+	}
+
+	return nil
+}
+
+// renderDimensionSets returns the dimensions of every set the metrics of the account are published under.
+func (x *Lambda) renderDimensionSets(report string, sets [][]string, calculatedScore *CalculatedScore) [][]dimension.Dimension {
+	labels := dimension.Labels{
+		Report:             report,
+		AccountId:          calculatedScore.AccountId,
+		AccountName:        calculatedScore.AccountName,
+		Workload:           calculatedScore.Workload,
+		Environment:        calculatedScore.Environment,
+		OrganizationalUnit: calculatedScore.OrganizationalUnit,
+		Dimensions:         calculatedScore.Dimensions,
+	}
+
+	if len(sets) == 0 {
+		return [][]dimension.Dimension{dimension.Metric(labels, nil)}
+	}
+
+	var rendered [][]dimension.Dimension
+	for _, set := range sets {
+		rendered = append(rendered, dimension.Metric(labels, set))
+	}
+
+	return rendered
+}
+
+// renderDimensions returns the dimensions as CloudWatch dimensions.
+func renderDimensions(dimensions []dimension.Dimension) []types.Dimension {
+	rendered := make([]types.Dimension, 0, len(dimensions))
+
+	for _, dimension := range dimensions {
+		rendered = append(rendered, types.Dimension{
+			Name:  aws.String(dimension.Name),
+			Value: aws.String(dimension.Value),
+		})
+	}
+
+	return rendered
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"io"
+	"log"
+	"strings"
+)
+
+// maxEmbeddedMetrics is the maximum number of metrics of a metric directive, and the maximum number of values of a
+// metric in a single document.
+const maxEmbeddedMetrics = 100
+
+// EmbeddedMetric is a metric definition of a CloudWatch Embedded Metric Format directive.
+type EmbeddedMetric struct {
+	Name string `json:"Name"`
+	Unit string `json:"Unit,omitempty"`
+}
+
+// EmbeddedDirective tells CloudWatch which members of the document to extract as metrics, and with what dimensions.
+type EmbeddedDirective struct {
+	Namespace  string           `json:"Namespace"`
+	Dimensions [][]string       `json:"Dimensions"`
+	Metrics    []EmbeddedMetric `json:"Metrics"`
+}
+
+// EmbeddedMetadata is the `_aws` member of an embedded document.
+type EmbeddedMetadata struct {
+	Timestamp         int64               `json:"Timestamp"`
+	CloudWatchMetrics []EmbeddedDirective `json:"CloudWatchMetrics"`
+}
+
+// embeddedGroup holds the datums that share a timestamp and dimensions, these are written as one document.
+type embeddedGroup struct {
+	timestamp  int64
+	dimensions []types.Dimension
+	names      []string
+	units      map[string]string
+	values     map[string][]float64
+}
+
+// EmbeddedSink writes the metrics as Embedded Metric Format documents, CloudWatch extracts these from the log.
+type EmbeddedSink struct {
+	output    io.Writer
+	namespace string
+}
+
+func (s *EmbeddedSink) Publish(_ context.Context, data []types.MetricDatum) error {
+	documents, err := EncodeEmbedded(s.namespace, data)
+
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Writing %d metrics in %d embedded metric documents", len(data), len(documents))
+	return WriteEmbedded(s.output, documents)
+}
+
+// EncodeEmbedded returns the datums as Embedded Metric Format documents. Datums that share a timestamp and dimensions
+// are written in one document, the values of the same metric are written as an array.
+func EncodeEmbedded(namespace string, data []types.MetricDatum) ([]map[string]any, error) {
+	var groups []*embeddedGroup
+	index := map[string]*embeddedGroup{}
+
+	for _, datum := range data {
+		timestamp := aws.ToTime(datum.Timestamp).UnixMilli()
+		key := embeddedKey(timestamp, datum.Dimensions)
+		group, ok := index[key]
+
+		if !ok {
+			group = &embeddedGroup{
+				timestamp:  timestamp,
+				dimensions: datum.Dimensions,
+				units:      map[string]string{},
+				values:     map[string][]float64{},
+			}
+			index[key] = group
+			groups = append(groups, group)
+		}
+
+		name := aws.ToString(datum.MetricName)
+		if _, ok := group.values[name]; !ok {
+			group.names = append(group.names, name)
+			group.units[name] = string(datum.Unit)
+		}
+
+		group.values[name] = append(group.values[name], aws.ToFloat64(datum.Value))
+	}
+
+	var documents []map[string]any
+	for _, group := range groups {
+		groupDocuments, err := group.encode(namespace)
+
+		if err != nil {
+			return nil, err
+		}
+
+		documents = append(documents, groupDocuments...)
+	}
+
+	return documents, nil
+}
+
+// WriteEmbedded writes every document on a line of its own, the Lambda runtime sends these to CloudWatch Logs.
+func WriteEmbedded(w io.Writer, documents []map[string]any) error {
+	for _, document := range documents {
+		line, err := json.Marshal(document)
+
+		if err != nil {
+			return err
+		}
+
+		_, err = fmt.Fprintf(w, "%s\n", line)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// encode splits the group into documents of at most maxEmbeddedMetrics metrics, with at most maxEmbeddedMetrics values
+// each.
+func (g *embeddedGroup) encode(namespace string) ([]map[string]any, error) {
+	var names []string
+	members := map[string]any{}
+
+	for _, dimension := range g.dimensions {
+		name := aws.ToString(dimension.Name)
+
+		if _, ok := g.values[name]; ok {
+			return nil, fmt.Errorf("metric `%s` has the same name as one of its dimensions", name)
+		}
+
+		names = append(names, name)
+		members[name] = aws.ToString(dimension.Value)
+	}
+
+	var documents []map[string]any
+	for offset := 0; ; offset += maxEmbeddedMetrics {
+		var metrics []string
+
+		for _, name := range g.names {
+			if len(g.values[name]) > offset {
+				metrics = append(metrics, name)
+			}
+		}
+
+		if len(metrics) == 0 {
+			break
+		}
+
+		for start := 0; start < len(metrics); start += maxEmbeddedMetrics {
+			end := min(start+maxEmbeddedMetrics, len(metrics))
+			documents = append(documents, g.document(namespace, names, members, metrics[start:end], offset))
+		}
+	}
+
+	return documents, nil
+}
+
+func (g *embeddedGroup) document(namespace string, dimensions []string, members map[string]any, metrics []string, offset int) map[string]any {
+	document := map[string]any{}
+
+	for name, value := range members {
+		document[name] = value
+	}
+
+	directive := EmbeddedDirective{Namespace: namespace, Dimensions: [][]string{dimensions}}
+	for _, name := range metrics {
+		values := g.values[name][offset:min(offset+maxEmbeddedMetrics, len(g.values[name]))]
+		directive.Metrics = append(directive.Metrics, EmbeddedMetric{Name: name, Unit: g.units[name]})
+
+		if len(values) == 1 {
+			document[name] = values[0]
+		} else {
+			document[name] = values
+		}
+	}
+
+	document["_aws"] = EmbeddedMetadata{Timestamp: g.timestamp, CloudWatchMetrics: []EmbeddedDirective{directive}}
+
+	return document
+}
+
+func embeddedKey(timestamp int64, dimensions []types.Dimension) string {
+	parts := []string{fmt.Sprintf("%d", timestamp)}
+
+	for _, dimension := range dimensions {
+		parts = append(parts, aws.ToString(dimension.Name)+"="+aws.ToString(dimension.Value))
+	}
+
+	return strings.Join(parts, "\x00")
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"time"
+)
+
+func embeddedDatum(name string, value float64, unit types.StandardUnit, dimensions ...string) types.MetricDatum {
+	datum := types.MetricDatum{
+		Timestamp:  aws.Time(time.Unix(1691920532, 0)),
+		MetricName: aws.String(name),
+		Value:      aws.Float64(value),
+		Unit:       unit,
+	}
+
+	for i := 0; i < len(dimensions); i += 2 {
+		datum.Dimensions = append(datum.Dimensions, types.Dimension{Name: aws.String(dimensions[i]), Value: aws.String(dimensions[i+1])})
+	}
+
+	return datum
+}
+
+// verifyEmbedded validates the written lines against the published schema, and checks that every dimension and metric
+// the directives refer to is a member of the document.
+func verifyEmbedded(t *testing.T, output string) []map[string]any {
+	schema, err := jsonschema.Compile("testdata/emf.schema.json")
+	require.NoError(t, err)
+
+	var documents []map[string]any
+	for _, line := range strings.Split(strings.TrimSuffix(output, "\n"), "\n") {
+		var document map[string]any
+		assert.NoError(t, json.Unmarshal([]byte(line), &document))
+		assert.NoError(t, schema.Validate(document))
+
+		metadata := document["_aws"].(map[string]any)
+		for _, directive := range metadata["CloudWatchMetrics"].([]any) {
+			for _, set := range directive.(map[string]any)["Dimensions"].([]any) {
+				for _, name := range set.([]any) {
+					assert.IsType(t, "", document[name.(string)], "dimension %s", name)
+				}
+			}
+			for _, metric := range directive.(map[string]any)["Metrics"].([]any) {
+				switch value := document[metric.(map[string]any)["Name"].(string)].(type) {
+				case float64:
+				case []any:
+					assert.LessOrEqual(t, len(value), maxEmbeddedMetrics)
+				default:
+					t.Errorf("metric %v is not a number or a list of numbers", metric)
+				}
+			}
+		}
+
+		documents = append(documents, document)
+	}
+
+	return documents
+}
+
+func TestEncodeEmbedded(t *testing.T) {
+	t.Run("Group the metrics of an account", func(t *testing.T) {
+		data := []types.MetricDatum{
+			embeddedDatum("Score", 80, types.StandardUnitPercent, "Report", "cis", "Workload", "payments"),
+			embeddedDatum("Controls", 10, types.StandardUnitCount, "Report", "cis", "Workload", "payments"),
+			embeddedDatum("Accounts", 1, types.StandardUnitCount, "Report", "cis", "Workload", "payments", "Status", "SCORED"),
+		}
+
+		documents, err := EncodeEmbedded("SecurityPosture", data)
+		assert.NoError(t, err)
+
+		var output bytes.Buffer
+		assert.NoError(t, WriteEmbedded(&output, documents))
+		written := verifyEmbedded(t, output.String())
+
+		assert.Equal(t, 2, len(written))
+		assert.Equal(t, map[string]any{
+			"Report":   "cis",
+			"Workload": "payments",
+			"Score":    float64(80),
+			"Controls": float64(10),
+			"_aws": map[string]any{
+				"Timestamp": float64(1691920532000),
+				"CloudWatchMetrics": []any{
+					map[string]any{
+						"Namespace":  "SecurityPosture",
+						"Dimensions": []any{[]any{"Report", "Workload"}},
+						"Metrics": []any{
+							map[string]any{"Name": "Score", "Unit": "Percent"},
+							map[string]any{"Name": "Controls", "Unit": "Count"},
+						},
+					},
+				},
+			},
+		}, written[0])
+		assert.Equal(t, float64(1), written[1]["Accounts"])
+		assert.Equal(t, "SCORED", written[1]["Status"])
+	})
+
+	t.Run("Write the values of accounts sharing dimensions as a list", func(t *testing.T) {
+		var data []types.MetricDatum
+		for i := 0; i < 250; i++ {
+			data = append(data, embeddedDatum("Score", float64(i%100), types.StandardUnitPercent, "Report", "cis"))
+		}
+		data = append(data, embeddedDatum("Findings", 5, types.StandardUnitCount, "Report", "cis"))
+
+		documents, err := EncodeEmbedded("SecurityPosture", data)
+		assert.NoError(t, err)
+
+		var output bytes.Buffer
+		assert.NoError(t, WriteEmbedded(&output, documents))
+		written := verifyEmbedded(t, output.String())
+
+		assert.Equal(t, 3, len(written))
+		assert.Equal(t, 100, len(written[0]["Score"].([]any)))
+		assert.Equal(t, float64(5), written[0]["Findings"])
+		assert.Equal(t, 100, len(written[1]["Score"].([]any)))
+		assert.Equal(t, 50, len(written[2]["Score"].([]any)))
+		assert.Nil(t, written[2]["Findings"])
+	})
+
+	t.Run("Reject a metric named after a dimension", func(t *testing.T) {
+		_, err := EncodeEmbedded("SecurityPosture", []types.MetricDatum{
+			embeddedDatum("Report", 1, types.StandardUnitCount, "Report", "cis"),
+		})
+		assert.EqualError(t, err, "metric `Report` has the same name as one of its dimensions")
+	})
+}
@@ -2,86 +2,249 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"io"
+	"net/http"
+	"os"
+	"shared/blobstore"
+	"shared/dimension"
+	"shared/membership"
+	"shared/score"
+	"sort"
 	"time"
 )
 
 type Lambda struct {
-	ctx    context.Context
-	client *cloudwatch.Client
+	ctx          context.Context
+	client       *cloudwatch.Client
+	store        blobstore.BlobStore
+	retryBackoff time.Duration
+	output       io.Writer
+	httpClient   *http.Client
 }
 
 func New(cfg aws.Config) *Lambda {
 	m := new(Lambda)
 	m.client = cloudwatch.NewFromConfig(cfg)
+	m.store = blobstore.NewFromConfig(cfg)
+	m.retryBackoff = defaultRetryBackoff
+	m.output = os.Stdout
+	m.httpClient = &http.Client{Timeout: 30 * time.Second}
 	return m
 }
 
 func (x *Lambda) Handler(ctx context.Context, request Request) (Response, error) {
 	x.ctx = ctx
 
+	namespace, err := resolveNamespace(request.Options.Namespace)
+
+	if err != nil {
+		return Response{}, err
+	}
+
+	err = validateDimensionSets(request.Options.DimensionSets)
+
+	if err != nil {
+		return Response{}, err
+	}
+
+	sinks, err := x.resolveSinks(request.Options.Sinks, namespace)
+
+	if err != nil {
+		return Response{}, err
+	}
+
+	var data []types.MetricDatum
+
 	for _, calculatedScore := range request.Accounts {
-		var data []types.MetricDatum
+		status := score.Resolve(calculatedScore.Status)
 
-		data = append(data, types.MetricDatum{
-			Timestamp:  aws.Time(time.Unix(request.Timestamp, 0)),
-			MetricName: aws.String("Score"),
-			Dimensions: x.renderDimensions(request.Report, calculatedScore.Workload, calculatedScore.Environment),
-			Value:      aws.Float64(calculatedScore.Score),
-			Unit:       types.StandardUnitPercent,
-		})
+		if status == score.StatusExcluded {
+			continue
+		}
 
-		data = append(data, types.MetricDatum{
-			Timestamp:  aws.Time(time.Unix(request.Timestamp, 0)),
-			MetricName: aws.String("Controls"),
-			Dimensions: x.renderDimensions(request.Report, calculatedScore.Workload, calculatedScore.Environment),
-			Value:      aws.Float64(float64(calculatedScore.ControlCount)),
-			Unit:       types.StandardUnitCount,
-		})
+		// The metrics are published once for every dimension set, CloudWatch aggregates the accounts within a set.
+		for _, set := range x.renderDimensionSets(request.Report, request.Options.DimensionSets, calculatedScore) {
+			dimensions := renderDimensions(set)
+			data = append(data, x.renderScore(request, calculatedScore, dimensions)...)
+			data = append(data, x.renderBaseline(request, calculatedScore, dimensions)...)
 
+			// Every account is counted by its status, so accounts without a score are visible without lowering the score.
+			data = append(data, types.MetricDatum{
+				Timestamp:  aws.Time(time.Unix(request.Timestamp, 0)),
+				MetricName: aws.String("Accounts"),
+				Dimensions: renderDimensions(dimension.WithStatus(set, string(status))),
+				Value:      aws.Float64(1),
+				Unit:       types.StandardUnitCount,
+			})
+		}
+	}
+
+	if request.NonOrganization.Handling != "" {
+		data = append(data, x.renderNonOrganization(request)...)
+	}
+
+	if request.Options.ControlMetrics {
+		controls, err := x.renderControls(request)
+
+		if err != nil {
+			return Response{}, err
+		}
+
+		data = append(data, controls...)
+	}
+
+	return Response{}, publish(ctx, sinks, data)
+}
+
+// renderNonOrganization counts the accounts outside the organization, labelled with how the report handled them.
+func (x *Lambda) renderNonOrganization(request Request) []types.MetricDatum {
+	var data []types.MetricDatum
+
+	counts := []struct {
+		membership membership.Membership
+		count      int
+	}{
+		{membership.Invited, request.NonOrganization.Invited},
+		{membership.Unknown, request.NonOrganization.Unknown},
+	}
+
+	for _, count := range counts {
 		data = append(data, types.MetricDatum{
 			Timestamp:  aws.Time(time.Unix(request.Timestamp, 0)),
-			MetricName: aws.String("Findings"),
-			Dimensions: x.renderDimensions(request.Report, calculatedScore.Workload, calculatedScore.Environment),
-			Value:      aws.Float64(float64(calculatedScore.FindingCount)),
-			Unit:       types.StandardUnitCount,
+			MetricName: aws.String("NonOrganizationAccounts"),
+			Dimensions: []types.Dimension{
+				{Name: aws.String("Report"), Value: aws.String(request.Report)},
+				{Name: aws.String("Membership"), Value: aws.String(string(count.membership))},
+				{Name: aws.String("Handling"), Value: aws.String(string(request.NonOrganization.Handling))},
+			},
+			Value: aws.Float64(float64(count.count)),
+			Unit:  types.StandardUnitCount,
 		})
+	}
 
-		// NOTE: The maximum number of metrics is 1000, we can optimize the API usage in the future here.
-		err := x.publishBatch(data)
+	return data
+}
+
+// renderControls counts the scored accounts failing each control, from the failed controls recorded by calculate-score.
+// An account of a split report counts once, however many of its partitions fail the control.
+func (x *Lambda) renderControls(request Request) ([]types.MetricDatum, error) {
+	failing := map[string]map[string]bool{}
+
+	for _, calculatedScore := range request.Accounts {
+		if score.Resolve(calculatedScore.Status) != score.StatusScored || calculatedScore.FailedControls == "" {
+			continue
+		}
+
+		data, err := x.store.Download(x.ctx, request.Bucket, calculatedScore.FailedControls)
 
 		if err != nil {
-			return Response{}, err
+			return nil, err
 		}
+
+		var controls []string
+		err = json.Unmarshal(data, &controls)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, control := range controls {
+			if failing[control] == nil {
+				failing[control] = map[string]bool{}
+			}
+			failing[control][calculatedScore.AccountId] = true
+		}
+	}
+
+	controls := make([]string, 0, len(failing))
+	for control := range failing {
+		controls = append(controls, control)
+	}
+	sort.Strings(controls)
+
+	var data []types.MetricDatum
+	for _, control := range controls {
+		data = append(data, types.MetricDatum{
+			Timestamp:  aws.Time(time.Unix(request.Timestamp, 0)),
+			MetricName: aws.String("FailingAccounts"),
+			Dimensions: []types.Dimension{
+				{Name: aws.String("Report"), Value: aws.String(request.Report)},
+				{Name: aws.String("Control"), Value: aws.String(control)},
+			},
+			Value: aws.Float64(float64(len(failing[control]))),
+			Unit:  types.StandardUnitCount,
+		})
 	}
 
-	return Response{}, nil
+	return data, nil
 }
 
-func (x *Lambda) publishBatch(data []types.MetricDatum) error {
-	_, err := x.client.PutMetricData(x.ctx, &cloudwatch.PutMetricDataInput{
-		Namespace:  aws.String("SecurityPosture"),
-		MetricData: data,
-	})
+// renderBaseline returns the remaining grace period of a newly joined account, which is not scored yet.
+func (x *Lambda) renderBaseline(request Request, calculatedScore *CalculatedScore, dimensions []types.Dimension) []types.MetricDatum {
+	if score.Resolve(calculatedScore.Status) != score.StatusBaselining || calculatedScore.Baseline == nil {
+		return nil
+	}
 
-	return err
+	return []types.MetricDatum{{
+		Timestamp:  aws.Time(time.Unix(request.Timestamp, 0)),
+		MetricName: aws.String("GraceRemainingHours"),
+		Dimensions: dimensions,
+		Value:      aws.Float64(float64(calculatedScore.Baseline.RemainingHours)),
+		Unit:       types.StandardUnitNone,
+	}}
 }
 
-func (x *Lambda) renderDimensions(report string, workload string, environment string) []types.Dimension {
-	return []types.Dimension{
-		{
-			Name:  aws.String("Report"),
-			Value: aws.String(report),
-		},
-		{
-			Name:  aws.String("Workload"),
-			Value: aws.String(workload),
-		},
-		{
-			Name:  aws.String("Environment"),
-			Value: aws.String(environment),
-		},
+// renderScore returns the score metrics, these are only published for accounts of which the findings were scored.
+func (x *Lambda) renderScore(request Request, calculatedScore *CalculatedScore, dimensions []types.Dimension) []types.MetricDatum {
+	var data []types.MetricDatum
+
+	if score.Resolve(calculatedScore.Status) != score.StatusScored {
+		return data
 	}
+
+	data = append(data, types.MetricDatum{
+		Timestamp:  aws.Time(time.Unix(request.Timestamp, 0)),
+		MetricName: aws.String("Score"),
+		Dimensions: dimensions,
+		Value:      aws.Float64(calculatedScore.Score),
+		Unit:       types.StandardUnitPercent,
+	})
+
+	data = append(data, types.MetricDatum{
+		Timestamp:  aws.Time(time.Unix(request.Timestamp, 0)),
+		MetricName: aws.String("Controls"),
+		Dimensions: dimensions,
+		Value:      aws.Float64(float64(calculatedScore.ControlCount)),
+		Unit:       types.StandardUnitCount,
+	})
+
+	data = append(data, types.MetricDatum{
+		Timestamp:  aws.Time(time.Unix(request.Timestamp, 0)),
+		MetricName: aws.String("ControlsPassed"),
+		Dimensions: dimensions,
+		Value:      aws.Float64(float64(calculatedScore.ControlPassedCount)),
+		Unit:       types.StandardUnitCount,
+	})
+
+	data = append(data, types.MetricDatum{
+		Timestamp:  aws.Time(time.Unix(request.Timestamp, 0)),
+		MetricName: aws.String("ControlsFailed"),
+		Dimensions: dimensions,
+		Value:      aws.Float64(float64(calculatedScore.ControlFailedCount)),
+		Unit:       types.StandardUnitCount,
+	})
+
+	data = append(data, types.MetricDatum{
+		Timestamp:  aws.Time(time.Unix(request.Timestamp, 0)),
+		MetricName: aws.String("Findings"),
+		Dimensions: dimensions,
+		Value:      aws.Float64(float64(calculatedScore.FindingCount)),
+		Unit:       types.StandardUnitCount,
+	})
+
+	return data
 }
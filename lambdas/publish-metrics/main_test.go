@@ -1,15 +1,24 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/awsdocs/aws-doc-sdk-examples/gov2/testtools"
 	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"shared/dimension"
+	"shared/membership"
+	"shared/report"
+	"shared/score"
 	"testing"
 	"time"
 )
@@ -22,7 +31,7 @@ func readEvent(path string) Request {
 	return event
 }
 
-func PutMetricDataInput(report string, workload string, environment string, score float64, controls int, findings int) *cloudwatch.PutMetricDataInput {
+func PutMetricDataInput(report string, workload string, environment string, score float64, controls int, passed int, failed int, findings int) *cloudwatch.PutMetricDataInput {
 	return &cloudwatch.PutMetricDataInput{
 		Namespace: aws.String("SecurityPosture"),
 		MetricData: []types.MetricDatum{
@@ -66,6 +75,46 @@ func PutMetricDataInput(report string, workload string, environment string, scor
 				Value: aws.Float64(float64(controls)),
 				Unit:  types.StandardUnitCount,
 			},
+			types.MetricDatum{
+				Timestamp:  aws.Time(time.Unix(1691920532, 0)),
+				MetricName: aws.String("ControlsPassed"),
+				Dimensions: []types.Dimension{
+					types.Dimension{
+						Name:  aws.String("Report"),
+						Value: aws.String(report),
+					},
+					types.Dimension{
+						Name:  aws.String("Workload"),
+						Value: aws.String(workload),
+					},
+					types.Dimension{
+						Name:  aws.String("Environment"),
+						Value: aws.String(environment),
+					},
+				},
+				Value: aws.Float64(float64(passed)),
+				Unit:  types.StandardUnitCount,
+			},
+			types.MetricDatum{
+				Timestamp:  aws.Time(time.Unix(1691920532, 0)),
+				MetricName: aws.String("ControlsFailed"),
+				Dimensions: []types.Dimension{
+					types.Dimension{
+						Name:  aws.String("Report"),
+						Value: aws.String(report),
+					},
+					types.Dimension{
+						Name:  aws.String("Workload"),
+						Value: aws.String(workload),
+					},
+					types.Dimension{
+						Name:  aws.String("Environment"),
+						Value: aws.String(environment),
+					},
+				},
+				Value: aws.Float64(float64(failed)),
+				Unit:  types.StandardUnitCount,
+			},
 			types.MetricDatum{
 				Timestamp:  aws.Time(time.Unix(1691920532, 0)),
 				MetricName: aws.String("Findings"),
@@ -86,7 +135,35 @@ func PutMetricDataInput(report string, workload string, environment string, scor
 				Value: aws.Float64(float64(findings)),
 				Unit:  types.StandardUnitCount,
 			},
+			StatusDatum(report, workload, environment, "SCORED"),
+		},
+	}
+}
+
+func StatusDatum(report string, workload string, environment string, status score.Status) types.MetricDatum {
+	return types.MetricDatum{
+		Timestamp:  aws.Time(time.Unix(1691920532, 0)),
+		MetricName: aws.String("Accounts"),
+		Dimensions: []types.Dimension{
+			{
+				Name:  aws.String("Report"),
+				Value: aws.String(report),
+			},
+			{
+				Name:  aws.String("Workload"),
+				Value: aws.String(workload),
+			},
+			{
+				Name:  aws.String("Environment"),
+				Value: aws.String(environment),
+			},
+			{
+				Name:  aws.String("Status"),
+				Value: aws.String(string(status)),
+			},
 		},
+		Value: aws.Float64(1),
+		Unit:  types.StandardUnitCount,
 	}
 }
 
@@ -98,31 +175,416 @@ func TestHandler(t *testing.T) {
 		stubber := testtools.NewStubber()
 		lambda := New(*stubber.SdkConfig)
 
-		// TODO: Do 1 API Call for multiple metrics
+		// The metrics of every account are published in a single batch.
+		input := PutMetricDataInput("aws-foundational-security-best-practices", "my-workload", "development", 80, 10, 8, 2, 20000)
+		input.MetricData = append(input.MetricData, PutMetricDataInput("aws-foundational-security-best-practices", "my-workload", "test", 90, 14, 13, 1, 120000).MetricData...)
+
+		stubber.Add(testtools.Stub{
+			OperationName: "PutMetricData",
+			Input:         input,
+			Output:        &cloudwatch.PutMetricDataOutput{},
+		})
+
+		_, err := lambda.Handler(ctx, event)
+		testtools.ExitTest(stubber, t)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Publish Score with split dimensions", func(t *testing.T) {
+		stubber := testtools.NewStubber()
+		lambda := New(*stubber.SdkConfig)
+
+		input := PutMetricDataInput("aws-foundational-security-best-practices", "my-workload", "development", 80, 10, 8, 2, 20000)
+		region := types.Dimension{
+			Name:  aws.String("Region"),
+			Value: aws.String("eu-west-1"),
+		}
+		for i := range input.MetricData[:5] {
+			input.MetricData[i].Dimensions = append(input.MetricData[i].Dimensions, region)
+		}
+		status := input.MetricData[5].Dimensions
+		input.MetricData[5].Dimensions = []types.Dimension{status[0], status[1], status[2], region, status[3]}
+
+		stubber.Add(testtools.Stub{
+			OperationName: "PutMetricData",
+			Input:         input,
+			Output:        &cloudwatch.PutMetricDataOutput{},
+		})
+
+		request := Request{
+			Report:    event.Report,
+			Timestamp: event.Timestamp,
+			Accounts: []*CalculatedScore{
+				{
+					AccountId:          "111122223333",
+					Workload:           "my-workload",
+					Environment:        "development",
+					Score:              80,
+					ControlCount:       10,
+					ControlPassedCount: 8,
+					ControlFailedCount: 2,
+					FindingCount:       20000,
+					Dimensions:         []dimension.Dimension{{Name: "Region", Value: "eu-west-1"}},
+				},
+			},
+		}
+
+		_, err := lambda.Handler(ctx, request)
+		testtools.ExitTest(stubber, t)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Publish status without score", func(t *testing.T) {
+		stubber := testtools.NewStubber()
+		lambda := New(*stubber.SdkConfig)
+
+		stubber.Add(testtools.Stub{
+			OperationName: "PutMetricData",
+			Input: &cloudwatch.PutMetricDataInput{
+				Namespace: aws.String("SecurityPosture"),
+				MetricData: []types.MetricDatum{
+					StatusDatum("aws-foundational-security-best-practices", "my-workload", "development", score.StatusNoFindings),
+					StatusDatum("aws-foundational-security-best-practices", "my-workload", "test", score.StatusError),
+				},
+			},
+			Output: &cloudwatch.PutMetricDataOutput{},
+		})
+
+		request := Request{
+			Report:    event.Report,
+			Timestamp: event.Timestamp,
+			Accounts: []*CalculatedScore{
+				{AccountId: "111122223333", Workload: "my-workload", Environment: "development", Status: score.StatusNoFindings},
+				{AccountId: "333322221111", Workload: "my-workload", Environment: "test", Status: score.StatusError},
+				{AccountId: "444455556666", Workload: "my-workload", Environment: "sandbox", Status: score.StatusExcluded},
+			},
+		}
+
+		_, err := lambda.Handler(ctx, request)
+		testtools.ExitTest(stubber, t)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Publish the accounts failing each control", func(t *testing.T) {
+		stubber := testtools.NewStubber()
+		lambda := New(*stubber.SdkConfig)
+
+		addFailedControlsStub := func(key string, controls []string) {
+			data, _ := json.Marshal(controls)
+			stubber.Add(testtools.Stub{
+				OperationName: "GetObject",
+				Input:         &s3.GetObjectInput{Bucket: aws.String("my-sample-bucket"), Key: aws.String(key)},
+				Output:        &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(data))},
+			})
+		}
+		datum := func(control string, count float64) types.MetricDatum {
+			return types.MetricDatum{
+				Timestamp:  aws.Time(time.Unix(event.Timestamp, 0)),
+				MetricName: aws.String("FailingAccounts"),
+				Dimensions: []types.Dimension{
+					{Name: aws.String("Report"), Value: aws.String(event.Report)},
+					{Name: aws.String("Control"), Value: aws.String(control)},
+				},
+				Value: aws.Float64(count),
+				Unit:  types.StandardUnitCount,
+			}
+		}
+
+		addFailedControlsStub("development.failed-controls.json", []string{"EC2.2", "S3.1"})
+		addFailedControlsStub("test.failed-controls.json", []string{"S3.1"})
+
+		input := PutMetricDataInput("aws-foundational-security-best-practices", "my-workload", "development", 80, 10, 8, 2, 20000)
+		input.MetricData = append(input.MetricData, PutMetricDataInput("aws-foundational-security-best-practices", "my-workload", "test", 90, 14, 13, 1, 120000).MetricData...)
+		input.MetricData = append(input.MetricData, StatusDatum("aws-foundational-security-best-practices", "my-workload", "sandbox", score.StatusNoFindings))
+		input.MetricData = append(input.MetricData, datum("EC2.2", 1), datum("S3.1", 2))
+
+		stubber.Add(testtools.Stub{
+			OperationName: "PutMetricData",
+			Input:         input,
+			Output:        &cloudwatch.PutMetricDataOutput{},
+		})
+
+		request := event
+		request.Options.ControlMetrics = true
+		development := *event.Accounts[0]
+		development.FailedControls = "development.failed-controls.json"
+		test := *event.Accounts[1]
+		test.FailedControls = "test.failed-controls.json"
+		request.Accounts = []*CalculatedScore{
+			&development,
+			&test,
+			{AccountId: "444455556666", Workload: "my-workload", Environment: "sandbox", Status: score.StatusNoFindings},
+		}
+
+		_, err := lambda.Handler(ctx, request)
+		testtools.ExitTest(stubber, t)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Count an account of a split report once per failing control", func(t *testing.T) {
+		stubber := testtools.NewStubber()
+		lambda := New(*stubber.SdkConfig)
+
+		request := Request{
+			Bucket:    event.Bucket,
+			Report:    event.Report,
+			Timestamp: event.Timestamp,
+			Options:   report.Options{ControlMetrics: true},
+		}
+		input := &cloudwatch.PutMetricDataInput{Namespace: aws.String("SecurityPosture")}
+
+		for _, region := range []string{"eu-west-1", "us-east-1"} {
+			key := region + ".failed-controls.json"
+			stubber.Add(testtools.Stub{
+				OperationName: "GetObject",
+				Input:         &s3.GetObjectInput{Bucket: aws.String("my-sample-bucket"), Key: aws.String(key)},
+				Output:        &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader([]byte(`["S3.1"]`)))},
+			})
+
+			partition := PutMetricDataInput("aws-foundational-security-best-practices", "my-workload", "development", 80, 10, 8, 2, 20000)
+			split := types.Dimension{Name: aws.String("Region"), Value: aws.String(region)}
+			for i := range partition.MetricData[:5] {
+				partition.MetricData[i].Dimensions = append(partition.MetricData[i].Dimensions, split)
+			}
+			status := partition.MetricData[5].Dimensions
+			partition.MetricData[5].Dimensions = []types.Dimension{status[0], status[1], status[2], split, status[3]}
+			input.MetricData = append(input.MetricData, partition.MetricData...)
+
+			request.Accounts = append(request.Accounts, &CalculatedScore{
+				AccountId:          "111122223333",
+				Workload:           "my-workload",
+				Environment:        "development",
+				Score:              80,
+				ControlCount:       10,
+				ControlPassedCount: 8,
+				ControlFailedCount: 2,
+				FindingCount:       20000,
+				FailedControls:     key,
+				Dimensions:         []dimension.Dimension{{Name: "Region", Value: region}},
+			})
+		}
+
+		input.MetricData = append(input.MetricData, types.MetricDatum{
+			Timestamp:  aws.Time(time.Unix(event.Timestamp, 0)),
+			MetricName: aws.String("FailingAccounts"),
+			Dimensions: []types.Dimension{
+				{Name: aws.String("Report"), Value: aws.String(event.Report)},
+				{Name: aws.String("Control"), Value: aws.String("S3.1")},
+			},
+			Value: aws.Float64(1),
+			Unit:  types.StandardUnitCount,
+		})
 
 		stubber.Add(testtools.Stub{
 			OperationName: "PutMetricData",
-			Input:         PutMetricDataInput("aws-foundational-security-best-practices", "my-workload", "development", 80, 10, 20000),
+			Input:         input,
 			Output:        &cloudwatch.PutMetricDataOutput{},
 		})
+
+		_, err := lambda.Handler(ctx, request)
+		testtools.ExitTest(stubber, t)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Publish the grace period of a baselining account", func(t *testing.T) {
+		stubber := testtools.NewStubber()
+		lambda := New(*stubber.SdkConfig)
+
+		status := StatusDatum("aws-foundational-security-best-practices", "my-workload", "development", score.StatusBaselining)
+		stubber.Add(testtools.Stub{
+			OperationName: "PutMetricData",
+			Input: &cloudwatch.PutMetricDataInput{
+				Namespace: aws.String("SecurityPosture"),
+				MetricData: []types.MetricDatum{
+					{
+						Timestamp:  aws.Time(time.Unix(1691920532, 0)),
+						MetricName: aws.String("GraceRemainingHours"),
+						Dimensions: status.Dimensions[:3],
+						Value:      aws.Float64(132),
+						Unit:       types.StandardUnitNone,
+					},
+					status,
+				},
+			},
+			Output: &cloudwatch.PutMetricDataOutput{},
+		})
+
+		request := Request{
+			Report:    event.Report,
+			Timestamp: event.Timestamp,
+			Accounts: []*CalculatedScore{
+				{
+					AccountId:   "111122223333",
+					Workload:    "my-workload",
+					Environment: "development",
+					Status:      score.StatusBaselining,
+					Score:       12,
+					Baseline:    &score.Baseline{Until: 1692395732, RemainingHours: 132},
+				},
+			},
+		}
+
+		_, err := lambda.Handler(ctx, request)
+		testtools.ExitTest(stubber, t)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Publish under dimension sets in a custom namespace", func(t *testing.T) {
+		stubber := testtools.NewStubber()
+		lambda := New(*stubber.SdkConfig)
+
+		dimensions := func(pairs ...string) []types.Dimension {
+			var dimensions []types.Dimension
+			for i := 0; i < len(pairs); i += 2 {
+				dimensions = append(dimensions, types.Dimension{Name: aws.String(pairs[i]), Value: aws.String(pairs[i+1])})
+			}
+			return dimensions
+		}
+		datum := func(name string, value float64, unit types.StandardUnit, dimensions []types.Dimension) types.MetricDatum {
+			return types.MetricDatum{
+				Timestamp:  aws.Time(time.Unix(1691920532, 0)),
+				MetricName: aws.String(name),
+				Dimensions: dimensions,
+				Value:      aws.Float64(value),
+				Unit:       unit,
+			}
+		}
+
+		var data []types.MetricDatum
+		for _, set := range [][]types.Dimension{
+			dimensions("Report", event.Report),
+			dimensions("Report", event.Report, "AccountId", "111122223333", "Region", "eu-west-1"),
+		} {
+			data = append(data,
+				datum("Score", 80, types.StandardUnitPercent, set),
+				datum("Controls", 10, types.StandardUnitCount, set),
+				datum("ControlsPassed", 8, types.StandardUnitCount, set),
+				datum("ControlsFailed", 2, types.StandardUnitCount, set),
+				datum("Findings", 20000, types.StandardUnitCount, set),
+				datum("Accounts", 1, types.StandardUnitCount, append(append([]types.Dimension{}, set...), dimensions("Status", "SCORED")...)),
+			)
+		}
+
 		stubber.Add(testtools.Stub{
 			OperationName: "PutMetricData",
-			Input:         PutMetricDataInput("aws-foundational-security-best-practices", "my-workload", "test", 90, 14, 120000),
+			Input:         &cloudwatch.PutMetricDataInput{Namespace: aws.String("SecurityPosture/Production"), MetricData: data},
 			Output:        &cloudwatch.PutMetricDataOutput{},
 		})
 
+		account := *event.Accounts[0]
+		account.Dimensions = []dimension.Dimension{{Name: "Region", Value: "eu-west-1"}}
+
+		request := Request{
+			Report:    event.Report,
+			Timestamp: event.Timestamp,
+			Accounts:  []*CalculatedScore{&account},
+			Options: report.Options{
+				Namespace:     "SecurityPosture/Production",
+				DimensionSets: [][]string{{"Report"}, {"Report", "AccountId", "Region"}},
+			},
+		}
+
+		_, err := lambda.Handler(ctx, request)
+		testtools.ExitTest(stubber, t)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Invalid namespace or dimension sets", func(t *testing.T) {
+		cases := []struct {
+			options report.Options
+			err     string
+		}{
+			{report.Options{Namespace: "AWS/SecurityHub"}, "namespace `AWS/SecurityHub` is reserved for AWS services"},
+			{report.Options{DimensionSets: [][]string{{}}}, "dimension set is empty"},
+			{report.Options{DimensionSets: [][]string{{"Report", "Report"}}}, "dimension set [Report Report]: `Report` is given twice"},
+			{report.Options{DimensionSets: [][]string{{"Report", "Status"}}}, "dimension set [Report Status]: `Status` is not a valid dimension"},
+		}
+
+		for _, c := range cases {
+			stubber := testtools.NewStubber()
+			lambda := New(*stubber.SdkConfig)
+
+			request := event
+			request.Options = c.options
+
+			_, err := lambda.Handler(ctx, request)
+			assert.EqualError(t, err, c.err)
+			testtools.ExitTest(stubber, t)
+		}
+	})
+
+	t.Run("Write embedded metric documents", func(t *testing.T) {
+		_ = os.Setenv("METRICS_MODE", "EMF")
+		defer func() { _ = os.Setenv("METRICS_MODE", "") }()
+
+		stubber := testtools.NewStubber()
+		lambda := New(*stubber.SdkConfig)
+		var output bytes.Buffer
+		lambda.output = &output
+
 		_, err := lambda.Handler(ctx, event)
 		testtools.ExitTest(stubber, t)
 		assert.NoError(t, err)
+
+		documents := verifyEmbedded(t, output.String())
+		assert.Equal(t, 4, len(documents), "the metrics and the status of both accounts")
+		assert.Equal(t, "development", documents[0]["Environment"])
+		assert.Equal(t, float64(80), documents[0]["Score"])
+		assert.Equal(t, "SCORED", documents[1]["Status"])
+		assert.Equal(t, float64(1), documents[1]["Accounts"])
+	})
+
+	t.Run("Publish to every sink of the report", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		_ = os.Setenv("OTLP_METRICS_ENDPOINT", server.URL)
+		defer func() { _ = os.Setenv("OTLP_METRICS_ENDPOINT", "") }()
+
+		stubber := testtools.NewStubber()
+		lambda := New(*stubber.SdkConfig)
+
+		input := PutMetricDataInput("aws-foundational-security-best-practices", "my-workload", "development", 80, 10, 8, 2, 20000)
+		input.MetricData = append(input.MetricData, PutMetricDataInput("aws-foundational-security-best-practices", "my-workload", "test", 90, 14, 13, 1, 120000).MetricData...)
+		stubber.Add(testtools.Stub{
+			OperationName: "PutMetricData",
+			Input:         input,
+			Output:        &cloudwatch.PutMetricDataOutput{},
+		})
+
+		request := event
+		request.Options.Sinks = []string{"OTLP", "CloudWatch"}
+
+		// The failing OTLP endpoint does not keep the metrics from CloudWatch.
+		_, err := lambda.Handler(ctx, request)
+		testtools.ExitTest(stubber, t)
+		assert.EqualError(t, err, "otlp endpoint returned 503 Service Unavailable: unavailable")
+	})
+
+	t.Run("Unsupported metrics mode", func(t *testing.T) {
+		_ = os.Setenv("METRICS_MODE", "STDOUT")
+		defer func() { _ = os.Setenv("METRICS_MODE", "") }()
+
+		stubber := testtools.NewStubber()
+		lambda := New(*stubber.SdkConfig)
+
+		_, err := lambda.Handler(ctx, event)
+		assert.EqualError(t, err, "METRICS_MODE `STDOUT` is not supported, use API or EMF")
+		testtools.ExitTest(stubber, t)
 	})
 
 	t.Run("Fail on PutMetricData", func(t *testing.T) {
+		_ = os.Setenv("PUBLISH_ATTEMPTS", "1")
+		defer func() { _ = os.Setenv("PUBLISH_ATTEMPTS", "") }()
+
 		stubber := testtools.NewStubber()
 		lambda := New(*stubber.SdkConfig)
 		raiseErr := &testtools.StubError{Err: errors.New("failed")}
 		stubber.Add(testtools.Stub{
 			OperationName: "PutMetricData",
-			Input:         PutMetricDataInput("aws-foundational-security-best-practices", "my-workload", "development", 90, 0, 0),
+			Input:         PutMetricDataInput("aws-foundational-security-best-practices", "my-workload", "development", 90, 0, 0, 0, 0),
 			Error:         raiseErr,
 		})
 
@@ -130,4 +592,78 @@ func TestHandler(t *testing.T) {
 		testtools.VerifyError(err, raiseErr, t)
 		testtools.ExitTest(stubber, t)
 	})
+
+	t.Run("Retry a failed batch", func(t *testing.T) {
+		stubber := testtools.NewStubber()
+		lambda := New(*stubber.SdkConfig)
+		lambda.retryBackoff = 0
+
+		input := PutMetricDataInput("aws-foundational-security-best-practices", "my-workload", "development", 80, 10, 8, 2, 20000)
+		input.MetricData = append(input.MetricData, PutMetricDataInput("aws-foundational-security-best-practices", "my-workload", "test", 90, 14, 13, 1, 120000).MetricData...)
+
+		stubber.Add(testtools.Stub{
+			OperationName: "PutMetricData",
+			Input:         input,
+			Error:         &testtools.StubError{Err: errors.New("throttled"), ContinueAfter: true},
+		})
+		stubber.Add(testtools.Stub{
+			OperationName: "PutMetricData",
+			Input:         input,
+			Output:        &cloudwatch.PutMetricDataOutput{},
+		})
+
+		_, err := lambda.Handler(ctx, event)
+		testtools.ExitTest(stubber, t)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Invalid concurrency", func(t *testing.T) {
+		_ = os.Setenv("PUBLISH_CONCURRENCY", "none")
+		defer func() { _ = os.Setenv("PUBLISH_CONCURRENCY", "") }()
+
+		stubber := testtools.NewStubber()
+		lambda := New(*stubber.SdkConfig)
+
+		_, err := lambda.Handler(ctx, event)
+		assert.ErrorContains(t, err, "PUBLISH_CONCURRENCY `none` is not a positive number")
+		testtools.ExitTest(stubber, t)
+	})
+
+	t.Run("Publish the accounts outside the organization", func(t *testing.T) {
+		stubber := testtools.NewStubber()
+		lambda := New(*stubber.SdkConfig)
+
+		datum := func(accountMembership string, count float64) types.MetricDatum {
+			return types.MetricDatum{
+				Timestamp:  aws.Time(time.Unix(event.Timestamp, 0)),
+				MetricName: aws.String("NonOrganizationAccounts"),
+				Dimensions: []types.Dimension{
+					{Name: aws.String("Report"), Value: aws.String(event.Report)},
+					{Name: aws.String("Membership"), Value: aws.String(accountMembership)},
+					{Name: aws.String("Handling"), Value: aws.String("TAG")},
+				},
+				Value: aws.Float64(count),
+				Unit:  types.StandardUnitCount,
+			}
+		}
+
+		stubber.Add(testtools.Stub{
+			OperationName: "PutMetricData",
+			Input: &cloudwatch.PutMetricDataInput{
+				Namespace:  aws.String("SecurityPosture"),
+				MetricData: []types.MetricDatum{datum("INVITED", 2), datum("UNKNOWN", 0)},
+			},
+			Output: &cloudwatch.PutMetricDataOutput{},
+		})
+
+		request := Request{
+			Report:          event.Report,
+			Timestamp:       event.Timestamp,
+			NonOrganization: membership.Summary{Handling: membership.Tag, Invited: 2},
+		}
+
+		_, err := lambda.Handler(ctx, request)
+		testtools.ExitTest(stubber, t)
+		assert.NoError(t, err)
+	})
 }
@@ -1,19 +1,38 @@
 package main
 
+import (
+	"shared/dimension"
+	"shared/membership"
+	"shared/owner"
+	"shared/report"
+	"shared/score"
+)
+
 type CalculatedScore struct {
-	AccountId    string  `json:"AccountId"`
-	Workload     string  `json:"Workload"`
-	Environment  string  `json:"Environment"`
-	Score        float64 `json:"Score"`
-	ControlCount int     `json:"ControlCount"`
-	FindingCount int     `json:"FindingCount"`
+	AccountId          string                `json:"AccountId"`
+	AccountName        string                `json:"AccountName"`
+	Workload           string                `json:"Workload"`
+	Environment        string                `json:"Environment"`
+	OrganizationalUnit string                `json:"OrganizationalUnit,omitempty"`
+	Owner              owner.Owner           `json:"Owner"`
+	Status             score.Status          `json:"Status"`
+	Baseline           *score.Baseline       `json:"Baseline,omitempty"`
+	Score              float64               `json:"Score"`
+	ControlCount       int                   `json:"ControlCount"`
+	ControlFailedCount int                   `json:"ControlFailedCount"`
+	ControlPassedCount int                   `json:"ControlPassedCount"`
+	FindingCount       int                   `json:"FindingCount"`
+	FailedControls     string                `json:"FailedControls,omitempty"`
+	Dimensions         []dimension.Dimension `json:"Dimensions"`
 }
 
 type Request struct {
-	Report    string             `json:"Report"`
-	Timestamp int64              `json:"Timestamp"`
-	Bucket    string             `json:"Bucket"`
-	Accounts  []*CalculatedScore `json:"Accounts"`
+	Report          string             `json:"Report"`
+	Timestamp       int64              `json:"Timestamp"`
+	Bucket          string             `json:"Bucket"`
+	Accounts        []*CalculatedScore `json:"Accounts"`
+	Options         report.Options     `json:"Options"`
+	NonOrganization membership.Summary `json:"NonOrganization"`
 }
 
 type Response struct {
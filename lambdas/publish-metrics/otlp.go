@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// OTLPSink sends the metrics as gauges with OTLP over HTTP, in the JSON encoding. The name of a metric is the
+// namespace and the metric name, for example `SecurityPosture.Score`, the dimensions are attributes.
+type OTLPSink struct {
+	client    *http.Client
+	endpoint  string
+	namespace string
+}
+
+// The OTLP messages of an ExportMetricsServiceRequest, limited to the fields of a gauge.
+type (
+	OTLPRequest struct {
+		ResourceMetrics []OTLPResourceMetrics `json:"resourceMetrics"`
+	}
+	OTLPResourceMetrics struct {
+		Resource     OTLPResource       `json:"resource"`
+		ScopeMetrics []OTLPScopeMetrics `json:"scopeMetrics"`
+	}
+	OTLPResource struct {
+		Attributes []OTLPAttribute `json:"attributes"`
+	}
+	OTLPScopeMetrics struct {
+		Scope   OTLPScope    `json:"scope"`
+		Metrics []OTLPMetric `json:"metrics"`
+	}
+	OTLPScope struct {
+		Name string `json:"name"`
+	}
+	OTLPMetric struct {
+		Name  string    `json:"name"`
+		Unit  string    `json:"unit,omitempty"`
+		Gauge OTLPGauge `json:"gauge"`
+	}
+	OTLPGauge struct {
+		DataPoints []OTLPDataPoint `json:"dataPoints"`
+	}
+	OTLPDataPoint struct {
+		Attributes   []OTLPAttribute `json:"attributes"`
+		TimeUnixNano string          `json:"timeUnixNano"`
+		AsDouble     float64         `json:"asDouble"`
+	}
+	OTLPAttribute struct {
+		Key   string    `json:"key"`
+		Value OTLPValue `json:"value"`
+	}
+	OTLPValue struct {
+		StringValue string `json:"stringValue"`
+	}
+)
+
+func (s *OTLPSink) Publish(ctx context.Context, data []types.MetricDatum) error {
+	encoded := EncodeOTLP(s.namespace, data)
+	body, err := json.Marshal(encoded)
+
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, bytes.NewReader(body))
+
+	if err != nil {
+		return err
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := s.client.Do(request)
+
+	if err != nil {
+		return err
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode/100 != 2 {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+		return fmt.Errorf("otlp endpoint returned %s: %s", response.Status, bytes.TrimSpace(message))
+	}
+
+	log.Printf("Sent %d metrics with OTLP", len(encoded.ResourceMetrics[0].ScopeMetrics[0].Metrics))
+	return nil
+}
+
+// EncodeOTLP returns a gauge for every metric name, with a data point for every aggregated datum of the metric.
+func EncodeOTLP(namespace string, data []types.MetricDatum) OTLPRequest {
+	var metrics []OTLPMetric
+	index := map[string]int{}
+
+	for _, datum := range aggregate(data) {
+		name := namespace + "." + aws.ToString(datum.MetricName)
+		i, ok := index[name]
+
+		if !ok {
+			i = len(metrics)
+			index[name] = i
+			metrics = append(metrics, OTLPMetric{Name: name, Unit: otlpUnit(datum.Unit)})
+		}
+
+		point := OTLPDataPoint{
+			TimeUnixNano: strconv.FormatInt(aws.ToTime(datum.Timestamp).UnixNano(), 10),
+			AsDouble:     aws.ToFloat64(datum.Value),
+		}
+
+		for _, dimension := range datum.Dimensions {
+			point.Attributes = append(point.Attributes, OTLPAttribute{
+				Key:   aws.ToString(dimension.Name),
+				Value: OTLPValue{StringValue: aws.ToString(dimension.Value)},
+			})
+		}
+
+		metrics[i].Gauge.DataPoints = append(metrics[i].Gauge.DataPoints, point)
+	}
+
+	return OTLPRequest{ResourceMetrics: []OTLPResourceMetrics{{
+		Resource: OTLPResource{Attributes: []OTLPAttribute{
+			{Key: "service.name", Value: OTLPValue{StringValue: "aws-security-posture"}},
+		}},
+		ScopeMetrics: []OTLPScopeMetrics{{Scope: OTLPScope{Name: "publish-metrics"}, Metrics: metrics}},
+	}}}
+}
+
+// otlpUnit returns the UCUM unit of a CloudWatch unit.
+func otlpUnit(unit types.StandardUnit) string {
+	switch unit {
+	case types.StandardUnitPercent:
+		return "%"
+	case types.StandardUnitCount, types.StandardUnitNone:
+		return "1"
+	}
+
+	return ""
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOTLPSink(t *testing.T) {
+	ctx := context.Background()
+	data := []types.MetricDatum{
+		embeddedDatum("Score", 80, types.StandardUnitPercent, "Report", "cis", "Environment", "development"),
+		embeddedDatum("Score", 90, types.StandardUnitPercent, "Report", "cis", "Environment", "test"),
+		embeddedDatum("Findings", 12, types.StandardUnitCount, "Report", "cis", "Environment", "test"),
+	}
+
+	t.Run("Export the gauges", func(t *testing.T) {
+		var received map[string]any
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/v1/metrics", r.URL.Path)
+			assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+			_, _ = w.Write([]byte("{}"))
+		}))
+		defer server.Close()
+
+		sink := &OTLPSink{client: server.Client(), endpoint: server.URL + "/v1/metrics", namespace: "SecurityPosture"}
+		assert.NoError(t, sink.Publish(ctx, data))
+
+		scope := received["resourceMetrics"].([]any)[0].(map[string]any)["scopeMetrics"].([]any)[0].(map[string]any)
+		metrics := scope["metrics"].([]any)
+		assert.Equal(t, 4, len(metrics))
+
+		score := metrics[0].(map[string]any)
+		assert.Equal(t, "SecurityPosture.Score", score["name"])
+		assert.Equal(t, "%", score["unit"])
+
+		points := score["gauge"].(map[string]any)["dataPoints"].([]any)
+		assert.Equal(t, 2, len(points))
+		assert.Equal(t, map[string]any{
+			"attributes": []any{
+				map[string]any{"key": "Report", "value": map[string]any{"stringValue": "cis"}},
+				map[string]any{"key": "Environment", "value": map[string]any{"stringValue": "development"}},
+			},
+			"timeUnixNano": "1691920532000000000",
+			"asDouble":     float64(80),
+		}, points[0])
+		assert.Equal(t, "SecurityPosture.ScoreMinimum", metrics[1].(map[string]any)["name"])
+		assert.Equal(t, "SecurityPosture.ScoreMaximum", metrics[2].(map[string]any)["name"])
+		assert.Equal(t, "SecurityPosture.Findings", metrics[3].(map[string]any)["name"])
+	})
+
+	t.Run("Fail on a rejected export", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		sink := &OTLPSink{client: server.Client(), endpoint: server.URL, namespace: "SecurityPosture"}
+		assert.EqualError(t, sink.Publish(ctx, data), "otlp endpoint returned 503 Service Unavailable: unavailable")
+	})
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/golang/snappy"
+	"google.golang.org/protobuf/encoding/protowire"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+)
+
+// PrometheusSink sends the metrics with the Prometheus remote-write protocol (version 0.1.0). The name of a metric is
+// the namespace and the metric name in snake case, for example `security_posture_score`, the dimensions are labels.
+type PrometheusSink struct {
+	client    *http.Client
+	url       string
+	namespace string
+}
+
+// PrometheusLabel is a label of a remote-write time series.
+type PrometheusLabel struct {
+	Name  string
+	Value string
+}
+
+// PrometheusSeries is a remote-write time series with a single sample.
+type PrometheusSeries struct {
+	Labels    []PrometheusLabel
+	Value     float64
+	Timestamp int64
+}
+
+func (s *PrometheusSink) Publish(ctx context.Context, data []types.MetricDatum) error {
+	series := PrometheusSeriesOf(s.namespace, data)
+	body := snappy.Encode(nil, EncodeWriteRequest(series))
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+
+	if err != nil {
+		return err
+	}
+
+	request.Header.Set("Content-Encoding", "snappy")
+	request.Header.Set("Content-Type", "application/x-protobuf")
+	request.Header.Set("User-Agent", "aws-security-posture")
+	request.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+
+	response, err := s.client.Do(request)
+
+	if err != nil {
+		return err
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode/100 != 2 {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+		return fmt.Errorf("prometheus remote-write returned %s: %s", response.Status, bytes.TrimSpace(message))
+	}
+
+	log.Printf("Sent %d series with Prometheus remote-write", len(series))
+	return nil
+}
+
+// PrometheusSeriesOf returns a series for every aggregated datum, with the labels sorted by name as remote-write
+// requires.
+func PrometheusSeriesOf(namespace string, data []types.MetricDatum) []PrometheusSeries {
+	var series []PrometheusSeries
+
+	for _, datum := range aggregate(data) {
+		labels := []PrometheusLabel{{Name: "__name__", Value: snakeCase(namespace) + "_" + snakeCase(aws.ToString(datum.MetricName))}}
+
+		for _, dimension := range datum.Dimensions {
+			labels = append(labels, PrometheusLabel{Name: snakeCase(aws.ToString(dimension.Name)), Value: aws.ToString(dimension.Value)})
+		}
+
+		sort.Slice(labels, func(i, j int) bool { return labels[i].Name < labels[j].Name })
+
+		series = append(series, PrometheusSeries{
+			Labels:    labels,
+			Value:     aws.ToFloat64(datum.Value),
+			Timestamp: aws.ToTime(datum.Timestamp).UnixMilli(),
+		})
+	}
+
+	return series
+}
+
+// EncodeWriteRequest returns the protobuf encoding of a prometheus.WriteRequest:
+//
+//	message WriteRequest { repeated TimeSeries timeseries = 1; }
+//	message TimeSeries { repeated Label labels = 1; repeated Sample samples = 2; }
+//	message Label { string name = 1; string value = 2; }
+//	message Sample { double value = 1; int64 timestamp = 2; }
+func EncodeWriteRequest(series []PrometheusSeries) []byte {
+	var request []byte
+
+	for _, s := range series {
+		var timeSeries []byte
+
+		for _, label := range s.Labels {
+			var encoded []byte
+			encoded = protowire.AppendTag(encoded, 1, protowire.BytesType)
+			encoded = protowire.AppendString(encoded, label.Name)
+			encoded = protowire.AppendTag(encoded, 2, protowire.BytesType)
+			encoded = protowire.AppendString(encoded, label.Value)
+
+			timeSeries = protowire.AppendTag(timeSeries, 1, protowire.BytesType)
+			timeSeries = protowire.AppendBytes(timeSeries, encoded)
+		}
+
+		var sample []byte
+		sample = protowire.AppendTag(sample, 1, protowire.Fixed64Type)
+		sample = protowire.AppendFixed64(sample, math.Float64bits(s.Value))
+		sample = protowire.AppendTag(sample, 2, protowire.VarintType)
+		sample = protowire.AppendVarint(sample, uint64(s.Timestamp))
+
+		timeSeries = protowire.AppendTag(timeSeries, 2, protowire.BytesType)
+		timeSeries = protowire.AppendBytes(timeSeries, sample)
+
+		request = protowire.AppendTag(request, 1, protowire.BytesType)
+		request = protowire.AppendBytes(request, timeSeries)
+	}
+
+	return request
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/golang/snappy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// decodeWriteRequest is the decoding counterpart of EncodeWriteRequest, as a remote-write receiver would read it.
+func decodeWriteRequest(t *testing.T, data []byte) []PrometheusSeries {
+	var series []PrometheusSeries
+
+	fields := func(data []byte, handle func(number protowire.Number, value []byte, scalar uint64)) {
+		for len(data) > 0 {
+			number, kind, n := protowire.ConsumeTag(data)
+			require.Greater(t, n, 0)
+			data = data[n:]
+
+			switch kind {
+			case protowire.BytesType:
+				value, n := protowire.ConsumeBytes(data)
+				require.Greater(t, n, 0)
+				handle(number, value, 0)
+				data = data[n:]
+			case protowire.Fixed64Type:
+				value, n := protowire.ConsumeFixed64(data)
+				require.Greater(t, n, 0)
+				handle(number, nil, value)
+				data = data[n:]
+			case protowire.VarintType:
+				value, n := protowire.ConsumeVarint(data)
+				require.Greater(t, n, 0)
+				handle(number, nil, value)
+				data = data[n:]
+			default:
+				t.Fatalf("unexpected wire type %d", kind)
+			}
+		}
+	}
+
+	fields(data, func(_ protowire.Number, timeSeries []byte, _ uint64) {
+		var s PrometheusSeries
+		fields(timeSeries, func(number protowire.Number, message []byte, _ uint64) {
+			switch number {
+			case 1:
+				var label PrometheusLabel
+				fields(message, func(number protowire.Number, value []byte, _ uint64) {
+					if number == 1 {
+						label.Name = string(value)
+					} else {
+						label.Value = string(value)
+					}
+				})
+				s.Labels = append(s.Labels, label)
+			case 2:
+				fields(message, func(number protowire.Number, _ []byte, scalar uint64) {
+					if number == 1 {
+						s.Value = math.Float64frombits(scalar)
+					} else {
+						s.Timestamp = int64(scalar)
+					}
+				})
+			}
+		})
+		series = append(series, s)
+	})
+
+	return series
+}
+
+func TestPrometheusSink(t *testing.T) {
+	ctx := context.Background()
+	data := []types.MetricDatum{
+		embeddedDatum("Score", 80, types.StandardUnitPercent, "Report", "cis", "Workload", "payments", "Tag:cost-center", "42"),
+		embeddedDatum("Accounts", 1, types.StandardUnitCount, "Report", "cis", "Status", "SCORED"),
+		embeddedDatum("Accounts", 1, types.StandardUnitCount, "Status", "SCORED", "Report", "cis"),
+	}
+
+	t.Run("Remote-write the series", func(t *testing.T) {
+		var received []PrometheusSeries
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "snappy", r.Header.Get("Content-Encoding"))
+			assert.Equal(t, "application/x-protobuf", r.Header.Get("Content-Type"))
+			assert.Equal(t, "0.1.0", r.Header.Get("X-Prometheus-Remote-Write-Version"))
+
+			compressed, _ := io.ReadAll(r.Body)
+			body, err := snappy.Decode(nil, compressed)
+			require.NoError(t, err)
+
+			received = decodeWriteRequest(t, body)
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer server.Close()
+
+		sink := &PrometheusSink{client: server.Client(), url: server.URL, namespace: "SecurityPosture"}
+		assert.NoError(t, sink.Publish(ctx, data))
+
+		scoreSeries := func(name string) PrometheusSeries {
+			return PrometheusSeries{
+				Labels: []PrometheusLabel{
+					{Name: "__name__", Value: name},
+					{Name: "report", Value: "cis"},
+					{Name: "tag_cost_center", Value: "42"},
+					{Name: "workload", Value: "payments"},
+				},
+				Value:     80,
+				Timestamp: 1691920532000,
+			}
+		}
+
+		assert.Equal(t, []PrometheusSeries{
+			scoreSeries("security_posture_score"),
+			scoreSeries("security_posture_score_minimum"),
+			scoreSeries("security_posture_score_maximum"),
+			{
+				Labels: []PrometheusLabel{
+					{Name: "__name__", Value: "security_posture_accounts"},
+					{Name: "report", Value: "cis"},
+					{Name: "status", Value: "SCORED"},
+				},
+				Value:     2,
+				Timestamp: 1691920532000,
+			},
+		}, received, "the accounts of a label set are one series")
+	})
+
+	t.Run("Fail on a rejected write", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "out of order sample", http.StatusBadRequest)
+		}))
+		defer server.Close()
+
+		sink := &PrometheusSink{client: server.Client(), url: server.URL, namespace: "SecurityPosture"}
+		assert.EqualError(t, sink.Publish(ctx, data), "prometheus remote-write returned 400 Bad Request: out of order sample")
+	})
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// maxBatchDatums is the maximum number of datums of a PutMetricData call.
+	maxBatchDatums = 1000
+	// maxBatchBytes stays below the 1 MB payload limit of a PutMetricData call, leaving room for the envelope.
+	maxBatchBytes          = 1000 * 1000
+	defaultConcurrency     = 4
+	defaultAttempts        = 3
+	defaultRetryBackoff    = time.Second
+	datumOverheadBytes     = 256
+	dimensionOverheadBytes = 96
+)
+
+// Publisher packs metric datums into as few PutMetricData calls as the API limits allow, and sends them concurrently.
+// A failed batch is retried on its own, the other batches are not sent again.
+type Publisher struct {
+	client      *cloudwatch.Client
+	namespace   string
+	concurrency int
+	attempts    int
+	backoff     time.Duration
+}
+
+// NewPublisher reads the parallelism from PUBLISH_CONCURRENCY and the attempts per batch from PUBLISH_ATTEMPTS, a retry
+// waits backoff times the number of attempts so far.
+func NewPublisher(client *cloudwatch.Client, namespace string, backoff time.Duration) (*Publisher, error) {
+	concurrency, err := lookupInt("PUBLISH_CONCURRENCY", defaultConcurrency)
+
+	if err != nil {
+		return nil, err
+	}
+
+	attempts, err := lookupInt("PUBLISH_ATTEMPTS", defaultAttempts)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &Publisher{
+		client:      client,
+		namespace:   namespace,
+		concurrency: concurrency,
+		attempts:    attempts,
+		backoff:     backoff,
+	}, nil
+}
+
+// Publish sends the datums in batches, the returned error joins the errors of every batch that kept failing.
+func (p *Publisher) Publish(ctx context.Context, data []types.MetricDatum) error {
+	batches := Pack(data, maxBatchDatums, maxBatchBytes)
+	errs := make([]error, len(batches))
+	semaphore := make(chan struct{}, p.concurrency)
+
+	var wg sync.WaitGroup
+	for i, batch := range batches {
+		wg.Add(1)
+		semaphore <- struct{}{}
+
+		go func(i int, batch []types.MetricDatum) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			errs[i] = p.publishBatch(ctx, batch)
+		}(i, batch)
+	}
+
+	wg.Wait()
+	log.Printf("Published %d metrics in %d batches", len(data), len(batches))
+
+	return errors.Join(errs...)
+}
+
+func (p *Publisher) publishBatch(ctx context.Context, batch []types.MetricDatum) error {
+	var err error
+
+	for attempt := 1; attempt <= p.attempts; attempt++ {
+		_, err = p.client.PutMetricData(ctx, &cloudwatch.PutMetricDataInput{
+			Namespace:  aws.String(p.namespace),
+			MetricData: batch,
+		})
+
+		if err == nil {
+			return nil
+		}
+
+		log.Printf("Publishing a batch of %d metrics failed (attempt %d of %d): %s", len(batch), attempt, p.attempts, err)
+
+		if attempt < p.attempts {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(p.backoff * time.Duration(attempt)):
+			}
+		}
+	}
+
+	return err
+}
+
+// Pack splits the datums in order into batches of at most maxDatums datums and an estimated maxBytes bytes.
+func Pack(data []types.MetricDatum, maxDatums int, maxBytes int) [][]types.MetricDatum {
+	var batches [][]types.MetricDatum
+	var batch []types.MetricDatum
+	size := 0
+
+	for _, datum := range data {
+		datumSize := estimateSize(datum)
+
+		if len(batch) > 0 && (len(batch) == maxDatums || size+datumSize > maxBytes) {
+			batches = append(batches, batch)
+			batch = nil
+			size = 0
+		}
+
+		batch = append(batch, datum)
+		size += datumSize
+	}
+
+	if len(batch) > 0 {
+		batches = append(batches, batch)
+	}
+
+	return batches
+}
+
+// estimateSize over-estimates the encoded size of a datum, the parameter names of the query protocol included.
+func estimateSize(datum types.MetricDatum) int {
+	size := datumOverheadBytes + len(aws.ToString(datum.MetricName))
+
+	for _, dimension := range datum.Dimensions {
+		size += dimensionOverheadBytes + len(aws.ToString(dimension.Name)) + len(aws.ToString(dimension.Value))
+	}
+
+	return size
+}
+
+func lookupInt(key string, fallback int) (int, error) {
+	value := os.Getenv(key)
+
+	if value == "" {
+		return fallback, nil
+	}
+
+	number, err := strconv.Atoi(value)
+
+	if err != nil || number < 1 {
+		return 0, fmt.Errorf("%s `%s` is not a positive number", key, value)
+	}
+
+	return number, nil
+}
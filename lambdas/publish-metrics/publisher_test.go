@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/awsdocs/aws-doc-sdk-examples/gov2/testtools"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func generateData(count int, value string) []types.MetricDatum {
+	var data []types.MetricDatum
+
+	for i := 0; i < count; i++ {
+		data = append(data, types.MetricDatum{
+			MetricName: aws.String(fmt.Sprintf("Metric%d", i)),
+			Dimensions: []types.Dimension{{Name: aws.String("Report"), Value: aws.String(value)}},
+			Value:      aws.Float64(1),
+		})
+	}
+
+	return data
+}
+
+func TestPack(t *testing.T) {
+	t.Run("Nothing to pack", func(t *testing.T) {
+		assert.Empty(t, Pack(nil, maxBatchDatums, maxBatchBytes))
+	})
+
+	t.Run("Split by count", func(t *testing.T) {
+		data := generateData(2500, "report")
+		batches := Pack(data, maxBatchDatums, maxBatchBytes)
+
+		assert.Equal(t, 3, len(batches))
+		assert.Equal(t, 1000, len(batches[0]))
+		assert.Equal(t, 1000, len(batches[1]))
+		assert.Equal(t, 500, len(batches[2]))
+		assert.Equal(t, data[1000], batches[1][0], "the order is kept")
+	})
+
+	t.Run("Split by size", func(t *testing.T) {
+		// Every datum is estimated above 100 KB, so only 9 fit in a batch.
+		data := generateData(20, strings.Repeat("x", 100*1000))
+		batches := Pack(data, maxBatchDatums, maxBatchBytes)
+
+		assert.Equal(t, 3, len(batches))
+		assert.Equal(t, 9, len(batches[0]))
+		assert.Equal(t, 2, len(batches[2]))
+	})
+
+	t.Run("Oversized datum gets a batch of its own", func(t *testing.T) {
+		data := generateData(2, strings.Repeat("x", 2*maxBatchBytes))
+		assert.Equal(t, 2, len(Pack(data, maxBatchDatums, maxBatchBytes)))
+	})
+}
+
+func TestPublisher(t *testing.T) {
+	ctx := context.Background()
+
+	addStub := func(stubber *testtools.AwsmStubber, data []types.MetricDatum, err error) {
+		stub := testtools.Stub{
+			OperationName: "PutMetricData",
+			Input:         &cloudwatch.PutMetricDataInput{Namespace: aws.String("SecurityPosture"), MetricData: data},
+			Output:        &cloudwatch.PutMetricDataOutput{},
+		}
+		if err != nil {
+			stub.Error = &testtools.StubError{Err: err, ContinueAfter: true}
+		}
+		stubber.Add(stub)
+	}
+
+	t.Run("Retry only the failed batch", func(t *testing.T) {
+		stubber := testtools.NewStubber()
+		publisher := &Publisher{client: cloudwatch.NewFromConfig(*stubber.SdkConfig), namespace: "SecurityPosture", concurrency: 1, attempts: 3}
+		data := generateData(1500, "report")
+
+		addStub(stubber, data[:1000], nil)
+		addStub(stubber, data[1000:], errors.New("throttled"))
+		addStub(stubber, data[1000:], nil)
+
+		err := publisher.Publish(ctx, data)
+		testtools.ExitTest(stubber, t)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Fail after the last attempt", func(t *testing.T) {
+		stubber := testtools.NewStubber()
+		publisher := &Publisher{client: cloudwatch.NewFromConfig(*stubber.SdkConfig), namespace: "SecurityPosture", concurrency: 1, attempts: 2}
+		data := generateData(1500, "report")
+
+		addStub(stubber, data[:1000], errors.New("throttled"))
+		addStub(stubber, data[:1000], errors.New("still throttled"))
+		addStub(stubber, data[1000:], nil)
+
+		err := publisher.Publish(ctx, data)
+		testtools.ExitTest(stubber, t)
+		assert.ErrorContains(t, err, "still throttled")
+	})
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// The sinks a report can publish its metrics to.
+const (
+	SinkCloudWatch = "CloudWatch"
+	SinkEMF        = "EMF"
+	SinkPrometheus = "Prometheus"
+	SinkOTLP       = "OTLP"
+	SinkStatsD     = "StatsD"
+)
+
+const (
+	modeAPI = "API"
+	modeEMF = "EMF"
+)
+
+// MetricsSink publishes the rendered metrics of a report, every sink translates the CloudWatch datums into its own
+// format.
+type MetricsSink interface {
+	Publish(ctx context.Context, data []types.MetricDatum) error
+}
+
+// resolveSinks returns the sinks of the report. Without sinks the METRICS_MODE of the function decides between
+// CloudWatch and EMF, the endpoints of the other sinks are configured on the function as well. With METRICS_MODE EMF
+// the function may not call PutMetricData, so the CloudWatch sink is rejected.
+func (x *Lambda) resolveSinks(names []string, namespace string) ([]MetricsSink, error) {
+	mode := os.Getenv("METRICS_MODE")
+
+	if len(names) == 0 {
+		switch mode {
+		case "", modeAPI:
+			names = []string{SinkCloudWatch}
+		case modeEMF:
+			names = []string{SinkEMF}
+		default:
+			return nil, fmt.Errorf("METRICS_MODE `%s` is not supported, use %s or %s", mode, modeAPI, modeEMF)
+		}
+	}
+
+	var sinks []MetricsSink
+	seen := map[string]bool{}
+
+	for _, name := range names {
+		if seen[name] {
+			return nil, fmt.Errorf("sink `%s` is given twice", name)
+		}
+		seen[name] = true
+
+		if name == SinkCloudWatch && mode == modeEMF {
+			return nil, fmt.Errorf("sink `%s` needs METRICS_MODE %s, the function is deployed with %s", name, modeAPI, modeEMF)
+		}
+
+		sink, err := x.newSink(name, namespace)
+
+		if err != nil {
+			return nil, err
+		}
+
+		sinks = append(sinks, sink)
+	}
+
+	return sinks, nil
+}
+
+func (x *Lambda) newSink(name string, namespace string) (MetricsSink, error) {
+	switch name {
+	case SinkCloudWatch:
+		return NewPublisher(x.client, namespace, x.retryBackoff)
+	case SinkEMF:
+		return &EmbeddedSink{output: x.output, namespace: namespace}, nil
+	case SinkPrometheus:
+		url, err := requireEnv(name, "PROMETHEUS_REMOTE_WRITE_URL")
+		if err != nil {
+			return nil, err
+		}
+		return &PrometheusSink{client: x.httpClient, url: url, namespace: namespace}, nil
+	case SinkOTLP:
+		endpoint, err := requireEnv(name, "OTLP_METRICS_ENDPOINT")
+		if err != nil {
+			return nil, err
+		}
+		return &OTLPSink{client: x.httpClient, endpoint: endpoint, namespace: namespace}, nil
+	case SinkStatsD:
+		address, err := requireEnv(name, "STATSD_ADDRESS")
+		if err != nil {
+			return nil, err
+		}
+		return &StatsDSink{address: address, namespace: namespace}, nil
+	}
+
+	return nil, fmt.Errorf("unknown sink `%s`, use %s, %s, %s, %s or %s", name, SinkCloudWatch, SinkEMF, SinkPrometheus, SinkOTLP, SinkStatsD)
+}
+
+// publish sends the metrics to every sink, a failing sink does not keep the metrics from the other sinks.
+func publish(ctx context.Context, sinks []MetricsSink, data []types.MetricDatum) error {
+	var errs []error
+
+	for _, sink := range sinks {
+		err := sink.Publish(ctx, data)
+
+		if err != nil {
+			log.Printf("Publishing to %T failed: %s", sink, err)
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// aggregateSeries holds the values of the datums that share a metric name, timestamp and dimensions.
+type aggregateSeries struct {
+	datum  types.MetricDatum
+	values []float64
+}
+
+// aggregate combines the datums of a series, as CloudWatch combines the accounts of a dimension set. The sinks without
+// statistics get a single value per series: a count is summed, a gauge is averaged and gets its minimum and maximum as
+// `<Name>Minimum` and `<Name>Maximum`.
+func aggregate(data []types.MetricDatum) []types.MetricDatum {
+	var all []*aggregateSeries
+	index := map[string]*aggregateSeries{}
+
+	for _, datum := range data {
+		key := seriesKey(datum)
+		s, ok := index[key]
+
+		if !ok {
+			s = &aggregateSeries{datum: datum}
+			index[key] = s
+			all = append(all, s)
+		}
+
+		s.values = append(s.values, aws.ToFloat64(datum.Value))
+	}
+
+	var aggregated []types.MetricDatum
+	for _, s := range all {
+		name := aws.ToString(s.datum.MetricName)
+		sum, minimum, maximum := s.values[0], s.values[0], s.values[0]
+
+		for _, value := range s.values[1:] {
+			sum += value
+			minimum = min(minimum, value)
+			maximum = max(maximum, value)
+		}
+
+		if s.datum.Unit == types.StandardUnitCount {
+			aggregated = append(aggregated, s.with(name, sum))
+			continue
+		}
+
+		aggregated = append(aggregated,
+			s.with(name, sum/float64(len(s.values))),
+			s.with(name+"Minimum", minimum),
+			s.with(name+"Maximum", maximum))
+	}
+
+	return aggregated
+}
+
+func (s *aggregateSeries) with(name string, value float64) types.MetricDatum {
+	datum := s.datum
+	datum.MetricName = aws.String(name)
+	datum.Value = aws.Float64(value)
+
+	return datum
+}
+
+// seriesKey identifies the series of a datum, the order of the dimensions does not matter.
+func seriesKey(datum types.MetricDatum) string {
+	var dimensions []string
+
+	for _, dimension := range datum.Dimensions {
+		dimensions = append(dimensions, aws.ToString(dimension.Name)+"="+aws.ToString(dimension.Value))
+	}
+
+	sort.Strings(dimensions)
+
+	return fmt.Sprintf("%s\x00%d\x00%s", aws.ToString(datum.MetricName), aws.ToTime(datum.Timestamp).UnixMilli(), strings.Join(dimensions, "\x00"))
+}
+
+func requireEnv(sink string, key string) (string, error) {
+	value := os.Getenv(key)
+
+	if value == "" {
+		return "", fmt.Errorf("sink %s needs %s", sink, key)
+	}
+
+	return value, nil
+}
+
+// snakeCase turns a CloudWatch name into a Prometheus or StatsD name, for example `Tag:cost-center` into
+// `tag_cost_center` and `AccountId` into `account_id`.
+func snakeCase(name string) string {
+	var b strings.Builder
+	runes := []rune(name)
+
+	for i, r := range runes {
+		switch {
+		case r < unicode.MaxASCII && unicode.IsUpper(r):
+			if i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1])) {
+				b.WriteRune('_')
+			}
+			b.WriteRune(unicode.ToLower(r))
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			b.WriteRune(r)
+		default:
+			b.WriteRune('_')
+		}
+	}
+
+	return b.String()
+}
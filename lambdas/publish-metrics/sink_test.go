@@ -0,0 +1,92 @@
+package main
+
+import (
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/awsdocs/aws-doc-sdk-examples/gov2/testtools"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+)
+
+func TestSnakeCase(t *testing.T) {
+	assert.Equal(t, "security_posture", snakeCase("SecurityPosture"))
+	assert.Equal(t, "account_id", snakeCase("AccountId"))
+	assert.Equal(t, "tag_cost_center", snakeCase("Tag:cost-center"))
+	assert.Equal(t, "security_posture_production", snakeCase("SecurityPosture/Production"))
+	assert.Equal(t, "controls_failed", snakeCase("ControlsFailed"))
+}
+
+func TestAggregate(t *testing.T) {
+	aggregated := aggregate([]types.MetricDatum{
+		embeddedDatum("Score", 80, types.StandardUnitPercent, "Report", "cis"),
+		embeddedDatum("Findings", 3, types.StandardUnitCount, "Report", "cis"),
+		embeddedDatum("Score", 50, types.StandardUnitPercent, "Report", "cis"),
+		embeddedDatum("Findings", 4, types.StandardUnitCount, "Report", "cis"),
+		embeddedDatum("Score", 20, types.StandardUnitPercent, "Report", "cis", "Workload", "payments"),
+	})
+
+	values := map[string]float64{}
+	for _, datum := range aggregated {
+		key := aws.ToString(datum.MetricName)
+		if len(datum.Dimensions) > 1 {
+			key += "/payments"
+		}
+		values[key] = aws.ToFloat64(datum.Value)
+	}
+
+	assert.Equal(t, map[string]float64{
+		"Score":                 65,
+		"ScoreMinimum":          50,
+		"ScoreMaximum":          80,
+		"Findings":              7,
+		"Score/payments":        20,
+		"ScoreMinimum/payments": 20,
+		"ScoreMaximum/payments": 20,
+	}, values, "counts are summed, gauges averaged")
+}
+
+func TestResolveSinks(t *testing.T) {
+	stubber := testtools.NewStubber()
+	lambda := New(*stubber.SdkConfig)
+
+	t.Run("CloudWatch by default", func(t *testing.T) {
+		sinks, err := lambda.resolveSinks(nil, "SecurityPosture")
+		assert.NoError(t, err)
+		assert.IsType(t, &Publisher{}, sinks[0])
+	})
+
+	t.Run("Several sinks", func(t *testing.T) {
+		_ = os.Setenv("STATSD_ADDRESS", "127.0.0.1:8125")
+		defer func() { _ = os.Setenv("STATSD_ADDRESS", "") }()
+
+		sinks, err := lambda.resolveSinks([]string{"EMF", "StatsD"}, "SecurityPosture")
+		assert.NoError(t, err)
+		assert.Equal(t, 2, len(sinks))
+		assert.IsType(t, &EmbeddedSink{}, sinks[0])
+		assert.IsType(t, &StatsDSink{}, sinks[1])
+	})
+
+	t.Run("Invalid sinks", func(t *testing.T) {
+		_, err := lambda.resolveSinks([]string{"Graphite"}, "SecurityPosture")
+		assert.EqualError(t, err, "unknown sink `Graphite`, use CloudWatch, EMF, Prometheus, OTLP or StatsD")
+
+		_, err = lambda.resolveSinks([]string{"Prometheus"}, "SecurityPosture")
+		assert.EqualError(t, err, "sink Prometheus needs PROMETHEUS_REMOTE_WRITE_URL")
+
+		_, err = lambda.resolveSinks([]string{"EMF", "EMF"}, "SecurityPosture")
+		assert.EqualError(t, err, "sink `EMF` is given twice")
+	})
+
+	t.Run("CloudWatch without the API mode", func(t *testing.T) {
+		_ = os.Setenv("METRICS_MODE", "EMF")
+		defer func() { _ = os.Setenv("METRICS_MODE", "") }()
+
+		_, err := lambda.resolveSinks([]string{"EMF", "CloudWatch"}, "SecurityPosture")
+		assert.EqualError(t, err, "sink `CloudWatch` needs METRICS_MODE API, the function is deployed with EMF")
+
+		sinks, err := lambda.resolveSinks(nil, "SecurityPosture")
+		assert.NoError(t, err)
+		assert.IsType(t, &EmbeddedSink{}, sinks[0])
+	})
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// maxStatsDPacket keeps a packet within the MTU of most networks, lines are never split over packets.
+const maxStatsDPacket = 1432
+
+// StatsDSink sends the metrics as gauges over UDP, with the dimensions as DogStatsD tags. The name of a metric is the
+// namespace and the metric name in snake case, for example `security_posture.score`. StatsD has no timestamps, the
+// server uses the moment a metric arrives.
+type StatsDSink struct {
+	address   string
+	namespace string
+}
+
+func (s *StatsDSink) Publish(ctx context.Context, data []types.MetricDatum) error {
+	var dialer net.Dialer
+	connection, err := dialer.DialContext(ctx, "udp", s.address)
+
+	if err != nil {
+		return err
+	}
+
+	defer connection.Close()
+
+	lines := EncodeStatsD(s.namespace, data)
+	packets := PackStatsD(lines, maxStatsDPacket)
+
+	for _, packet := range packets {
+		_, err = connection.Write([]byte(packet))
+
+		if err != nil {
+			return err
+		}
+	}
+
+	log.Printf("Sent %d metrics in %d StatsD packets", len(lines), len(packets))
+	return nil
+}
+
+// EncodeStatsD returns a gauge line for every aggregated datum, for example `security_posture.score:80|g|#report:cis`.
+func EncodeStatsD(namespace string, data []types.MetricDatum) []string {
+	var lines []string
+
+	for _, datum := range aggregate(data) {
+		var tags []string
+		for _, dimension := range datum.Dimensions {
+			tags = append(tags, snakeCase(aws.ToString(dimension.Name))+":"+statsDValue(aws.ToString(dimension.Value)))
+		}
+
+		line := fmt.Sprintf("%s.%s:%s|g", snakeCase(namespace), snakeCase(aws.ToString(datum.MetricName)),
+			strconv.FormatFloat(aws.ToFloat64(datum.Value), 'f', -1, 64))
+
+		if len(tags) > 0 {
+			line += "|#" + strings.Join(tags, ",")
+		}
+
+		lines = append(lines, line)
+	}
+
+	return lines
+}
+
+// PackStatsD joins the lines with newlines into packets of at most maxBytes, a longer line gets a packet of its own.
+func PackStatsD(lines []string, maxBytes int) []string {
+	var packets []string
+	var packet strings.Builder
+
+	for _, line := range lines {
+		if packet.Len() > 0 && packet.Len()+1+len(line) > maxBytes {
+			packets = append(packets, packet.String())
+			packet.Reset()
+		}
+
+		if packet.Len() > 0 {
+			packet.WriteByte('\n')
+		}
+		packet.WriteString(line)
+	}
+
+	if packet.Len() > 0 {
+		packets = append(packets, packet.String())
+	}
+
+	return packets
+}
+
+// statsDValue replaces the characters that separate the parts of a line in a tag value.
+func statsDValue(value string) string {
+	return strings.NewReplacer(",", "_", "|", "_", "#", "_", "\n", "_").Replace(value)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStatsDSink(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Send gauges with tags", func(t *testing.T) {
+		listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer listener.Close()
+
+		sink := &StatsDSink{address: listener.LocalAddr().String(), namespace: "SecurityPosture"}
+		assert.NoError(t, sink.Publish(ctx, []types.MetricDatum{
+			embeddedDatum("Score", 80.5, types.StandardUnitPercent, "Report", "cis", "AccountName", "acme,payments"),
+			embeddedDatum("ControlsFailed", 2, types.StandardUnitCount, "Report", "cis"),
+		}))
+
+		buffer := make([]byte, maxStatsDPacket)
+		_ = listener.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, _, err := listener.ReadFrom(buffer)
+		require.NoError(t, err)
+
+		assert.Equal(t, "security_posture.score:80.5|g|#report:cis,account_name:acme_payments\n"+
+			"security_posture.score_minimum:80.5|g|#report:cis,account_name:acme_payments\n"+
+			"security_posture.score_maximum:80.5|g|#report:cis,account_name:acme_payments\n"+
+			"security_posture.controls_failed:2|g|#report:cis", string(buffer[:n]))
+	})
+
+	t.Run("Pack the lines into packets", func(t *testing.T) {
+		lines := []string{strings.Repeat("a", 600), strings.Repeat("b", 600), strings.Repeat("c", 600), strings.Repeat("d", 2000)}
+		packets := PackStatsD(lines, maxStatsDPacket)
+
+		assert.Equal(t, 3, len(packets))
+		assert.Equal(t, lines[0]+"\n"+lines[1], packets[0])
+		assert.Equal(t, lines[2], packets[1])
+		assert.Equal(t, lines[3], packets[2], "a line longer than a packet is sent on its own")
+	})
+}
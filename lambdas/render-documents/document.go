@@ -0,0 +1,314 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"shared/dimension"
+	"shared/score"
+	"sort"
+	"strings"
+	"time"
+)
+
+// defaultTopControls is the number of failing controls a document lists when the report does not say.
+const defaultTopControls = 10
+
+// controlPattern finds the Security Hub control id at the end of a control, like IAM.1 in
+// aws-foundational-security-best-practices/v/1.0.0/IAM.1.
+var controlPattern = regexp.MustCompile(`(?:^|/)([A-Za-z][A-Za-z0-9]*)\.(\d+)$`)
+
+// Document is what a template renders, the summary of the organization or the document of a single workload.
+type Document struct {
+	Report      string
+	Workload    string
+	GeneratedAt time.Time
+	// Score is the average of the scored accounts, nil when no account was scored.
+	Score        *Trend
+	Workloads    []WorkloadScore
+	Environments []EnvironmentScore
+	Accounts     []AccountScore
+	Controls     []FailingControl
+	Exceptions   []Exception
+}
+
+// Trend is a score with its change since the previous run, Change is nil when there was no previous score.
+type Trend struct {
+	Score  float64
+	Change *float64
+}
+
+// WorkloadScore is a workload in the summary of the organization.
+type WorkloadScore struct {
+	Workload     string
+	Score        Trend
+	Environments []EnvironmentScore
+}
+
+// EnvironmentScore is the average score of the accounts of a workload in an environment.
+type EnvironmentScore struct {
+	Environment string
+	Accounts    int
+	Score       Trend
+}
+
+// AccountScore is a scored account of a workload.
+type AccountScore struct {
+	AccountId   string
+	AccountName string
+	Environment string
+	Score       float64
+}
+
+// FailingControl is a control with the number of scored accounts failing it.
+type FailingControl struct {
+	Control        string
+	Accounts       int
+	RemediationUrl string
+}
+
+// Exception is an account that is not part of the scores, with the reason.
+type Exception struct {
+	AccountId   string
+	AccountName string
+	Workload    string
+	Environment string
+	Status      score.Status
+	Reason      string
+}
+
+// Build returns the summary of the organization and a document for every workload, sorted by name, and the state the
+// next run compares its scores with. The failed controls are keyed by dimension.Key.
+func Build(request Request, failed map[string][]string, exclusions []Exclusion, previous *State, top int) (Document, []Document, State) {
+	generatedAt := time.Unix(request.Timestamp, 0).UTC()
+	next := State{Workloads: map[string]WorkloadState{}}
+	summary := Document{Report: request.Report, GeneratedAt: generatedAt}
+
+	byWorkload := map[string][]*CalculatedScore{}
+	var scored []*CalculatedScore
+	listed := map[string]bool{}
+
+	for _, calculated := range request.Accounts {
+		workload := workloadName(calculated)
+		byWorkload[workload] = append(byWorkload[workload], calculated)
+
+		status := score.Resolve(calculated.Status)
+		if status == score.StatusScored {
+			scored = append(scored, calculated)
+			continue
+		}
+
+		summary.Exceptions = append(summary.Exceptions, newException(calculated, status))
+		listed[calculated.AccountId] = true
+	}
+
+	for _, exclusion := range exclusions {
+		if listed[exclusion.AccountId] {
+			continue
+		}
+
+		summary.Exceptions = append(summary.Exceptions, Exception{
+			AccountId:   exclusion.AccountId,
+			AccountName: exclusion.AccountName,
+			Status:      score.StatusExcluded,
+			Reason:      exclusion.Reason,
+		})
+	}
+
+	if len(scored) > 0 {
+		average := averageScore(scored)
+		summary.Score = &Trend{Score: average, Change: change(average, previous.Score)}
+		next.Score = &average
+	}
+
+	summary.Controls = topControls(scored, failed, top)
+
+	workloads := make([]string, 0, len(byWorkload))
+	for workload := range byWorkload {
+		workloads = append(workloads, workload)
+	}
+	sort.Strings(workloads)
+
+	var documents []Document
+	for _, workload := range workloads {
+		document, state := buildWorkload(request, workload, byWorkload[workload], failed, previous.Workloads[workload], top)
+		documents = append(documents, document)
+
+		if document.Score == nil {
+			continue
+		}
+
+		next.Workloads[workload] = state
+		summary.Workloads = append(summary.Workloads, WorkloadScore{
+			Workload:     workload,
+			Score:        *document.Score,
+			Environments: document.Environments,
+		})
+	}
+
+	return summary, documents, next
+}
+
+func buildWorkload(request Request, workload string, accounts []*CalculatedScore, failed map[string][]string, previous WorkloadState, top int) (Document, WorkloadState) {
+	document := Document{Report: request.Report, Workload: workload, GeneratedAt: time.Unix(request.Timestamp, 0).UTC()}
+	state := WorkloadState{Environments: map[string]float64{}}
+
+	byEnvironment := map[string][]*CalculatedScore{}
+	var scored []*CalculatedScore
+
+	for _, calculated := range accounts {
+		status := score.Resolve(calculated.Status)
+
+		if status != score.StatusScored {
+			document.Exceptions = append(document.Exceptions, newException(calculated, status))
+			continue
+		}
+
+		scored = append(scored, calculated)
+		byEnvironment[calculated.Environment] = append(byEnvironment[calculated.Environment], calculated)
+		document.Accounts = append(document.Accounts, AccountScore{
+			AccountId:   calculated.AccountId,
+			AccountName: calculated.AccountName,
+			Environment: calculated.Environment,
+			Score:       calculated.Score,
+		})
+	}
+
+	if len(scored) == 0 {
+		return document, state
+	}
+
+	state.Score = averageScore(scored)
+	document.Score = &Trend{Score: state.Score}
+	if previous.Environments != nil {
+		document.Score.Change = change(state.Score, &previous.Score)
+	}
+
+	environments := make([]string, 0, len(byEnvironment))
+	for environment := range byEnvironment {
+		environments = append(environments, environment)
+	}
+	sort.Strings(environments)
+
+	for _, environment := range environments {
+		average := averageScore(byEnvironment[environment])
+		state.Environments[environment] = average
+
+		var last *float64
+		if value, ok := previous.Environments[environment]; ok {
+			last = &value
+		}
+
+		document.Environments = append(document.Environments, EnvironmentScore{
+			Environment: environment,
+			Accounts:    len(byEnvironment[environment]),
+			Score:       Trend{Score: average, Change: change(average, last)},
+		})
+	}
+
+	sort.SliceStable(document.Accounts, func(i, j int) bool {
+		return document.Accounts[i].Environment < document.Accounts[j].Environment
+	})
+
+	document.Controls = topControls(scored, failed, top)
+
+	return document, state
+}
+
+// topControls returns the controls failed by the most accounts, controls failed by as many accounts are sorted by name.
+// An account of a split report counts once, however many of its partitions fail the control.
+func topControls(accounts []*CalculatedScore, failed map[string][]string, top int) []FailingControl {
+	failing := map[string]map[string]bool{}
+
+	for _, calculated := range accounts {
+		for _, control := range failed[dimension.Key(calculated.AccountId, calculated.Dimensions)] {
+			if failing[control] == nil {
+				failing[control] = map[string]bool{}
+			}
+			failing[control][calculated.AccountId] = true
+		}
+	}
+
+	var controls []FailingControl
+	for control, accountIds := range failing {
+		controls = append(controls, FailingControl{Control: control, Accounts: len(accountIds), RemediationUrl: RemediationUrl(control)})
+	}
+
+	sort.Slice(controls, func(i, j int) bool {
+		if controls[i].Accounts != controls[j].Accounts {
+			return controls[i].Accounts > controls[j].Accounts
+		}
+		return controls[i].Control < controls[j].Control
+	})
+
+	return controls[:min(top, len(controls))]
+}
+
+// RemediationUrl links a control to its remediation in the Security Hub documentation, controls without a Security Hub
+// control id link to the reference of all controls.
+func RemediationUrl(control string) string {
+	match := controlPattern.FindStringSubmatch(control)
+
+	if match == nil {
+		return "https://docs.aws.amazon.com/securityhub/latest/userguide/securityhub-controls-reference.html"
+	}
+
+	service := strings.ToLower(match[1])
+	return fmt.Sprintf("https://docs.aws.amazon.com/securityhub/latest/userguide/%s-controls.html#%s-%s", service, service, match[2])
+}
+
+func newException(calculated *CalculatedScore, status score.Status) Exception {
+	exception := Exception{
+		AccountId:   calculated.AccountId,
+		AccountName: calculated.AccountName,
+		Workload:    workloadName(calculated),
+		Environment: calculated.Environment,
+		Status:      status,
+	}
+
+	switch status {
+	case score.StatusBaselining:
+		exception.Reason = "The account joined the organization recently."
+		if calculated.Baseline != nil {
+			exception.Reason = fmt.Sprintf("The account is in its grace period until %s.",
+				time.Unix(calculated.Baseline.Until, 0).UTC().Format(time.DateOnly))
+		}
+	case score.StatusNoFindings:
+		exception.Reason = "The account has no findings."
+	case score.StatusStandardNotEnabled:
+		exception.Reason = "The standard of the report is not enabled in the account."
+	case score.StatusExcluded:
+		exception.Reason = "The account is excluded by the classification file."
+	default:
+		exception.Reason = "The score could not be calculated."
+	}
+
+	return exception
+}
+
+// workloadName returns the workload of the account, accounts without a workload are grouped under None.
+func workloadName(calculated *CalculatedScore) string {
+	if calculated.Workload == "" {
+		return "None"
+	}
+
+	return calculated.Workload
+}
+
+func averageScore(accounts []*CalculatedScore) float64 {
+	var total float64
+
+	for _, calculated := range accounts {
+		total += calculated.Score
+	}
+
+	return total / float64(len(accounts))
+}
+
+func change(current float64, previous *float64) *float64 {
+	if previous == nil {
+		return nil
+	}
+
+	difference := current - *previous
+	return &difference
+}
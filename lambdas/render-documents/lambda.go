@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"log"
+	"os"
+	"path"
+	"regexp"
+	"shared/blobstore"
+	"shared/dimension"
+	"shared/layout"
+	"shared/score"
+	"shared/statefile"
+)
+
+// stateVersion is the newest version of the state format this function understands.
+const stateVersion = 1
+
+// unsafeKeyCharacters are replaced in the workload names of document keys.
+var unsafeKeyCharacters = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// output is a document rendered with a template to a key in the bucket.
+type output struct {
+	template string
+	key      string
+	document Document
+}
+
+type Lambda struct {
+	ctx   context.Context
+	store blobstore.BlobStore
+}
+
+func New(cfg aws.Config) *Lambda {
+	m := new(Lambda)
+	m.store = blobstore.NewFromConfig(cfg)
+	return m
+}
+
+func (x *Lambda) Handler(ctx context.Context, request Request) (Response, error) {
+	x.ctx = ctx
+	response := Response{Documents: []string{}}
+	options := request.Options.Documents
+
+	if options == nil {
+		log.Printf("Report %s does not render documents", request.Report)
+		return response, nil
+	}
+
+	top := options.TopControls
+	if top == 0 {
+		top = defaultTopControls
+	}
+
+	if top < 0 {
+		return response, fmt.Errorf("TopControls %d is negative", top)
+	}
+
+	renderer, err := x.loadRenderer(templatesBucket(request.Bucket), options.Templates)
+
+	if err != nil {
+		return response, err
+	}
+
+	previous, err := statefile.Load[State](x.ctx, x.store, request.Bucket, statefile.Key(request.Report, "documents"), stateVersion)
+
+	if err != nil {
+		return response, err
+	}
+
+	failed := map[string][]string{}
+	for _, calculated := range request.Accounts {
+		if score.Resolve(calculated.Status) != score.StatusScored || calculated.FailedControls == "" {
+			continue
+		}
+
+		var controls []string
+		err = x.download(request.Bucket, calculated.FailedControls, &controls)
+
+		if err != nil {
+			return response, err
+		}
+
+		failed[dimension.Key(calculated.AccountId, calculated.Dimensions)] = controls
+	}
+
+	var exclusions []Exclusion
+	if request.Exclusions != "" {
+		err = x.download(request.Bucket, request.Exclusions, &exclusions)
+
+		if err != nil {
+			return response, err
+		}
+	}
+
+	summary, workloads, next := Build(request, failed, exclusions, previous, top)
+	prefix := layout.Folder(request.Report, "documents", request.Timestamp)
+
+	outputs := []output{
+		{SummaryHtml, path.Join(prefix, "summary.html"), summary},
+		{SummaryMarkdown, path.Join(prefix, "summary.md"), summary},
+	}
+
+	names := map[string]bool{}
+	for _, workload := range workloads {
+		name := uniqueName(unsafeKeyCharacters.ReplaceAllString(workload.Workload, "-"), names)
+		outputs = append(outputs,
+			output{WorkloadHtml, path.Join(prefix, "workloads", name+".html"), workload},
+			output{WorkloadMarkdown, path.Join(prefix, "workloads", name+".md"), workload},
+		)
+	}
+
+	for _, document := range outputs {
+		data, err := renderer.Render(document.template, document.document)
+
+		if err != nil {
+			return response, err
+		}
+
+		err = x.store.Upload(x.ctx, request.Bucket, document.key, data)
+
+		if err != nil {
+			return response, err
+		}
+
+		response.Documents = append(response.Documents, document.key)
+	}
+
+	log.Printf("Rendered %d documents to %s", len(response.Documents), prefix)
+
+	return response, statefile.Save(x.ctx, x.store, request.Bucket, statefile.Key(request.Report, "documents"), stateVersion, &next)
+}
+
+// uniqueName returns the name, suffixed with a number when a workload with another name got it after replacing the
+// unsafe characters, like `Team A` and `Team-A`.
+func uniqueName(name string, taken map[string]bool) string {
+	unique := name
+
+	for i := 2; taken[unique]; i++ {
+		unique = fmt.Sprintf("%s-%d", name, i)
+	}
+
+	taken[unique] = true
+	return unique
+}
+
+// loadRenderer parses the built-in templates, replaced by the templates below the prefix in the bucket.
+func (x *Lambda) loadRenderer(bucket string, prefix string) (*Renderer, error) {
+	overrides := map[string]string{}
+
+	if prefix != "" {
+		for _, name := range []string{SummaryHtml, SummaryMarkdown, WorkloadHtml, WorkloadMarkdown} {
+			data, err := x.store.Download(x.ctx, bucket, path.Join(prefix, name))
+
+			if blobstore.IsNotFound(err) {
+				continue
+			}
+
+			if err != nil {
+				return nil, err
+			}
+
+			log.Printf("Using template %s from %s", name, prefix)
+			overrides[name] = string(data)
+		}
+
+		if len(overrides) == 0 {
+			log.Printf("Warning: no templates found below s3://%s/%s, using the built-in templates", bucket, prefix)
+		}
+	}
+
+	return NewRenderer(overrides)
+}
+
+// templatesBucket returns the bucket of the templates, TEMPLATES_BUCKET or else the bucket of the report.
+func templatesBucket(bucket string) string {
+	if value := os.Getenv("TEMPLATES_BUCKET"); value != "" {
+		return value
+	}
+
+	return bucket
+}
+
+func (x *Lambda) download(bucket string, key string, v any) error {
+	data, err := x.store.Download(x.ctx, bucket, key)
+
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, v)
+}
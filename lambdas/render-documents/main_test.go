@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/awsdocs/aws-doc-sdk-examples/gov2/testtools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"shared/blobstore/blobtest"
+	"shared/dimension"
+	"shared/layout"
+	"shared/report"
+	"testing"
+)
+
+func readEvent(path string) Request {
+	file, _ := os.ReadFile(path)
+
+	var event Request
+	_ = json.Unmarshal(file, &event)
+	return event
+}
+
+// newLocalLambda returns a lambda on the local storage backend, seeded with the failed controls and exclusions of the
+// event.
+func newLocalLambda(t *testing.T) (*Lambda, *blobtest.Store) {
+	store := blobtest.New(t)
+
+	store.Seed("aws-foundational-security-best-practices/accounts/2023/08/13/111122223333.failed-controls.json",
+		`["aws-foundational-security-best-practices/v/1.0.0/IAM.1","aws-foundational-security-best-practices/v/1.0.0/S3.1"]`)
+	store.Seed("aws-foundational-security-best-practices/accounts/2023/08/13/333322221111.failed-controls.json",
+		`["aws-foundational-security-best-practices/v/1.0.0/IAM.1"]`)
+	store.Seed("aws-foundational-security-best-practices/excluded/2023/08/13/1691920532.json",
+		`[{"AccountId":"555566667777","AccountName":"sandbox","Reason":"The account is suspended."}]`)
+
+	stubber := testtools.NewStubber()
+	lambda := New(*stubber.SdkConfig)
+	lambda.store = store
+	return lambda, store
+}
+
+func TestHandler(t *testing.T) {
+	ctx := context.Background()
+	event := readEvent("../../events/render-documents.json")
+	prefix := layout.Folder("aws-foundational-security-best-practices", "documents", 1691920532)
+
+	t.Run("Render documents", func(t *testing.T) {
+		lambda, store := newLocalLambda(t)
+		store.Seed("aws-foundational-security-best-practices/documents/state.json", `{"Version":1,"Score":80,
+			"Workloads":{"my-workload":{"Score":82,"Environments":{"production":75,"test":89}}}}`)
+
+		response, err := lambda.Handler(ctx, event)
+		require.NoError(t, err)
+		assert.Equal(t, []string{
+			prefix + "/summary.html",
+			prefix + "/summary.md",
+			prefix + "/workloads/my-workload.html",
+			prefix + "/workloads/my-workload.md",
+			prefix + "/workloads/payments.html",
+			prefix + "/workloads/payments.md",
+		}, response.Documents)
+
+		summary := string(store.Read(prefix + "/summary.md"))
+		assert.Contains(t, summary, "**Score: 85%** (+5 since the last run)")
+		assert.Contains(t, summary, "| **my-workload** | | | **85%** | +3 |")
+		assert.Contains(t, summary, "| | production | 1 | 80% | +5 |")
+		assert.Contains(t, summary, "| | test | 1 | 90% | +1 |")
+		assert.Contains(t, summary, "| aws-foundational-security-best-practices/v/1.0.0/IAM.1 | 2 | [Remediation](https://docs.aws.amazon.com/securityhub/latest/userguide/iam-controls.html#iam-1) |")
+		assert.Contains(t, summary, "| 444455556666 (payments-production) | payments | production | BASELINING | The account is in its grace period until 2023-08-20. |")
+		assert.Contains(t, summary, "| 555566667777 (sandbox) |  |  | EXCLUDED | The account is suspended. |")
+
+		html := string(store.Read(prefix + "/summary.html"))
+		assert.Contains(t, html, `<a href="https://docs.aws.amazon.com/securityhub/latest/userguide/s3-controls.html#s3-1">Remediation</a>`)
+		assert.Contains(t, html, `<td class="number up">&#43;5</td>`)
+
+		payments := string(store.Read(prefix + "/workloads/payments.md"))
+		assert.Contains(t, payments, "No account of the workload was scored in this run.")
+		assert.Contains(t, payments, "| 444455556666 (payments-production) | production | BASELINING |")
+
+		var state State
+		require.NoError(t, json.Unmarshal(store.Read("aws-foundational-security-best-practices/documents/state.json"), &state))
+		assert.Equal(t, 85.0, *state.Score)
+		assert.Equal(t, map[string]WorkloadState{
+			"my-workload": {Score: 85, Environments: map[string]float64{"production": 80, "test": 90}},
+		}, state.Workloads)
+	})
+
+	t.Run("Count an account of a split report once", func(t *testing.T) {
+		lambda, store := newLocalLambda(t)
+		store.Seed("aws-foundational-security-best-practices/accounts/2023/08/13/111122223333-central.failed-controls.json",
+			`["aws-foundational-security-best-practices/v/1.0.0/IAM.1"]`)
+
+		request := readEvent("../../events/render-documents.json")
+		central := *request.Accounts[0]
+		central.Dimensions = []dimension.Dimension{{Name: "Region", Value: "eu-central-1"}}
+		central.FailedControls = "aws-foundational-security-best-practices/accounts/2023/08/13/111122223333-central.failed-controls.json"
+		request.Accounts[0].Dimensions = []dimension.Dimension{{Name: "Region", Value: "eu-west-1"}}
+		request.Accounts = []*CalculatedScore{request.Accounts[0], &central}
+
+		_, err := lambda.Handler(ctx, request)
+		require.NoError(t, err)
+
+		// Both partitions fail IAM.1, only the one in eu-west-1 fails S3.1.
+		summary := string(store.Read(prefix + "/summary.md"))
+		assert.Contains(t, summary, "| aws-foundational-security-best-practices/v/1.0.0/IAM.1 | 1 |")
+		assert.Contains(t, summary, "| aws-foundational-security-best-practices/v/1.0.0/S3.1 | 1 |")
+	})
+
+	t.Run("Suffix workloads with the same document name", func(t *testing.T) {
+		lambda, _ := newLocalLambda(t)
+
+		request := readEvent("../../events/render-documents.json")
+		request.Accounts[0].Workload = "Team-A"
+		request.Accounts[1].Workload = "Team A"
+		request.Accounts = request.Accounts[:2]
+
+		response, err := lambda.Handler(ctx, request)
+		require.NoError(t, err)
+		assert.Equal(t, []string{
+			prefix + "/summary.html",
+			prefix + "/summary.md",
+			prefix + "/workloads/Team-A.html",
+			prefix + "/workloads/Team-A.md",
+			prefix + "/workloads/Team-A-2.html",
+			prefix + "/workloads/Team-A-2.md",
+		}, response.Documents)
+	})
+
+	t.Run("Use the templates of the report", func(t *testing.T) {
+		lambda, store := newLocalLambda(t)
+		store.Seed("templates/compact/workload.md.tmpl", "{{.Workload}}: {{if .Score}}{{score .Score.Score}} ({{change .Score.Change}}){{end}}")
+
+		request := readEvent("../../events/render-documents.json")
+		request.Options.Documents.Templates = "templates/compact"
+
+		_, err := lambda.Handler(ctx, request)
+		require.NoError(t, err)
+		assert.Equal(t, "my-workload: 85% (new)", string(store.Read(prefix+"/workloads/my-workload.md")))
+		assert.Contains(t, string(store.Read(prefix+"/workloads/my-workload.html")), "<h1>Security posture of my-workload</h1>")
+	})
+
+	t.Run("Use the templates of the configuration bucket", func(t *testing.T) {
+		_ = os.Setenv("TEMPLATES_BUCKET", "my-config-bucket")
+		defer func() { _ = os.Setenv("TEMPLATES_BUCKET", "") }()
+
+		lambda, store := newLocalLambda(t)
+		require.NoError(t, store.Upload(ctx, "my-config-bucket", "templates/compact/workload.md.tmpl", []byte("{{.Workload}}")))
+		store.Seed("templates/compact/summary.md.tmpl", "the findings bucket is not used")
+
+		request := readEvent("../../events/render-documents.json")
+		request.Options.Documents.Templates = "templates/compact"
+
+		_, err := lambda.Handler(ctx, request)
+		require.NoError(t, err)
+		assert.Equal(t, "my-workload", string(store.Read(prefix+"/workloads/my-workload.md")))
+		assert.Contains(t, string(store.Read(prefix+"/summary.md")), "**Score: 85%**")
+	})
+
+	t.Run("Use the built-in templates without templates below the prefix", func(t *testing.T) {
+		lambda, store := newLocalLambda(t)
+
+		request := readEvent("../../events/render-documents.json")
+		request.Options.Documents.Templates = "templates/missing"
+
+		_, err := lambda.Handler(ctx, request)
+		require.NoError(t, err)
+		assert.Contains(t, string(store.Read(prefix+"/workloads/my-workload.html")), "<h1>Security posture of my-workload</h1>")
+	})
+
+	t.Run("Invalid template of the report", func(t *testing.T) {
+		lambda, store := newLocalLambda(t)
+		store.Seed("templates/broken/summary.html.tmpl", "{{.Report")
+
+		request := readEvent("../../events/render-documents.json")
+		request.Options.Documents.Templates = "templates/broken"
+
+		_, err := lambda.Handler(ctx, request)
+		assert.ErrorContains(t, err, "template summary.html.tmpl: ")
+	})
+
+	t.Run("Skip a report without documents", func(t *testing.T) {
+		stubber := testtools.NewStubber()
+		lambda := New(*stubber.SdkConfig)
+
+		request := readEvent("../../events/render-documents.json")
+		request.Options = report.Options{}
+
+		response, err := lambda.Handler(ctx, request)
+		testtools.ExitTest(stubber, t)
+		assert.NoError(t, err)
+		assert.Empty(t, response.Documents)
+	})
+}
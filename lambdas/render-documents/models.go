@@ -0,0 +1,57 @@
+package main
+
+import (
+	"shared/dimension"
+	"shared/report"
+	"shared/score"
+	"shared/statefile"
+)
+
+// CalculatedScore is the part of the result of calculate-score the documents are rendered from.
+type CalculatedScore struct {
+	AccountId      string          `json:"AccountId"`
+	AccountName    string          `json:"AccountName"`
+	Workload       string          `json:"Workload"`
+	Environment    string          `json:"Environment"`
+	Status         score.Status    `json:"Status"`
+	Baseline       *score.Baseline `json:"Baseline,omitempty"`
+	Score          float64         `json:"Score"`
+	FailedControls string          `json:"FailedControls,omitempty"`
+	// Dimensions are the values of the split dimensions, an account has a score for every combination of values.
+	Dimensions []dimension.Dimension `json:"Dimensions"`
+}
+
+// Exclusion is an account fetch-account-mapping did not score, with the reason.
+type Exclusion struct {
+	AccountId   string `json:"AccountId"`
+	AccountName string `json:"AccountName"`
+	Reason      string `json:"Reason"`
+}
+
+type Request struct {
+	Report     string             `json:"Report"`
+	Timestamp  int64              `json:"Timestamp"`
+	Bucket     string             `json:"Bucket"`
+	Accounts   []*CalculatedScore `json:"Accounts"`
+	Exclusions string             `json:"Exclusions"`
+	Options    report.Options     `json:"Options"`
+}
+
+type Response struct {
+	// Documents are the keys of the rendered documents.
+	Documents []string `json:"Documents"`
+}
+
+// State is kept in the bucket between the runs of a report, to show the trend of the scores.
+type State struct {
+	statefile.Header
+	// Score is the average score of the organization, nil when no account was scored.
+	Score     *float64                 `json:"Score,omitempty"`
+	Workloads map[string]WorkloadState `json:"Workloads"`
+}
+
+// WorkloadState is the average score of a workload, and of each of its environments.
+type WorkloadState struct {
+	Score        float64            `json:"Score"`
+	Environments map[string]float64 `json:"Environments"`
+}
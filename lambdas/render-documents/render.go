@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmlTemplate "html/template"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	textTemplate "text/template"
+	"time"
+)
+
+// The templates a report renders, a report can replace each of them with a file of the same name in the bucket.
+const (
+	SummaryHtml      = "summary.html.tmpl"
+	SummaryMarkdown  = "summary.md.tmpl"
+	WorkloadHtml     = "workload.html.tmpl"
+	WorkloadMarkdown = "workload.md.tmpl"
+)
+
+//go:embed templates/*.tmpl
+var defaultTemplates embed.FS
+
+// executor is what html/template and text/template have in common.
+type executor interface {
+	Execute(w io.Writer, data any) error
+}
+
+// Renderer renders the documents with the parsed templates, by template name.
+type Renderer struct {
+	templates map[string]executor
+}
+
+// templateFuncs are available in every template.
+var templateFuncs = map[string]any{
+	"score": func(value float64) string {
+		return formatNumber(value) + "%"
+	},
+	"change": func(value *float64) string {
+		if value == nil {
+			return "new"
+		}
+
+		rounded := math.Round(*value*100) / 100
+		switch {
+		case rounded > 0:
+			return "+" + formatNumber(rounded)
+		case rounded < 0:
+			return formatNumber(rounded)
+		}
+
+		return "±0"
+	},
+	// direction is the class of a change in the HTML templates: up, down, same or new.
+	"direction": func(value *float64) string {
+		if value == nil {
+			return "new"
+		}
+
+		rounded := math.Round(*value*100) / 100
+		switch {
+		case rounded > 0:
+			return "up"
+		case rounded < 0:
+			return "down"
+		}
+
+		return "same"
+	},
+	"date": func(t time.Time) string {
+		return t.Format("2006-01-02 15:04 MST")
+	},
+	// cell escapes the characters that end a cell of a Markdown table.
+	"cell": func(value string) string {
+		return strings.NewReplacer("|", "\\|", "\n", " ").Replace(value)
+	},
+}
+
+// NewRenderer parses the templates, the overrides replace the built-in templates with the same name.
+func NewRenderer(overrides map[string]string) (*Renderer, error) {
+	renderer := &Renderer{templates: map[string]executor{}}
+
+	for _, name := range []string{SummaryHtml, SummaryMarkdown, WorkloadHtml, WorkloadMarkdown} {
+		source, ok := overrides[name]
+
+		if !ok {
+			data, err := defaultTemplates.ReadFile("templates/" + name)
+
+			if err != nil {
+				return nil, err
+			}
+
+			source = string(data)
+		}
+
+		var parsed executor
+		var err error
+
+		// HTML templates escape their values for the context they are written in, Markdown is written as is.
+		if strings.HasSuffix(name, ".html.tmpl") {
+			parsed, err = htmlTemplate.New(name).Funcs(templateFuncs).Parse(source)
+		} else {
+			parsed, err = textTemplate.New(name).Funcs(templateFuncs).Parse(source)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("template %s: %w", name, err)
+		}
+
+		renderer.templates[name] = parsed
+	}
+
+	return renderer, nil
+}
+
+// Render executes the template with the document.
+func (r *Renderer) Render(name string, document Document) ([]byte, error) {
+	var buffer bytes.Buffer
+	err := r.templates[name].Execute(&buffer, document)
+
+	if err != nil {
+		return nil, fmt.Errorf("template %s: %w", name, err)
+	}
+
+	return buffer.Bytes(), nil
+}
+
+func formatNumber(value float64) string {
+	return strconv.FormatFloat(math.Round(value*100)/100, 'f', -1, 64)
+}
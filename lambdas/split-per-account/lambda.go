@@ -1,27 +1,29 @@
 package main
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/service/s3"
-	"io"
 	"log"
-	"path/filepath"
+	"shared/artifact"
+	"shared/blobstore"
+	"shared/dimension"
+	"shared/finding"
+	"shared/layout"
 	"sort"
+	"strings"
 	"time"
 )
 
 type Lambda struct {
-	ctx      context.Context
-	s3Client *s3.Client
+	ctx   context.Context
+	store blobstore.BlobStore
 }
 
 func New(cfg aws.Config) *Lambda {
 	m := new(Lambda)
-	m.s3Client = s3.NewFromConfig(cfg)
+	m.store = blobstore.NewFromConfig(cfg)
 	return m
 }
 
@@ -34,15 +36,22 @@ func (x *Lambda) Handler(ctx context.Context, request Request) (Response, error)
 		Bucket:    request.Bucket,
 		Accounts:  []Account{},
 		Timestamp: request.Timestamp,
+		Options:   request.Options,
 	}
 
-	aggregatedFindings, err := x.downloadFindings(request.Bucket, request.AggregatedFindings)
+	err := dimension.Validate(request.SplitBy)
 
 	if err != nil {
 		return response, err
 	}
 
-	findings, err := x.downloadFindings(request.Bucket, request.Findings)
+	aggregatedFindings, err := x.downloadFindings(request.Bucket, request.AggregatedFindings, request.Checksums)
+
+	if err != nil {
+		return response, err
+	}
+
+	findings, err := x.downloadFindings(request.Bucket, request.Findings, request.Checksums)
 
 	if err != nil {
 		return response, err
@@ -50,67 +59,100 @@ func (x *Lambda) Handler(ctx context.Context, request Request) (Response, error)
 
 	mergedFindings := append(aggregatedFindings, findings...)
 
-	for accountId, accountFindings := range x.splitPerAccountId(mergedFindings) {
-		data, _ := json.Marshal(accountFindings)
-		accountObjectKey, err := x.uploadFile(accountId, data)
+	for _, partition := range x.split(mergedFindings, request.SplitBy) {
+		data, _ := json.Marshal(partition.Findings)
+		accountObjectKey, err := x.uploadFile(partition.AccountId, partition.Dimensions, data)
 
 		if err != nil {
 			return response, err
 		}
 
+		checksum := artifact.NewChecksum(data, len(partition.Findings))
+
 		response.Accounts = append(response.Accounts, Account{
-			AccountId:   accountId,
-			AccountName: x.resolveAccountName(accountFindings),
+			AccountId:   partition.AccountId,
+			AccountName: x.resolveAccountName(partition.Findings),
 			Bucket:      request.Bucket,
 			Key:         accountObjectKey,
 			Controls:    request.Controls,
 			GroupBy:     request.GroupBy,
+			Checksum:    &checksum,
+			Dimensions:  partition.Dimensions,
 		})
 	}
 
 	return response, err
 }
 
-func (x *Lambda) splitPerAccountId(findings []*Finding) map[string][]*Finding {
-	var findingsPerAccount = make(map[string][]*Finding)
+type Partition struct {
+	AccountId  string
+	Dimensions []dimension.Dimension
+	Findings   []*finding.Finding
+}
 
-	for _, finding := range findings {
-		AwsAccountId := finding.AwsAccountId
-		findingsPerAccount[AwsAccountId] = append(findingsPerAccount[AwsAccountId], finding)
-	}
+// split partitions the findings per account and the values of the SplitBy dimensions, sorted by account and values.
+func (x *Lambda) split(findings []*finding.Finding, splitBy []string) []*Partition {
+	var partitions []*Partition
+	index := map[string]*Partition{}
 
-	return x.sortByAccountId(findingsPerAccount)
-}
+	for _, record := range findings {
+		dimensions := dimension.Resolve(splitBy, record)
+		id := record.AwsAccountId + "/" + dimension.Id(dimensions)
+		partition, ok := index[id]
 
-func (x *Lambda) sortByAccountId(findingsPerAccount map[string][]*Finding) map[string][]*Finding {
-	var accountIds []string
+		if !ok {
+			partition = &Partition{AccountId: record.AwsAccountId, Dimensions: dimensions}
+			index[id] = partition
+			partitions = append(partitions, partition)
+		}
 
-	for accountId := range findingsPerAccount {
-		accountIds = append(accountIds, accountId)
+		partition.Findings = append(partition.Findings, record)
 	}
-	sort.Strings(accountIds)
 
-	var findingsPerAccountSorted = make(map[string][]*Finding)
+	sort.SliceStable(partitions, func(i, j int) bool {
+		if partitions[i].AccountId != partitions[j].AccountId {
+			return partitions[i].AccountId < partitions[j].AccountId
+		}
+
+		return sortKey(partitions[i].Dimensions) < sortKey(partitions[j].Dimensions)
+	})
+
+	return partitions
+}
+
+func sortKey(dimensions []dimension.Dimension) string {
+	var values []string
 
-	for _, accountId := range accountIds {
-		findingsPerAccountSorted[accountId] = findingsPerAccount[accountId]
+	for _, value := range dimensions {
+		values = append(values, value.Value)
 	}
 
-	return findingsPerAccountSorted
+	return strings.Join(values, "\n")
 }
 
-func (x *Lambda) downloadFindings(bucket string, keys []string) ([]*Finding, error) {
-	var findings []*Finding
+func (x *Lambda) downloadFindings(bucket string, keys []string, checksums artifact.Checksums) ([]*finding.Finding, error) {
+	var findings []*finding.Finding
 
 	for _, key := range keys {
-		data, err := x.downloadFile(bucket, key)
+		data, err := x.store.Download(x.ctx, bucket, key)
+		if err != nil {
+			return []*finding.Finding{}, err
+		}
+		checksum, err := checksums.Require(key)
+		if err != nil {
+			return []*finding.Finding{}, err
+		}
+		err = checksum.VerifySum(key, artifact.Sum(data))
+		if err != nil {
+			return []*finding.Finding{}, err
+		}
+		records, err := finding.Decode(data)
 		if err != nil {
-			return []*Finding{}, err
+			return []*finding.Finding{}, err
 		}
-		var records []*Finding
-		err = json.Unmarshal(data, &records)
+		err = checksum.VerifyRecords(key, len(records))
 		if err != nil {
-			return []*Finding{}, err
+			return []*finding.Finding{}, err
 		}
 		findings = append(findings, records...)
 
@@ -120,52 +162,23 @@ func (x *Lambda) downloadFindings(bucket string, keys []string) ([]*Finding, err
 	return findings, nil
 }
 
-func (x *Lambda) downloadFile(bucket string, key string) ([]byte, error) {
-	log.Printf("Downloading s3://%s/%s", bucket, key)
-
-	response, err := x.s3Client.GetObject(x.ctx, &s3.GetObjectInput{
-		Bucket: aws.String(bucket),
-		Key:    aws.String(key),
-	})
-	if err != nil {
-		return nil, err
-	}
-
-	return io.ReadAll(response.Body)
-}
-
-func (x *Lambda) uploadFile(accountId string, data []byte) (string, error) {
+func (x *Lambda) uploadFile(accountId string, dimensions []dimension.Dimension, data []byte) (string, error) {
 	request := x.ctx.Value("request").(Request)
-	key := x.resolveBucketKey(accountId)
-	log.Printf("Upload to s3://%s/%s", request.Bucket, key)
+	key := layout.Timestamped(request.Report, accountId, request.Timestamp)
 
-	_, err := x.s3Client.PutObject(x.ctx, &s3.PutObjectInput{
-		Bucket: aws.String(request.Bucket),
-		Key:    aws.String(key),
-		Body:   bytes.NewReader(data),
-	})
+	// Every combination of dimension values of an account is stored next to each other, suffixed by its id.
+	if len(dimensions) > 0 {
+		key = layout.Key(request.Report, accountId, time.Unix(request.Timestamp, 0), fmt.Sprintf("%d-%s", request.Timestamp, dimension.Id(dimensions)))
+	}
+	err := x.store.Upload(x.ctx, request.Bucket, key, data)
 
 	return key, err
 }
 
-func (x *Lambda) resolveBucketKey(accountId string) string {
-	request := x.ctx.Value("request").(Request)
-	t := time.Unix(request.Timestamp, 0)
-
-	return filepath.Join(
-		request.Report,
-		accountId,
-		fmt.Sprintf("%d", t.Year()),
-		fmt.Sprintf("%02d", int(t.Month())),
-		fmt.Sprintf("%02d", t.Day()),
-		fmt.Sprintf("%d.json", t.Unix()),
-	)
-}
-
-func (x *Lambda) resolveAccountName(findings []*Finding) string {
-	for _, finding := range findings {
-		if finding.AwsAccountName != "" {
-			return finding.AwsAccountName
+func (x *Lambda) resolveAccountName(findings []*finding.Finding) string {
+	for _, record := range findings {
+		if record.AwsAccountName != "" {
+			return record.AwsAccountName
 		}
 	}
 
@@ -8,6 +8,9 @@ import (
 	"github.com/stretchr/testify/assert"
 	"io"
 	"os"
+	"shared/artifact"
+	"shared/dimension"
+	"shared/finding"
 	"testing"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -23,17 +26,17 @@ func readEvent(path string) Request {
 	return event
 }
 
-func readStrippedFindings(path string) ([]Finding, []byte, []byte) {
+func readStrippedFindings(path string) ([]finding.Finding, []byte, []byte) {
 	file, _ := os.ReadFile(path)
 
-	var findings []Finding
+	var findings []finding.Finding
 	_ = json.Unmarshal(file, &findings)
 	dataset1, _ := json.Marshal(findings[0:4])
 	dataset2, _ := json.Marshal(findings[4:7])
 	return findings, dataset1, dataset2
 }
 
-func getPages(findings []Finding) ([]byte, []byte, []byte) {
+func getPages(findings []finding.Finding) ([]byte, []byte, []byte) {
 	page1, _ := json.Marshal(findings[0:3])
 	page2, _ := json.Marshal(findings[3:6])
 	page3, _ := json.Marshal(findings[6:7])
@@ -59,6 +62,11 @@ func TestHandler(t *testing.T) {
 				"aws-foundational-security-best-practices/raw/2023/08/13/e43e3ef4-9380-11ee-b9d1-0242ac120002.json",
 				"aws-foundational-security-best-practices/raw/2023/08/13/e88c8e3e-9380-11ee-b9d1-0242ac120002.json",
 			},
+			Checksums: artifact.Checksums{
+				"aws-foundational-security-best-practices/raw/2023/08/13/dfcec91a-9380-11ee-b9d1-0242ac120002.json": artifact.NewChecksum(page1, 3),
+				"aws-foundational-security-best-practices/raw/2023/08/13/e43e3ef4-9380-11ee-b9d1-0242ac120002.json": artifact.NewChecksum(page2, 3),
+				"aws-foundational-security-best-practices/raw/2023/08/13/e88c8e3e-9380-11ee-b9d1-0242ac120002.json": artifact.NewChecksum(page3, 1),
+			},
 		}
 
 		stubber := testtools.NewStubber()
@@ -120,6 +128,12 @@ func TestHandler(t *testing.T) {
 			assert.Equal(t, event.GroupBy, account.GroupBy)
 		}
 
+		checksums := map[string]artifact.Checksum{}
+		for _, account := range response.Accounts {
+			checksums[account.AccountId] = *account.Checksum
+		}
+		assert.Equal(t, artifact.NewChecksum(dataset1, 4), checksums["111122223333"])
+		assert.Equal(t, artifact.NewChecksum(dataset2, 3), checksums["333322221111"])
 	})
 
 	t.Run("Read 2 raw findings and 1 aggregated and split based on AccountId", func(t *testing.T) {
@@ -136,6 +150,11 @@ func TestHandler(t *testing.T) {
 			AggregatedFindings: []string{
 				"aws-foundational-security-best-practices/aggregated/2023/08/13/dfcec91a-9380-11ee-b9d1-0242ac120002.json",
 			},
+			Checksums: artifact.Checksums{
+				"aws-foundational-security-best-practices/aggregated/2023/08/13/dfcec91a-9380-11ee-b9d1-0242ac120002.json": artifact.NewChecksum(page1, 3),
+				"aws-foundational-security-best-practices/raw/2023/08/13/e43e3ef4-9380-11ee-b9d1-0242ac120002.json":        artifact.NewChecksum(page2, 3),
+				"aws-foundational-security-best-practices/raw/2023/08/13/e88c8e3e-9380-11ee-b9d1-0242ac120002.json":        artifact.NewChecksum(page3, 1),
+			},
 		}
 
 		stubber := testtools.NewStubber()
@@ -214,8 +233,109 @@ func TestHandler(t *testing.T) {
 		testtools.ExitTest(stubber, t)
 	})
 
+	t.Run("Split by region and resource tag", func(t *testing.T) {
+		regionalFindings := []finding.Finding{
+			{Id: "1", AwsAccountId: "111122223333", Region: "eu-west-1", ResourceTags: map[string]string{"team": "platform"}},
+			{Id: "2", AwsAccountId: "111122223333", Region: "eu-central-1"},
+			{Id: "3", AwsAccountId: "111122223333", Region: "eu-west-1", ResourceTags: map[string]string{"team": "platform"}},
+		}
+		data, _ := json.Marshal(regionalFindings)
+
+		event := Request{
+			Bucket:    "my-sample-bucket",
+			Report:    "aws-foundational-security-best-practices",
+			Timestamp: 1691920532,
+			SplitBy:   []string{"Account", "Region", "Tag:team"},
+			Findings:  []string{"aws-foundational-security-best-practices/raw/2023/08/13/dfcec91a-9380-11ee-b9d1-0242ac120002.json"},
+			Checksums: artifact.Checksums{"aws-foundational-security-best-practices/raw/2023/08/13/dfcec91a-9380-11ee-b9d1-0242ac120002.json": artifact.NewChecksum(data, len(regionalFindings))},
+		}
+
+		stubber := testtools.NewStubber()
+		lambda := New(*stubber.SdkConfig)
+		stubber.Add(testtools.Stub{
+			OperationName: "GetObject",
+			Input:         &s3.GetObjectInput{Bucket: aws.String("my-sample-bucket"), Key: aws.String("aws-foundational-security-best-practices/raw/2023/08/13/dfcec91a-9380-11ee-b9d1-0242ac120002.json")},
+			Output:        &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(data))},
+		})
+		for i := 0; i < 2; i++ {
+			stubber.Add(testtools.Stub{
+				OperationName: "PutObject",
+				Input:         &s3.PutObjectInput{Bucket: aws.String("my-sample-bucket")},
+				Output:        &s3.PutObjectOutput{},
+				IgnoreFields:  []string{"Key", "Body"},
+			})
+		}
+
+		response, err := lambda.Handler(ctx, event)
+		testtools.ExitTest(stubber, t)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, len(response.Accounts))
+		assert.Equal(t, []dimension.Dimension{{Name: "Region", Value: "eu-central-1"}, {Name: "Tag:team", Value: dimension.Missing}}, response.Accounts[0].Dimensions)
+		assert.Equal(t, []dimension.Dimension{{Name: "Region", Value: "eu-west-1"}, {Name: "Tag:team", Value: "platform"}}, response.Accounts[1].Dimensions)
+		assert.Equal(t, 1, response.Accounts[0].Checksum.Records)
+		assert.Equal(t, 2, response.Accounts[1].Checksum.Records)
+		assert.NotEqual(t, response.Accounts[0].Key, response.Accounts[1].Key)
+		assert.Regexp(t, "^aws-foundational-security-best-practices/111122223333/2023/08/13/1691920532-[a-f0-9]{12}.json$", response.Accounts[0].Key)
+	})
+
+	t.Run("Fail on unknown split dimension", func(t *testing.T) {
+		event := readEvent("../../events/split-per-account.json")
+		event.SplitBy = []string{"Availability Zone"}
+		stubber := testtools.NewStubber()
+		lambda := New(*stubber.SdkConfig)
+
+		_, err := lambda.Handler(ctx, event)
+		testtools.ExitTest(stubber, t)
+		assert.Error(t, err)
+	})
+
+	t.Run("Fail on checksum mismatch", func(t *testing.T) {
+		event := readEvent("../../events/split-per-account.json")
+		event.Checksums = artifact.Checksums{
+			"aws-foundational-security-best-practices/raw/2023/08/13/dfcec91a-9380-11ee-b9d1-0242ac120002.json": artifact.NewChecksum(page2, 3),
+		}
+		stubber := testtools.NewStubber()
+		lambda := New(*stubber.SdkConfig)
+		stubber.Add(testtools.Stub{
+			OperationName: "GetObject",
+			Input:         &s3.GetObjectInput{Bucket: aws.String("my-sample-bucket"), Key: aws.String("aws-foundational-security-best-practices/raw/2023/08/13/dfcec91a-9380-11ee-b9d1-0242ac120002.json")},
+			Output:        &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(page1))},
+		})
+
+		_, err := lambda.Handler(ctx, event)
+		testtools.ExitTest(stubber, t)
+
+		var integrityErr *artifact.IntegrityError
+		assert.True(t, errors.As(err, &integrityErr))
+		assert.Equal(t, "SHA256", integrityErr.Field)
+	})
+
+	t.Run("Fail on a missing checksum", func(t *testing.T) {
+		event := readEvent("../../events/split-per-account.json")
+		stubber := testtools.NewStubber()
+		lambda := New(*stubber.SdkConfig)
+		stubber.Add(testtools.Stub{
+			OperationName: "GetObject",
+			Input:         &s3.GetObjectInput{Bucket: aws.String("my-sample-bucket"), Key: aws.String("aws-foundational-security-best-practices/raw/2023/08/13/dfcec91a-9380-11ee-b9d1-0242ac120002.json")},
+			Output:        &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(page1))},
+		})
+
+		_, err := lambda.Handler(ctx, event)
+		testtools.ExitTest(stubber, t)
+
+		var integrityErr *artifact.IntegrityError
+		assert.True(t, errors.As(err, &integrityErr))
+		assert.Equal(t, "Checksum", integrityErr.Field)
+	})
+
 	t.Run("Fail on upload", func(t *testing.T) {
 		event := readEvent("../../events/split-per-account.json")
+		event.Checksums = artifact.Checksums{
+			"aws-foundational-security-best-practices/raw/2023/08/13/dfcec91a-9380-11ee-b9d1-0242ac120002.json": artifact.NewChecksum(page1, 3),
+			"aws-foundational-security-best-practices/raw/2023/08/13/e43e3ef4-9380-11ee-b9d1-0242ac120002.json": artifact.NewChecksum(page2, 3),
+			"aws-foundational-security-best-practices/raw/2023/08/13/e88c8e3e-9380-11ee-b9d1-0242ac120002.json": artifact.NewChecksum(page3, 1),
+		}
 		stubber := testtools.NewStubber()
 		lambda := New(*stubber.SdkConfig)
 		raiseErr := &testtools.StubError{Err: errors.New("failed")}
@@ -1,37 +1,39 @@
 package main
 
+import (
+	"shared/artifact"
+	"shared/dimension"
+	"shared/report"
+)
+
 type Request struct {
-	Report             string   `json:"Report"`
-	Timestamp          int64    `json:"Timestamp"`
-	Bucket             string   `json:"Bucket"`
-	Controls           string   `json:"Controls"`
-	GroupBy            string   `json:"GroupBy"`
-	Findings           []string `json:"Findings"`
-	AggregatedFindings []string `json:"AggregatedFindings"`
+	Report             string             `json:"Report"`
+	Timestamp          int64              `json:"Timestamp"`
+	Bucket             string             `json:"Bucket"`
+	Controls           string             `json:"Controls"`
+	GroupBy            string             `json:"GroupBy"`
+	SplitBy            []string           `json:"SplitBy"`
+	Options            report.Options     `json:"Options"`
+	Findings           []string           `json:"Findings"`
+	AggregatedFindings []string           `json:"AggregatedFindings"`
+	Checksums          artifact.Checksums `json:"Checksums"`
 }
 
 type Account struct {
-	AccountId   string `json:"AccountId"`
-	AccountName string `json:"AccountName"`
-	Bucket      string `json:"Bucket"`
-	Key         string `json:"Key"`
-	Controls    string `json:"Controls"`
-	GroupBy     string `json:"GroupBy"`
-}
-
-type Finding struct {
-	Id             string `json:"Id"`
-	Status         string `json:"Status"`
-	ProductArn     string `json:"ProductArn"`
-	GeneratorId    string `json:"GeneratorId"`
-	AwsAccountId   string `json:"AwsAccountId"`
-	AwsAccountName string `json:"AwsAccountName"`
-	Title          string `json:"Title"`
+	AccountId   string                `json:"AccountId"`
+	AccountName string                `json:"AccountName"`
+	Bucket      string                `json:"Bucket"`
+	Key         string                `json:"Key"`
+	Controls    string                `json:"Controls"`
+	GroupBy     string                `json:"GroupBy"`
+	Checksum    *artifact.Checksum    `json:"Checksum"`
+	Dimensions  []dimension.Dimension `json:"Dimensions"`
 }
 
 type Response struct {
-	Report    string    `json:"Report"`
-	Timestamp int64     `json:"Timestamp"`
-	Bucket    string    `json:"Bucket"`
-	Accounts  []Account `json:"Accounts"`
+	Report    string         `json:"Report"`
+	Timestamp int64          `json:"Timestamp"`
+	Bucket    string         `json:"Bucket"`
+	Accounts  []Account      `json:"Accounts"`
+	Options   report.Options `json:"Options"`
 }
@@ -1,12 +1,17 @@
 package main
 
-import "github.com/aws/aws-sdk-go-v2/service/securityhub/types"
+import (
+	"github.com/aws/aws-sdk-go-v2/service/securityhub/types"
+	"shared/report"
+)
 
 type Request struct {
 	Report          string                          `json:"Report"`
 	Bucket          string                          `json:"Bucket"`
 	SubscriptionArn string                          `json:"SubscriptionArn"`
 	Filter          types.AwsSecurityFindingFilters `json:"Filter"`
+	SplitBy         []string                        `json:"SplitBy"`
+	Options         report.Options                  `json:"Options"`
 }
 
 type Response struct {
@@ -15,4 +20,6 @@ type Response struct {
 	Controls string                          `json:"Controls"`
 	GroupBy  string                          `json:"GroupBy"`
 	Filter   types.AwsSecurityFindingFilters `json:"Filter"`
+	SplitBy  []string                        `json:"SplitBy"`
+	Options  report.Options                  `json:"Options"`
 }
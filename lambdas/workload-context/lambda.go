@@ -5,10 +5,25 @@ import (
 	"errors"
 	"fmt"
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"log"
 	"os"
+	"regexp"
+	"shared/classification"
+	"shared/dimension"
+	"shared/owner"
 	"strings"
 )
 
+const (
+	defaultWorkloadTagKey    = "workload"
+	defaultEnvironmentTagKey = "environment"
+	defaultOwnerTagKey       = "owner"
+	defaultContactTagKey     = "contact"
+	defaultCostCenterTagKey  = "cost-center"
+	defaultEnvironment       = "production"
+	unclassified             = "unclassified"
+)
+
 type Lambda struct {
 	ctx context.Context
 }
@@ -25,15 +40,131 @@ func (x *Lambda) Handler(ctx context.Context, request Request) (Response, error)
 		Key:         request.Key,
 		GroupBy:     request.GroupBy,
 		Controls:    request.Controls,
+		Checksum:    request.Checksum,
+		Dimensions:  request.Dimensions,
+		Baseline:    request.Baseline,
+		Options:     request.Options,
 	}
 	x.ctx = ctx
 
+	// The classification file is read once per run by fetch-account-mapping, it passes the override of the account.
+	override := request.Override
+
+	if override == nil {
+		override = &classification.Override{}
+	}
+
+	if override.Exclude {
+		log.Printf("Account %s is excluded by the classification file", request.AccountId)
+	}
+
+	response.Excluded = override.Exclude
+
+	rules, err := loadNamingRules()
+
+	if err != nil {
+		return response, err
+	}
+
+	environments, err := loadOrganizationalUnitEnvironments()
+
+	if err != nil {
+		return response, err
+	}
+
+	unitEnvironment := ""
+	if environments != nil {
+		// The OU path is resolved by fetch-account-mapping, as part of the account inventory.
+		path := request.OrganizationalUnit
+
+		if path == "" {
+			path = dimension.Missing
+		}
+
+		response.OrganizationalUnit = path
+		response.Dimensions = append(response.Dimensions, dimension.Dimension{Name: dimension.OrganizationalUnit, Value: path})
+		unitEnvironment = matchOrganizationalUnit(environments, path)
+	}
+
+	classification := x.resolveContext(request, override, rules, unitEnvironment)
+	response.Workload = classification.Workload
+	response.Environment = classification.Environment
+	response.Owner = x.resolveOwner(request, override, classification)
+
+	return response, nil
+}
+
+// resolveOwner prefers the classification file, then the account tags and then the team of the naming rule.
+func (x *Lambda) resolveOwner(request Request, override *classification.Override, classification Classification) owner.Owner {
+	fromFile := owner.Owner{Team: override.Owner, Email: override.ContactEmail, CostCenter: override.CostCenter}
+	fromTags := owner.Owner{
+		Team:       request.Tags[lookupEnv("OWNER_TAG_KEY", defaultOwnerTagKey)],
+		Email:      request.Tags[lookupEnv("CONTACT_TAG_KEY", defaultContactTagKey)],
+		CostCenter: request.Tags[lookupEnv("COST_CENTER_TAG_KEY", defaultCostCenterTagKey)],
+	}
+
+	return fromFile.Merge(fromTags).Merge(owner.Owner{Team: classification.Team})
+}
+
+// resolveContext prefers the classification file, then the account tags and then the environment of the OU. The
+// account name is only classified when the workload or environment is still missing.
+func (x *Lambda) resolveContext(request Request, override *classification.Override, rules []*regexp.Regexp, unitEnvironment string) Classification {
+	workload := override.Workload
+	environment := override.Environment
+
+	if workload == "" {
+		workload = request.Tags[lookupEnv("WORKLOAD_TAG_KEY", defaultWorkloadTagKey)]
+	}
+
+	if environment == "" {
+		environment = request.Tags[lookupEnv("ENVIRONMENT_TAG_KEY", defaultEnvironmentTagKey)]
+	}
+
+	if environment == "" {
+		environment = unitEnvironment
+	}
+
+	if workload != "" && environment != "" {
+		return Classification{Workload: workload, Environment: environment}
+	}
+
+	classification, err := x.classifyName(request, rules)
+
+	if workload != "" {
+		// Without an environment tag, or one in the name, the account is considered production.
+		classification.Workload = workload
+		if err != nil {
+			classification.Environment = defaultEnvironment
+		}
+	} else if err != nil {
+		log.Printf("%s, account %s is %s", err, request.AccountId, unclassified)
+		return Classification{Workload: unclassified, Environment: unclassified}
+	}
+
+	if environment != "" {
+		classification.Environment = environment
+	}
+
+	return classification
+}
+
+// classifyName applies the configured naming rules, without rules the name is parsed as <prefix>-<workload>-<environment>.
+func (x *Lambda) classifyName(request Request, rules []*regexp.Regexp) (Classification, error) {
+	if len(rules) > 0 {
+		return matchNamingRules(rules, request.AccountName)
+	}
+
 	name := x.platformOverwrite(request.AccountId, request.AccountName)
 	workload, environment, err := x.resolveWorkloadAndEnvironment(name)
-	response.Workload = workload
-	response.Environment = environment
+	return Classification{Workload: workload, Environment: environment}, err
+}
+
+func lookupEnv(key string, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
 
-	return response, err
+	return fallback
 }
 
 func (x *Lambda) platformOverwrite(accountId string, name string) string {
@@ -51,7 +182,7 @@ func (x *Lambda) platformOverwrite(accountId string, name string) string {
 
 func (x *Lambda) resolveWorkloadAndEnvironment(name string) (string, string, error) {
 	workload := ""
-	environment := "production"
+	environment := defaultEnvironment
 	parts := strings.Split(name, "-")
 
 	if len(parts) < 2 {
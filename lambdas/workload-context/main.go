@@ -2,9 +2,9 @@ package main
 
 import (
 	"context"
-	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"log"
+	"shared/invoke"
 )
 
 func main() {
@@ -13,5 +13,11 @@ func main() {
 		log.Printf("error: %v", err)
 		return
 	}
-	lambda.Start(New(cfg).Handler)
+	// Invalid naming rules would fail every account, so they keep the function from starting.
+	_, err = loadNamingRules()
+	if err != nil {
+		log.Printf("error: %v", err)
+		return
+	}
+	invoke.Start(New(cfg).Handler)
 }
@@ -6,6 +6,9 @@ import (
 	"github.com/awsdocs/aws-doc-sdk-examples/gov2/testtools"
 	"github.com/stretchr/testify/assert"
 	"os"
+	"shared/classification"
+	"shared/dimension"
+	"shared/owner"
 	"testing"
 )
 
@@ -32,6 +35,7 @@ func TestHandler(t *testing.T) {
 		assert.NoError(t, err)
 		assert.Equal(t, event.Bucket, response.Bucket)
 		assert.Equal(t, event.Key, response.Key)
+		assert.Equal(t, event.Checksum, response.Checksum)
 		assert.Equal(t, event.AccountId, response.AccountId)
 		assert.Equal(t, "my-workload", response.Workload)
 		assert.Equal(t, "development", response.Environment)
@@ -129,3 +133,281 @@ func TestHandler(t *testing.T) {
 		_ = os.Setenv("PLATFORM_ACCOUNTS", "")
 	})
 }
+
+func TestTags(t *testing.T) {
+	ctx := context.Background()
+	event := readEvent("../../events/workload-context.json")
+
+	t.Run("Tags take precedence over the account name", func(t *testing.T) {
+		stubber := testtools.NewStubber()
+		lambda := New(*stubber.SdkConfig)
+
+		event.AccountName = "prefix-my-workload-development"
+		event.Tags = map[string]string{"workload": "payments", "environment": "test"}
+
+		response, err := lambda.Handler(ctx, event)
+		testtools.ExitTest(stubber, t)
+		assert.NoError(t, err)
+		assert.Equal(t, "payments", response.Workload)
+		assert.Equal(t, "test", response.Environment)
+	})
+
+	t.Run("Account names outside the convention", func(t *testing.T) {
+		stubber := testtools.NewStubber()
+		lambda := New(*stubber.SdkConfig)
+
+		event.AccountName = "payments"
+		event.Tags = map[string]string{"workload": "payments"}
+
+		response, err := lambda.Handler(ctx, event)
+		testtools.ExitTest(stubber, t)
+		assert.NoError(t, err)
+		assert.Equal(t, "payments", response.Workload)
+		assert.Equal(t, "production", response.Environment)
+	})
+
+	t.Run("Fall back to the account name for a missing tag", func(t *testing.T) {
+		stubber := testtools.NewStubber()
+		lambda := New(*stubber.SdkConfig)
+
+		event.AccountName = "prefix-my-workload-development"
+		event.Tags = map[string]string{"environment": "acceptance"}
+
+		response, err := lambda.Handler(ctx, event)
+		testtools.ExitTest(stubber, t)
+		assert.NoError(t, err)
+		assert.Equal(t, "my-workload", response.Workload)
+		assert.Equal(t, "acceptance", response.Environment)
+	})
+
+	t.Run("Configurable tag keys", func(t *testing.T) {
+		_ = os.Setenv("WORKLOAD_TAG_KEY", "Application")
+		_ = os.Setenv("ENVIRONMENT_TAG_KEY", "Stage")
+		stubber := testtools.NewStubber()
+		lambda := New(*stubber.SdkConfig)
+
+		event.AccountName = "prefix-my-workload-development"
+		event.Tags = map[string]string{"workload": "payments", "Application": "billing", "Stage": "production"}
+
+		response, err := lambda.Handler(ctx, event)
+		testtools.ExitTest(stubber, t)
+		assert.NoError(t, err)
+		assert.Equal(t, "billing", response.Workload)
+		assert.Equal(t, "production", response.Environment)
+
+		_ = os.Setenv("WORKLOAD_TAG_KEY", "")
+		_ = os.Setenv("ENVIRONMENT_TAG_KEY", "")
+	})
+}
+
+func TestNamingRules(t *testing.T) {
+	ctx := context.Background()
+	event := readEvent("../../events/workload-context.json")
+	_ = os.Setenv("NAMING_RULES", `[
+		"^acme-(?P<team>[a-z]+)-(?P<workload>[a-z-]+)-(?P<environment>dev|tst|prd)$",
+		"^(?P<workload>[a-z]+)\\.(?P<environment>[a-z]+)$",
+		"^legacy-(?P<workload>[a-z-]+)$",
+		"^(?P<workload>[a-z]*)_(?P<environment>[a-z]+)$"
+	]`)
+	defer func() { _ = os.Setenv("NAMING_RULES", "") }()
+
+	tests := []struct {
+		name        string
+		workload    string
+		environment string
+		team        string
+	}{
+		{"acme-platform-my-workload-prd", "my-workload", "prd", "platform"},
+		{"payments.test", "payments", "test", ""},
+		{"legacy-billing", "billing", "production", ""},
+		{"acme-platform-my-workload-production", "unclassified", "unclassified", ""},
+		{"something-else", "unclassified", "unclassified", ""},
+		{"billing_test", "billing", "test", ""},
+		{"_test", "unclassified", "unclassified", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stubber := testtools.NewStubber()
+			lambda := New(*stubber.SdkConfig)
+
+			event.AccountName = test.name
+			response, err := lambda.Handler(ctx, event)
+			testtools.ExitTest(stubber, t)
+			assert.NoError(t, err)
+			assert.Equal(t, test.workload, response.Workload)
+			assert.Equal(t, test.environment, response.Environment)
+			assert.Equal(t, test.team, response.Owner.Team)
+		})
+	}
+
+	t.Run("Tags take precedence over the rules", func(t *testing.T) {
+		stubber := testtools.NewStubber()
+		lambda := New(*stubber.SdkConfig)
+
+		event.AccountName = "something-else"
+		event.Tags = map[string]string{"workload": "payments"}
+
+		response, err := lambda.Handler(ctx, event)
+		testtools.ExitTest(stubber, t)
+		assert.NoError(t, err)
+		assert.Equal(t, "payments", response.Workload)
+		assert.Equal(t, "production", response.Environment)
+		event.Tags = nil
+	})
+
+	t.Run("Invalid rules", func(t *testing.T) {
+		stubber := testtools.NewStubber()
+		lambda := New(*stubber.SdkConfig)
+
+		_ = os.Setenv("NAMING_RULES", `["^(?P<environment>[a-z]+)$"]`)
+		_, err := loadNamingRules()
+		assert.EqualError(t, err, "naming rule `^(?P<environment>[a-z]+)$` has no `workload` group")
+		_, err = lambda.Handler(ctx, event)
+		assert.ErrorContains(t, err, "has no `workload` group")
+
+		_ = os.Setenv("NAMING_RULES", `["^(?P<workload>[a-z+$"]`)
+		_, err = lambda.Handler(ctx, event)
+		assert.ErrorContains(t, err, "is invalid")
+
+		_ = os.Setenv("NAMING_RULES", `^(?P<workload>[a-z]+)$`)
+		_, err = lambda.Handler(ctx, event)
+		assert.ErrorContains(t, err, "is not a JSON list")
+	})
+}
+
+func TestOrganizationalUnits(t *testing.T) {
+	ctx := context.Background()
+	event := readEvent("../../events/workload-context.json")
+	event.AccountName = "prefix-payments"
+	_ = os.Setenv("OU_ENVIRONMENTS", `{"Workloads/Prod": "production", "Workloads/NonProd": "development", "Workloads/NonProd/Test": "test"}`)
+	defer func() { _ = os.Setenv("OU_ENVIRONMENTS", "") }()
+
+	t.Run("Map the OU path to an environment", func(t *testing.T) {
+		stubber := testtools.NewStubber()
+		lambda := New(*stubber.SdkConfig)
+
+		event.OrganizationalUnit = "Workloads/NonProd/Test"
+		response, err := lambda.Handler(ctx, event)
+		testtools.ExitTest(stubber, t)
+		assert.NoError(t, err)
+		assert.Equal(t, "payments", response.Workload)
+		assert.Equal(t, "test", response.Environment)
+		assert.Equal(t, "Workloads/NonProd/Test", response.OrganizationalUnit)
+		assert.Equal(t, []dimension.Dimension{{Name: "OrganizationalUnit", Value: "Workloads/NonProd/Test"}}, response.Dimensions)
+	})
+
+	t.Run("Nested OUs inherit the environment", func(t *testing.T) {
+		stubber := testtools.NewStubber()
+		lambda := New(*stubber.SdkConfig)
+
+		event.OrganizationalUnit = "Workloads/Prod/Payments"
+		response, err := lambda.Handler(ctx, event)
+		testtools.ExitTest(stubber, t)
+		assert.NoError(t, err)
+		assert.Equal(t, "production", response.Environment)
+		assert.Equal(t, "Workloads/Prod/Payments", response.OrganizationalUnit)
+	})
+
+	t.Run("Unmapped OUs fall back to the account name", func(t *testing.T) {
+		stubber := testtools.NewStubber()
+		lambda := New(*stubber.SdkConfig)
+
+		event.AccountName = "prefix-my-workload-acceptance"
+		event.OrganizationalUnit = "Root"
+		response, err := lambda.Handler(ctx, event)
+		testtools.ExitTest(stubber, t)
+		assert.NoError(t, err)
+		assert.Equal(t, "acceptance", response.Environment)
+		assert.Equal(t, "Root", response.OrganizationalUnit)
+	})
+
+	t.Run("Accounts without a known OU", func(t *testing.T) {
+		stubber := testtools.NewStubber()
+		lambda := New(*stubber.SdkConfig)
+
+		event.OrganizationalUnit = ""
+		response, err := lambda.Handler(ctx, event)
+		testtools.ExitTest(stubber, t)
+		assert.NoError(t, err)
+		assert.Equal(t, "None", response.OrganizationalUnit)
+	})
+}
+
+func TestClassificationFile(t *testing.T) {
+	ctx := context.Background()
+	event := readEvent("../../events/workload-context.json")
+	event.AccountName = "prefix-my-workload-development"
+
+	t.Run("The override is applied before the heuristics", func(t *testing.T) {
+		stubber := testtools.NewStubber()
+		lambda := New(*stubber.SdkConfig)
+
+		request := event
+		request.Tags = map[string]string{"workload": "billing", "environment": "test"}
+		request.Override = &classification.Override{Workload: "payments", Environment: "production", Owner: "team-payments"}
+		response, err := lambda.Handler(ctx, request)
+		testtools.ExitTest(stubber, t)
+		assert.NoError(t, err)
+		assert.Equal(t, "payments", response.Workload)
+		assert.Equal(t, "production", response.Environment)
+		assert.Equal(t, "team-payments", response.Owner.Team)
+		assert.False(t, response.Excluded)
+	})
+
+	t.Run("Partial overrides", func(t *testing.T) {
+		stubber := testtools.NewStubber()
+		lambda := New(*stubber.SdkConfig)
+
+		request := event
+		request.Override = &classification.Override{Environment: "sandbox", Exclude: true}
+		response, err := lambda.Handler(ctx, request)
+		testtools.ExitTest(stubber, t)
+		assert.NoError(t, err)
+		assert.Equal(t, "my-workload", response.Workload)
+		assert.Equal(t, "sandbox", response.Environment)
+		assert.True(t, response.Excluded)
+	})
+
+	t.Run("Accounts without an override", func(t *testing.T) {
+		stubber := testtools.NewStubber()
+		lambda := New(*stubber.SdkConfig)
+
+		response, err := lambda.Handler(ctx, event)
+		testtools.ExitTest(stubber, t)
+		assert.NoError(t, err)
+		assert.Equal(t, "my-workload", response.Workload)
+		assert.Equal(t, "development", response.Environment)
+		assert.False(t, response.Excluded)
+	})
+}
+
+func TestOwner(t *testing.T) {
+	ctx := context.Background()
+	event := readEvent("../../events/workload-context.json")
+	event.AccountName = "prefix-my-workload-development"
+
+	t.Run("Owner from the account tags", func(t *testing.T) {
+		stubber := testtools.NewStubber()
+		lambda := New(*stubber.SdkConfig)
+
+		event.Tags = map[string]string{"owner": "payments", "contact": "payments@example.com", "cost-center": "CC-1234"}
+		response, err := lambda.Handler(ctx, event)
+		testtools.ExitTest(stubber, t)
+		assert.NoError(t, err)
+		assert.Equal(t, owner.Owner{Team: "payments", Email: "payments@example.com", CostCenter: "CC-1234"}, response.Owner)
+	})
+
+	t.Run("The classification file takes precedence over the tags", func(t *testing.T) {
+		stubber := testtools.NewStubber()
+		lambda := New(*stubber.SdkConfig)
+
+		request := event
+		request.Tags = map[string]string{"owner": "payments", "contact": "payments@example.com", "cost-center": "CC-1234"}
+		request.Override = &classification.Override{Owner: "billing", ContactEmail: "billing@example.com"}
+		response, err := lambda.Handler(ctx, request)
+		testtools.ExitTest(stubber, t)
+		assert.NoError(t, err)
+		assert.Equal(t, owner.Owner{Team: "billing", Email: "billing@example.com", CostCenter: "CC-1234"}, response.Owner)
+	})
+}
@@ -1,21 +1,51 @@
 package main
 
+import (
+	"shared/artifact"
+	"shared/classification"
+	"shared/dimension"
+	"shared/owner"
+	"shared/report"
+	"shared/score"
+)
+
 type Request struct {
-	AccountId   string `json:"AccountId"`
-	AccountName string `json:"AccountName"`
-	Bucket      string `json:"Bucket"`
-	Key         string `json:"Key"`
-	GroupBy     string `json:"GroupBy"`
-	Controls    string `json:"Controls"`
+	AccountId          string                   `json:"AccountId"`
+	AccountName        string                   `json:"AccountName"`
+	Bucket             string                   `json:"Bucket"`
+	Key                string                   `json:"Key"`
+	GroupBy            string                   `json:"GroupBy"`
+	Controls           string                   `json:"Controls"`
+	Checksum           *artifact.Checksum       `json:"Checksum"`
+	Dimensions         []dimension.Dimension    `json:"Dimensions"`
+	Tags               map[string]string        `json:"Tags,omitempty"`
+	OrganizationalUnit string                   `json:"OrganizationalUnit,omitempty"`
+	Override           *classification.Override `json:"Override,omitempty"`
+	Baseline           *score.Baseline          `json:"Baseline,omitempty"`
+	Options            report.Options           `json:"Options"`
 }
 
 type Response struct {
-	AccountId   string `json:"AccountId"`
-	AccountName string `json:"AccountName"`
-	Workload    string `json:"Workload"`
-	Environment string `json:"Environment"`
-	Bucket      string `json:"Bucket"`
-	Key         string `json:"Key"`
-	GroupBy     string `json:"GroupBy"`
-	Controls    string `json:"Controls"`
+	AccountId          string                `json:"AccountId"`
+	AccountName        string                `json:"AccountName"`
+	Workload           string                `json:"Workload"`
+	Environment        string                `json:"Environment"`
+	OrganizationalUnit string                `json:"OrganizationalUnit,omitempty"`
+	Owner              owner.Owner           `json:"Owner"`
+	Excluded           bool                  `json:"Excluded,omitempty"`
+	Baseline           *score.Baseline       `json:"Baseline,omitempty"`
+	Options            report.Options        `json:"Options"`
+	Bucket             string                `json:"Bucket"`
+	Key                string                `json:"Key"`
+	GroupBy            string                `json:"GroupBy"`
+	Controls           string                `json:"Controls"`
+	Checksum           *artifact.Checksum    `json:"Checksum"`
+	Dimensions         []dimension.Dimension `json:"Dimensions"`
+}
+
+// Classification is the workload, environment and, when known, the team of an account.
+type Classification struct {
+	Workload    string
+	Environment string
+	Team        string
 }
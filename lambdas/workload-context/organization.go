@@ -0,0 +1,44 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// loadOrganizationalUnitEnvironments reads OU_ENVIRONMENTS, a JSON object that maps an OU path to an environment.
+// The OU path is only published when the table is configured.
+func loadOrganizationalUnitEnvironments() (map[string]string, error) {
+	value := os.Getenv("OU_ENVIRONMENTS")
+
+	if value == "" {
+		return nil, nil
+	}
+
+	var environments map[string]string
+	if err := json.Unmarshal([]byte(value), &environments); err != nil {
+		return nil, fmt.Errorf("OU_ENVIRONMENTS is not a JSON object of OU paths and environments: %w", err)
+	}
+
+	return environments, nil
+}
+
+// matchOrganizationalUnit returns the environment of the longest configured path that contains the OU path.
+func matchOrganizationalUnit(environments map[string]string, path string) string {
+	match := ""
+	environment := ""
+
+	for prefix, value := range environments {
+		if path != prefix && !strings.HasPrefix(path, prefix+"/") {
+			continue
+		}
+
+		if len(prefix) > len(match) {
+			match = prefix
+			environment = value
+		}
+	}
+
+	return environment
+}
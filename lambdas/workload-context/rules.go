@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+)
+
+// loadNamingRules reads NAMING_RULES, an ordered JSON list of regular expressions with the named groups `workload`,
+// `environment` and optionally `team`.
+func loadNamingRules() ([]*regexp.Regexp, error) {
+	value := os.Getenv("NAMING_RULES")
+
+	if value == "" {
+		return nil, nil
+	}
+
+	var expressions []string
+	if err := json.Unmarshal([]byte(value), &expressions); err != nil {
+		return nil, fmt.Errorf("NAMING_RULES is not a JSON list of expressions: %w", err)
+	}
+
+	var rules []*regexp.Regexp
+	for _, expression := range expressions {
+		rule, err := regexp.Compile(expression)
+
+		if err != nil {
+			return nil, fmt.Errorf("naming rule `%s` is invalid: %w", expression, err)
+		}
+
+		if rule.SubexpIndex("workload") < 0 {
+			return nil, fmt.Errorf("naming rule `%s` has no `workload` group", expression)
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+// matchNamingRules classifies the name with the first rule that matches, a rule without an environment means production.
+// A rule of which the `workload` group matches an empty string does not match.
+func matchNamingRules(rules []*regexp.Regexp, name string) (Classification, error) {
+	for _, rule := range rules {
+		match := rule.FindStringSubmatch(name)
+
+		if match == nil || group(rule, match, "workload") == "" {
+			continue
+		}
+
+		classification := Classification{
+			Workload:    group(rule, match, "workload"),
+			Environment: group(rule, match, "environment"),
+			Team:        group(rule, match, "team"),
+		}
+
+		if classification.Environment == "" {
+			classification.Environment = defaultEnvironment
+		}
+
+		return classification, nil
+	}
+
+	return Classification{Environment: defaultEnvironment}, fmt.Errorf("no naming rule matches `%s`", name)
+}
+
+func group(rule *regexp.Regexp, match []string, name string) string {
+	if index := rule.SubexpIndex(name); index >= 0 {
+		return match[index]
+	}
+
+	return ""
+}
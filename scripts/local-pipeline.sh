@@ -0,0 +1,97 @@
+#!/usr/bin/env bash
+# Runs the pipeline against the local storage backend, using the fixtures in the events folder.
+#
+# Every step that only needs the bucket is invoked with LOCAL_EVENT, in the order of the state machine. The steps that
+# require AWS APIs are replaced by their fixtures: collect-findings by events/stripped-findings.json, the controls by
+# events/controls.json and fetch-account-mapping by the account names in the findings. publish-metrics writes its
+# metrics in the embedded metric format to the storage folder, and build-dashboard writes the dashboard body to the
+# bucket. import-regressions imports findings into Security Hub and is not run.
+#
+# OPTIONS are the options of the report, notify-changes only runs when they have notification rules, and then sends to
+# the webhooks in NOTIFICATION_WEBHOOKS.
+set -euo pipefail
+
+ROOT="$(cd "$(dirname "$0")/.." && pwd)"
+BUCKET="${BUCKET:-my-sample-bucket}"
+REPORT="${REPORT:-local-report}"
+DEFAULT_OPTIONS='{"ControlMetrics": true, "Documents": {}, "Export": {}, "Dashboard": {}}'
+OPTIONS="${OPTIONS:-$DEFAULT_OPTIONS}"
+TIMESTAMP="$(date +%s)"
+
+export STORAGE_BACKEND=local
+export STORAGE_PATH="${STORAGE_PATH:-$ROOT/.storage}"
+export LOCAL_EVENT=-
+export AWS_REGION="${AWS_REGION:-eu-west-1}"
+export METRICS_MODE=EMF
+export DASHBOARD_MODE=S3
+
+invoke() {
+	(cd "$ROOT/lambdas/$1" && go run .)
+}
+
+# has reports whether the option is present, like the choice states of the state machine.
+has() {
+	echo "$STATE" | jq -e --arg option "$1" '.Options | has($option)' > /dev/null
+}
+
+echo "[+] Seeding s3://$BUCKET/$REPORT in $STORAGE_PATH" >&2
+DATE_PATH="$(date -u +%Y/%m/%d)"
+FINDINGS="$REPORT/raw/$DATE_PATH/fixture.json"
+CONTROLS="$REPORT/controls/$DATE_PATH/fixture.json"
+mkdir -p "$STORAGE_PATH/$BUCKET/$REPORT/raw/$DATE_PATH" "$STORAGE_PATH/$BUCKET/$REPORT/controls/$DATE_PATH"
+cp "$ROOT/events/stripped-findings.json" "$STORAGE_PATH/$BUCKET/$FINDINGS"
+cp "$ROOT/events/controls.json" "$STORAGE_PATH/$BUCKET/$CONTROLS"
+
+echo "[+] Aggregate findings" >&2
+SHA256="$(sha256sum "$STORAGE_PATH/$BUCKET/$FINDINGS" | cut -d ' ' -f 1)"
+RECORDS="$(jq length "$STORAGE_PATH/$BUCKET/$FINDINGS")"
+STATE="$(jq -n \
+	--arg bucket "$BUCKET" --arg report "$REPORT" --arg controls "$CONTROLS" --arg findings "$FINDINGS" \
+	--arg sha256 "$SHA256" --argjson records "$RECORDS" --argjson options "$OPTIONS" \
+	'{Bucket: $bucket, Report: $report, Controls: $controls, GroupBy: "GeneratorId", Findings: [$findings], FindingCount: 1,
+	  Checksums: {($findings): {SHA256: $sha256, Records: $records}}, Options: $options}' \
+	| invoke aggregate-findings)"
+
+echo "[+] Split per account" >&2
+STATE="$(echo "$STATE" | jq --argjson timestamp "$TIMESTAMP" '.Timestamp = $timestamp' | invoke split-per-account)"
+
+# fetch-account-mapping needs Organizations, the accounts keep the names of the findings and get the options of the
+# report passed along, as fetch-account-mapping does.
+STATE="$(echo "$STATE" | jq '.Exclusions = "" | .Options as $options | .Accounts |= map(.Options = $options)')"
+
+SCORES="[]"
+for ACCOUNT in $(echo "$STATE" | jq -c '.Accounts[]'); do
+	echo "[+] Calculate score for $(echo "$ACCOUNT" | jq -r '.AccountId')" >&2
+	SCORE="$(echo "$ACCOUNT" | invoke workload-context | invoke calculate-score)"
+	SCORES="$(echo "$SCORES" | jq --argjson score "$SCORE" '. + [$score]')"
+done
+STATE="$(echo "$STATE" | jq --argjson scores "$SCORES" '.Accounts = $scores')"
+
+if has Notifications; then
+	echo "[+] Notify changes" >&2
+	echo "$STATE" | invoke notify-changes >&2
+fi
+
+METRICS="$STORAGE_PATH/$BUCKET/$REPORT/metrics/$DATE_PATH/$TIMESTAMP.emf"
+mkdir -p "$(dirname "$METRICS")"
+echo "[+] Publish metrics to $METRICS" >&2
+echo "$STATE" | invoke publish-metrics > "$METRICS"
+
+if has Documents; then
+	echo "[+] Render documents" >&2
+	echo "$STATE" | invoke render-documents
+fi
+
+if has Export; then
+	echo "[+] Export controls" >&2
+	echo "$STATE" | invoke export-controls
+fi
+
+if has Dashboard; then
+	echo "[+] Build dashboard" >&2
+	echo "$STATE" | invoke build-dashboard
+fi
+
+if has Regressions; then
+	echo "[-] Skipping import-regressions, it needs Security Hub" >&2
+fi
@@ -0,0 +1,146 @@
+package artifact
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"strconv"
+)
+
+// Checksum is recorded for every intermediate artifact, so the next step can detect truncated or overwritten objects.
+type Checksum struct {
+	SHA256  string `json:"SHA256"`
+	Records int    `json:"Records"`
+}
+
+// Checksums maps an object key to the checksum of the artifact stored under it.
+type Checksums map[string]Checksum
+
+type IntegrityError struct {
+	Key      string
+	Field    string
+	Expected string
+	Actual   string
+}
+
+func (x *IntegrityError) Error() string {
+	return fmt.Sprintf("integrity check of %s failed: %s is %s, expected %s", x.Key, x.Field, x.Actual, x.Expected)
+}
+
+func NewChecksum(data []byte, records int) Checksum {
+	return Checksum{SHA256: Sum(data), Records: records}
+}
+
+// Sum returns the hex encoded SHA-256 of data.
+func Sum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Get returns the checksum recorded for key, or nil when there is none.
+func (x Checksums) Get(key string) *Checksum {
+	checksum, ok := x[key]
+
+	if !ok {
+		return nil
+	}
+
+	return &checksum
+}
+
+// Require returns the checksum recorded for key, an artifact without a recorded checksum is not trusted.
+func (x Checksums) Require(key string) (*Checksum, error) {
+	checksum := x.Get(key)
+
+	if checksum == nil {
+		return nil, missing(key)
+	}
+
+	return checksum, nil
+}
+
+// Merge returns a copy of the checksums, with the checksums of other added to it.
+func (x Checksums) Merge(other Checksums) Checksums {
+	merged := Checksums{}
+
+	for key, checksum := range x {
+		merged[key] = checksum
+	}
+
+	for key, checksum := range other {
+		merged[key] = checksum
+	}
+
+	return merged
+}
+
+// VerifySum compares sum with the recorded SHA-256, an artifact without a recorded checksum fails the check.
+func (x *Checksum) VerifySum(key string, sum string) error {
+	if x == nil {
+		return missing(key)
+	}
+
+	if x.SHA256 == sum {
+		return nil
+	}
+
+	return &IntegrityError{Key: key, Field: "SHA256", Expected: x.SHA256, Actual: sum}
+}
+
+// VerifyRecords compares the number of decoded records with the recorded number of records.
+func (x *Checksum) VerifyRecords(key string, records int) error {
+	if x == nil {
+		return missing(key)
+	}
+
+	if x.Records == records {
+		return nil
+	}
+
+	return &IntegrityError{Key: key, Field: "Records", Expected: strconv.Itoa(x.Records), Actual: strconv.Itoa(records)}
+}
+
+// missing is the error of an artifact of which the checksum was lost, or never recorded.
+func missing(key string) error {
+	return &IntegrityError{Key: key, Field: "Checksum", Expected: "a recorded checksum", Actual: "missing"}
+}
+
+// Reader computes the SHA-256 of everything that is read through it.
+type Reader struct {
+	reader io.Reader
+	hash   hash.Hash
+}
+
+func NewReader(reader io.Reader) *Reader {
+	h := sha256.New()
+	return &Reader{reader: io.TeeReader(reader, h), hash: h}
+}
+
+func (x *Reader) Read(p []byte) (int, error) {
+	return x.reader.Read(p)
+}
+
+func (x *Reader) Sum() string {
+	return hex.EncodeToString(x.hash.Sum(nil))
+}
+
+// Writer computes the SHA-256 of everything that is written through it.
+type Writer struct {
+	writer io.Writer
+	hash   hash.Hash
+}
+
+func NewWriter(writer io.Writer) *Writer {
+	h := sha256.New()
+	return &Writer{writer: io.MultiWriter(writer, h), hash: h}
+}
+
+func (x *Writer) Write(p []byte) (int, error) {
+	return x.writer.Write(p)
+}
+
+func (x *Writer) Sum() string {
+	return hex.EncodeToString(x.hash.Sum(nil))
+}
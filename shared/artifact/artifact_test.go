@@ -0,0 +1,78 @@
+package artifact
+
+import (
+	"bytes"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"testing"
+)
+
+func TestChecksum(t *testing.T) {
+	data := []byte(`[{"Id": "finding-1"}]`)
+	checksums := Checksums{"my/key.json": NewChecksum(data, 1)}
+
+	t.Run("Matching artifact", func(t *testing.T) {
+		checksum := checksums.Get("my/key.json")
+		assert.NoError(t, checksum.VerifySum("my/key.json", Sum(data)))
+		assert.NoError(t, checksum.VerifyRecords("my/key.json", 1))
+	})
+
+	t.Run("Truncated artifact", func(t *testing.T) {
+		err := checksums.Get("my/key.json").VerifySum("my/key.json", Sum(data[:10]))
+
+		var integrityErr *IntegrityError
+		assert.True(t, errors.As(err, &integrityErr))
+		assert.Equal(t, "my/key.json", integrityErr.Key)
+		assert.Equal(t, "SHA256", integrityErr.Field)
+	})
+
+	t.Run("Record count mismatch", func(t *testing.T) {
+		err := checksums.Get("my/key.json").VerifyRecords("my/key.json", 2)
+		assert.EqualError(t, err, "integrity check of my/key.json failed: Records is 2, expected 1")
+	})
+
+	t.Run("No recorded checksum", func(t *testing.T) {
+		checksum, err := checksums.Require("other/key.json")
+		assert.Nil(t, checksum)
+		assert.EqualError(t, err, "integrity check of other/key.json failed: Checksum is missing, expected a recorded checksum")
+
+		var integrityErr *IntegrityError
+		assert.True(t, errors.As(checksum.VerifySum("other/key.json", "invalid"), &integrityErr))
+		assert.True(t, errors.As(checksum.VerifyRecords("other/key.json", 10), &integrityErr))
+		assert.Equal(t, "Checksum", integrityErr.Field)
+	})
+
+	t.Run("No checksums at all", func(t *testing.T) {
+		_, err := Checksums(nil).Require("my/key.json")
+		assert.Error(t, err)
+	})
+
+	t.Run("Merge", func(t *testing.T) {
+		merged := checksums.Merge(Checksums{"other/key.json": NewChecksum([]byte("[]"), 0)})
+		assert.Equal(t, 2, len(merged))
+		assert.Equal(t, 1, len(checksums))
+	})
+}
+
+func TestReader(t *testing.T) {
+	data := []byte(`[{"Id": "finding-1"}]`)
+	reader := NewReader(bytes.NewReader(data))
+
+	read, err := io.ReadAll(reader)
+	assert.NoError(t, err)
+	assert.Equal(t, data, read)
+	assert.Equal(t, Sum(data), reader.Sum())
+}
+
+func TestWriter(t *testing.T) {
+	data := []byte(`[{"Id": "finding-1"}]`)
+
+	var buffer bytes.Buffer
+	writer := NewWriter(&buffer)
+	_, err := writer.Write(data)
+
+	assert.NoError(t, err)
+	assert.Equal(t, data, buffer.Bytes())
+	assert.Equal(t, Sum(data), writer.Sum())
+}
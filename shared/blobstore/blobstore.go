@@ -0,0 +1,26 @@
+package blobstore
+
+import (
+	"context"
+	"io"
+)
+
+// BlobStore stores the intermediate artifacts that are passed between the steps of the pipeline.
+type BlobStore interface {
+	// Download reads the complete object into memory.
+	Download(ctx context.Context, bucket string, key string) ([]byte, error)
+	// Open streams the object, the caller has to close the reader.
+	Open(ctx context.Context, bucket string, key string) (io.ReadCloser, error)
+	// Upload writes the object in a single request.
+	Upload(ctx context.Context, bucket string, key string, data []byte) error
+	// NewWriter streams an object of unknown size, memory usage is bounded by the store and not by the object.
+	NewWriter(ctx context.Context, bucket string, key string) Writer
+}
+
+// Writer only publishes the object on Close, Abort discards everything that has been written.
+type Writer interface {
+	io.WriteCloser
+	Abort() error
+	// Size returns the number of bytes written so far.
+	Size() int64
+}
@@ -0,0 +1,34 @@
+// Package blobtest provides a local blob store for the tests of the functions.
+package blobtest
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"shared/blobstore"
+	"testing"
+)
+
+// Bucket is the bucket of the event fixtures.
+const Bucket = "my-sample-bucket"
+
+// Store is a local blob store in a temporary directory that is removed when the test ends.
+type Store struct {
+	*blobstore.Local
+	t *testing.T
+}
+
+func New(t *testing.T) *Store {
+	return &Store{Local: blobstore.NewLocal(t.TempDir()), t: t}
+}
+
+// Seed writes the object to the bucket of the event fixtures.
+func (x *Store) Seed(key string, data string) {
+	require.NoError(x.t, x.Upload(context.Background(), Bucket, key, []byte(data)))
+}
+
+// Read returns the object from the bucket of the event fixtures.
+func (x *Store) Read(key string) []byte {
+	data, err := x.Download(context.Background(), Bucket, key)
+	require.NoError(x.t, err)
+	return data
+}
@@ -0,0 +1,35 @@
+package blobstore
+
+import (
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"os"
+)
+
+const (
+	BackendS3    = "s3"
+	BackendLocal = "local"
+
+	defaultLocalPath = ".storage"
+)
+
+// NewFromConfig selects the storage backend with the STORAGE_BACKEND environment variable, S3 is the default.
+// The local backend stores the buckets below STORAGE_PATH.
+func NewFromConfig(cfg aws.Config) BlobStore {
+	switch os.Getenv("STORAGE_BACKEND") {
+	case BackendLocal:
+		return NewLocal(resolveLocalPath())
+	}
+
+	return NewS3(s3.NewFromConfig(cfg))
+}
+
+func resolveLocalPath() string {
+	path := os.Getenv("STORAGE_PATH")
+
+	if path == "" {
+		return defaultLocalPath
+	}
+
+	return path
+}
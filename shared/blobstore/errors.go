@@ -0,0 +1,14 @@
+package blobstore
+
+import (
+	"errors"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"io/fs"
+)
+
+// IsNotFound reports whether the error is returned for an object that does not exist, in any of the backends.
+// S3 only returns NoSuchKey when the caller is allowed to list the bucket, otherwise the error is AccessDenied.
+func IsNotFound(err error) bool {
+	var noSuchKey *types.NoSuchKey
+	return errors.As(err, &noSuchKey) || errors.Is(err, fs.ErrNotExist)
+}
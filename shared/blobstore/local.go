@@ -0,0 +1,146 @@
+package blobstore
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Local stores every bucket as a directory below Root, using the same key layout as S3.
+type Local struct {
+	Root string
+}
+
+func NewLocal(root string) *Local {
+	return &Local{Root: root}
+}
+
+func (x *Local) Download(ctx context.Context, bucket string, key string) ([]byte, error) {
+	path, err := x.resolvePath(bucket, key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Downloading %s", path)
+	return os.ReadFile(path)
+}
+
+func (x *Local) Open(ctx context.Context, bucket string, key string) (io.ReadCloser, error) {
+	path, err := x.resolvePath(bucket, key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return os.Open(path)
+}
+
+func (x *Local) Upload(ctx context.Context, bucket string, key string, data []byte) error {
+	path, err := x.resolvePath(bucket, key)
+
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Upload file to %s", path)
+	err = os.MkdirAll(filepath.Dir(path), 0o755)
+
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0o644)
+}
+
+func (x *Local) NewWriter(ctx context.Context, bucket string, key string) Writer {
+	path, err := x.resolvePath(bucket, key)
+
+	if err != nil {
+		return &localWriter{err: err}
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0o755)
+
+	if err != nil {
+		return &localWriter{err: err}
+	}
+
+	// Write to a temporary file next to the target, so a partial object is never visible under the key.
+	file, err := os.CreateTemp(filepath.Dir(path), fmt.Sprintf(".%s-*", filepath.Base(path)))
+
+	return &localWriter{path: path, file: file, err: err}
+}
+
+func (x *Local) resolvePath(bucket string, key string) (string, error) {
+	root, err := filepath.Abs(filepath.Join(x.Root, bucket))
+
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(root, filepath.FromSlash(key))
+
+	if !strings.HasPrefix(path, root+string(filepath.Separator)) {
+		return "", fmt.Errorf("key %s resolves outside of bucket %s", key, bucket)
+	}
+
+	return path, nil
+}
+
+type localWriter struct {
+	path    string
+	file    *os.File
+	err     error
+	written int64
+}
+
+func (x *localWriter) Write(data []byte) (int, error) {
+	if x.err != nil {
+		return 0, x.err
+	}
+
+	n, err := x.file.Write(data)
+	x.written += int64(n)
+
+	return n, err
+}
+
+// Close moves the temporary file to its final location.
+func (x *localWriter) Close() error {
+	if x.err != nil {
+		return x.err
+	}
+
+	err := x.file.Close()
+
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Upload file to %s", x.path)
+	return os.Rename(x.file.Name(), x.path)
+}
+
+func (x *localWriter) Abort() error {
+	if x.file == nil {
+		return nil
+	}
+
+	_ = x.file.Close()
+	err := os.Remove(x.file.Name())
+
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	return err
+}
+
+func (x *localWriter) Size() int64 {
+	return x.written
+}
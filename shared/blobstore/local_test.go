@@ -0,0 +1,106 @@
+package blobstore
+
+import (
+	"context"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLocal(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Upload mirrors the S3 key layout", func(t *testing.T) {
+		root := t.TempDir()
+		store := NewLocal(root)
+
+		err := store.Upload(ctx, "my-sample-bucket", "my-report/raw/2023/08/13/batch.json", []byte("[]"))
+		assert.NoError(t, err)
+
+		data, err := os.ReadFile(filepath.Join(root, "my-sample-bucket", "my-report", "raw", "2023", "08", "13", "batch.json"))
+		assert.NoError(t, err)
+		assert.Equal(t, "[]", string(data))
+	})
+
+	t.Run("Download and Open", func(t *testing.T) {
+		store := NewLocal(t.TempDir())
+		_ = store.Upload(ctx, "my-sample-bucket", "my/key.json", []byte("[1,2]"))
+
+		data, err := store.Download(ctx, "my-sample-bucket", "my/key.json")
+		assert.NoError(t, err)
+		assert.Equal(t, "[1,2]", string(data))
+
+		body, err := store.Open(ctx, "my-sample-bucket", "my/key.json")
+		assert.NoError(t, err)
+		data, _ = io.ReadAll(body)
+		_ = body.Close()
+		assert.Equal(t, "[1,2]", string(data))
+	})
+
+	t.Run("Missing object", func(t *testing.T) {
+		store := NewLocal(t.TempDir())
+		_, err := store.Download(ctx, "my-sample-bucket", "my/key.json")
+		assert.True(t, os.IsNotExist(err))
+		assert.True(t, IsNotFound(err))
+	})
+
+	t.Run("Keys cannot escape the bucket", func(t *testing.T) {
+		store := NewLocal(t.TempDir())
+		err := store.Upload(ctx, "my-sample-bucket", "../other-bucket/key.json", []byte("[]"))
+		assert.Error(t, err)
+	})
+
+	t.Run("Writer only publishes on Close", func(t *testing.T) {
+		store := NewLocal(t.TempDir())
+		writer := store.NewWriter(ctx, "my-sample-bucket", "my/key.json")
+		_, err := writer.Write([]byte("[1,"))
+		assert.NoError(t, err)
+		_, err = writer.Write([]byte("2]"))
+		assert.NoError(t, err)
+
+		_, err = store.Download(ctx, "my-sample-bucket", "my/key.json")
+		assert.True(t, os.IsNotExist(err))
+
+		assert.NoError(t, writer.Close())
+		assert.Equal(t, int64(5), writer.Size())
+		data, err := store.Download(ctx, "my-sample-bucket", "my/key.json")
+		assert.NoError(t, err)
+		assert.Equal(t, "[1,2]", string(data))
+	})
+
+	t.Run("Writer abort leaves nothing behind", func(t *testing.T) {
+		root := t.TempDir()
+		store := NewLocal(root)
+		writer := store.NewWriter(ctx, "my-sample-bucket", "my/key.json")
+		_, _ = writer.Write([]byte("[1,"))
+		assert.NoError(t, writer.Abort())
+
+		entries, err := os.ReadDir(filepath.Join(root, "my-sample-bucket", "my"))
+		assert.NoError(t, err)
+		assert.Equal(t, 0, len(entries))
+	})
+}
+
+func TestNewFromConfig(t *testing.T) {
+	t.Run("S3 is the default backend", func(t *testing.T) {
+		_ = os.Setenv("STORAGE_BACKEND", "")
+		_, ok := NewFromConfig(aws.Config{}).(*S3)
+		assert.True(t, ok)
+	})
+
+	t.Run("Local backend", func(t *testing.T) {
+		_ = os.Setenv("STORAGE_BACKEND", "local")
+		_ = os.Setenv("STORAGE_PATH", "/tmp/storage")
+		store, ok := NewFromConfig(aws.Config{}).(*Local)
+		assert.True(t, ok)
+		assert.Equal(t, "/tmp/storage", store.Root)
+
+		_ = os.Setenv("STORAGE_PATH", "")
+		store, _ = NewFromConfig(aws.Config{}).(*Local)
+		assert.Equal(t, ".storage", store.Root)
+		_ = os.Setenv("STORAGE_BACKEND", "")
+	})
+}
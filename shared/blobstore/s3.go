@@ -0,0 +1,201 @@
+package blobstore
+
+import (
+	"bytes"
+	"context"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"io"
+	"log"
+)
+
+const (
+	Megabyte = 1024 * 1024
+	// MinimumPartSize is the smallest part S3 accepts, except for the last part of an upload.
+	MinimumPartSize = 5 * Megabyte
+	DefaultPartSize = 8 * Megabyte
+)
+
+type S3 struct {
+	client *s3.Client
+	// PartSize is the number of bytes a Writer buffers before it uploads a part.
+	PartSize int
+}
+
+func NewS3(client *s3.Client) *S3 {
+	return &S3{
+		client:   client,
+		PartSize: DefaultPartSize,
+	}
+}
+
+func (x *S3) Download(ctx context.Context, bucket string, key string) ([]byte, error) {
+	log.Printf("Downloading s3://%s/%s", bucket, key)
+
+	body, err := x.Open(ctx, bucket, key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer body.Close()
+	return io.ReadAll(body)
+}
+
+func (x *S3) Open(ctx context.Context, bucket string, key string) (io.ReadCloser, error) {
+	response, err := x.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return response.Body, nil
+}
+
+func (x *S3) Upload(ctx context.Context, bucket string, key string, data []byte) error {
+	log.Printf("Upload file to s3://%s/%s", bucket, key)
+
+	_, err := x.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+		Body:   bytes.NewReader(data),
+	})
+
+	return err
+}
+
+func (x *S3) NewWriter(ctx context.Context, bucket string, key string) Writer {
+	partSize := x.PartSize
+
+	if partSize < MinimumPartSize {
+		partSize = MinimumPartSize
+	}
+
+	return newMultipartUpload(ctx, x.client, bucket, key, partSize)
+}
+
+// multipartUpload buffers everything written to it and uploads the buffer as a part once it reaches the part size.
+// When all data fits in a single buffer, the multipart upload is never started and a plain PutObject is used instead.
+type multipartUpload struct {
+	ctx      context.Context
+	client   *s3.Client
+	bucket   string
+	key      string
+	partSize int
+	buffer   bytes.Buffer
+	uploadId *string
+	parts    []types.CompletedPart
+	written  int64
+}
+
+func newMultipartUpload(ctx context.Context, client *s3.Client, bucket string, key string, partSize int) *multipartUpload {
+	return &multipartUpload{
+		ctx:      ctx,
+		client:   client,
+		bucket:   bucket,
+		key:      key,
+		partSize: partSize,
+	}
+}
+
+func (x *multipartUpload) Write(data []byte) (int, error) {
+	n, _ := x.buffer.Write(data)
+	x.written += int64(n)
+
+	if x.buffer.Len() < x.partSize {
+		return n, nil
+	}
+
+	return n, x.flush()
+}
+
+// Close uploads the remaining buffer and completes the upload.
+func (x *multipartUpload) Close() error {
+	if x.uploadId == nil {
+		_, err := x.client.PutObject(x.ctx, &s3.PutObjectInput{
+			Bucket: aws.String(x.bucket),
+			Key:    aws.String(x.key),
+			Body:   bytes.NewReader(x.buffer.Bytes()),
+		})
+
+		return err
+	}
+
+	if x.buffer.Len() > 0 {
+		err := x.flush()
+
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := x.client.CompleteMultipartUpload(x.ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(x.bucket),
+		Key:             aws.String(x.key),
+		UploadId:        x.uploadId,
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: x.parts},
+	})
+
+	return err
+}
+
+// Abort discards the upload, so no incomplete parts are left behind in the bucket.
+func (x *multipartUpload) Abort() error {
+	x.buffer.Reset()
+
+	if x.uploadId == nil {
+		return nil
+	}
+
+	_, err := x.client.AbortMultipartUpload(x.ctx, &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(x.bucket),
+		Key:      aws.String(x.key),
+		UploadId: x.uploadId,
+	})
+
+	return err
+}
+
+func (x *multipartUpload) Size() int64 {
+	return x.written
+}
+
+func (x *multipartUpload) flush() error {
+	if x.uploadId == nil {
+		output, err := x.client.CreateMultipartUpload(x.ctx, &s3.CreateMultipartUploadInput{
+			Bucket: aws.String(x.bucket),
+			Key:    aws.String(x.key),
+		})
+
+		if err != nil {
+			return err
+		}
+
+		x.uploadId = output.UploadId
+	}
+
+	partNumber := aws.Int32(int32(len(x.parts) + 1))
+	output, err := x.client.UploadPart(x.ctx, &s3.UploadPartInput{
+		Bucket:     aws.String(x.bucket),
+		Key:        aws.String(x.key),
+		UploadId:   x.uploadId,
+		PartNumber: partNumber,
+		Body:       bytes.NewReader(x.buffer.Bytes()),
+	})
+
+	if err != nil {
+		return err
+	}
+
+	x.parts = append(x.parts, types.CompletedPart{
+		ETag:       output.ETag,
+		PartNumber: partNumber,
+	})
+	x.buffer.Reset()
+
+	return nil
+}
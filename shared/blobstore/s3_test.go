@@ -0,0 +1,189 @@
+package blobstore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/awsdocs/aws-doc-sdk-examples/gov2/testtools"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"testing"
+)
+
+func TestS3(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Download", func(t *testing.T) {
+		stubber := testtools.NewStubber()
+		store := NewS3(s3.NewFromConfig(*stubber.SdkConfig))
+		stubber.Add(testtools.Stub{
+			OperationName: "GetObject",
+			Input:         &s3.GetObjectInput{Bucket: aws.String("my-sample-bucket"), Key: aws.String("my/key.json")},
+			Output:        &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader([]byte("[]")))},
+		})
+
+		data, err := store.Download(ctx, "my-sample-bucket", "my/key.json")
+		testtools.ExitTest(stubber, t)
+		assert.NoError(t, err)
+		assert.Equal(t, "[]", string(data))
+	})
+
+	t.Run("Fail on download", func(t *testing.T) {
+		stubber := testtools.NewStubber()
+		store := NewS3(s3.NewFromConfig(*stubber.SdkConfig))
+		raiseErr := &testtools.StubError{Err: errors.New("failed")}
+		stubber.Add(testtools.Stub{
+			OperationName: "GetObject",
+			Input:         &s3.GetObjectInput{Bucket: aws.String("my-sample-bucket"), Key: aws.String("my/key.json")},
+			Error:         raiseErr,
+		})
+
+		_, err := store.Download(ctx, "my-sample-bucket", "my/key.json")
+		testtools.VerifyError(err, raiseErr, t)
+		testtools.ExitTest(stubber, t)
+		assert.False(t, IsNotFound(err))
+	})
+
+	t.Run("Missing object", func(t *testing.T) {
+		stubber := testtools.NewStubber()
+		store := NewS3(s3.NewFromConfig(*stubber.SdkConfig))
+		stubber.Add(testtools.Stub{
+			OperationName: "GetObject",
+			Input:         &s3.GetObjectInput{Bucket: aws.String("my-sample-bucket"), Key: aws.String("my/key.json")},
+			Error:         &testtools.StubError{Err: &types.NoSuchKey{}, ContinueAfter: true},
+		})
+
+		_, err := store.Download(ctx, "my-sample-bucket", "my/key.json")
+		testtools.ExitTest(stubber, t)
+		assert.True(t, IsNotFound(err))
+	})
+
+	t.Run("Upload", func(t *testing.T) {
+		stubber := testtools.NewStubber()
+		store := NewS3(s3.NewFromConfig(*stubber.SdkConfig))
+		stubber.Add(testtools.Stub{
+			OperationName: "PutObject",
+			Input:         &s3.PutObjectInput{Bucket: aws.String("my-sample-bucket"), Key: aws.String("my/key.json"), Body: bytes.NewReader([]byte("[]"))},
+			Output:        &s3.PutObjectOutput{},
+		})
+
+		err := store.Upload(ctx, "my-sample-bucket", "my/key.json", []byte("[]"))
+		testtools.ExitTest(stubber, t)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Writer never buffers less than the S3 minimum part size", func(t *testing.T) {
+		store := NewS3(nil)
+		store.PartSize = 1
+		writer := store.NewWriter(ctx, "my-sample-bucket", "my/key.json").(*multipartUpload)
+		assert.Equal(t, MinimumPartSize, writer.partSize)
+	})
+}
+
+func TestMultipartUpload(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Small payload uses a single PutObject", func(t *testing.T) {
+		stubber := testtools.NewStubber()
+		client := s3.NewFromConfig(*stubber.SdkConfig)
+		stubber.Add(testtools.Stub{
+			OperationName: "PutObject",
+			Input:         &s3.PutObjectInput{Bucket: aws.String("my-sample-bucket"), Key: aws.String("my/key.json"), Body: bytes.NewReader([]byte("[1,2]"))},
+			Output:        &s3.PutObjectOutput{},
+		})
+
+		upload := newMultipartUpload(ctx, client, "my-sample-bucket", "my/key.json", 10)
+		_, _ = upload.Write([]byte("[1,2]"))
+		err := upload.Close()
+
+		testtools.ExitTest(stubber, t)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, len(upload.parts))
+		assert.Equal(t, int64(5), upload.Size())
+	})
+
+	t.Run("Large payload is uploaded in parts", func(t *testing.T) {
+		stubber := testtools.NewStubber()
+		client := s3.NewFromConfig(*stubber.SdkConfig)
+		stubber.Add(testtools.Stub{
+			OperationName: "CreateMultipartUpload",
+			Input:         &s3.CreateMultipartUploadInput{Bucket: aws.String("my-sample-bucket"), Key: aws.String("my/key.json")},
+			Output:        &s3.CreateMultipartUploadOutput{UploadId: aws.String("upload-1")},
+		})
+		stubber.Add(testtools.Stub{
+			OperationName: "UploadPart",
+			Input:         &s3.UploadPartInput{Bucket: aws.String("my-sample-bucket"), Key: aws.String("my/key.json"), UploadId: aws.String("upload-1"), PartNumber: aws.Int32(1)},
+			Output:        &s3.UploadPartOutput{ETag: aws.String("etag-1")},
+			IgnoreFields:  []string{"Body"},
+		})
+		stubber.Add(testtools.Stub{
+			OperationName: "UploadPart",
+			Input:         &s3.UploadPartInput{Bucket: aws.String("my-sample-bucket"), Key: aws.String("my/key.json"), UploadId: aws.String("upload-1"), PartNumber: aws.Int32(2)},
+			Output:        &s3.UploadPartOutput{ETag: aws.String("etag-2")},
+			IgnoreFields:  []string{"Body"},
+		})
+		stubber.Add(testtools.Stub{
+			OperationName: "UploadPart",
+			Input:         &s3.UploadPartInput{Bucket: aws.String("my-sample-bucket"), Key: aws.String("my/key.json"), UploadId: aws.String("upload-1"), PartNumber: aws.Int32(3)},
+			Output:        &s3.UploadPartOutput{ETag: aws.String("etag-3")},
+			IgnoreFields:  []string{"Body"},
+		})
+		stubber.Add(testtools.Stub{
+			OperationName: "CompleteMultipartUpload",
+			Input: &s3.CompleteMultipartUploadInput{
+				Bucket:   aws.String("my-sample-bucket"),
+				Key:      aws.String("my/key.json"),
+				UploadId: aws.String("upload-1"),
+				MultipartUpload: &types.CompletedMultipartUpload{Parts: []types.CompletedPart{
+					{ETag: aws.String("etag-1"), PartNumber: aws.Int32(1)},
+					{ETag: aws.String("etag-2"), PartNumber: aws.Int32(2)},
+					{ETag: aws.String("etag-3"), PartNumber: aws.Int32(3)},
+				}},
+			},
+			Output: &s3.CompleteMultipartUploadOutput{},
+		})
+
+		upload := newMultipartUpload(ctx, client, "my-sample-bucket", "my/key.json", 10)
+		_, _ = upload.Write([]byte("0123456789"))
+		_, _ = upload.Write([]byte("0123456789"))
+		_, _ = upload.Write([]byte("01234"))
+		err := upload.Close()
+
+		testtools.ExitTest(stubber, t)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, len(upload.parts))
+		assert.Equal(t, int64(25), upload.Size())
+	})
+
+	t.Run("Failed part aborts the upload", func(t *testing.T) {
+		stubber := testtools.NewStubber()
+		client := s3.NewFromConfig(*stubber.SdkConfig)
+		raiseErr := &testtools.StubError{Err: errors.New("failed"), ContinueAfter: true}
+		stubber.Add(testtools.Stub{
+			OperationName: "CreateMultipartUpload",
+			Input:         &s3.CreateMultipartUploadInput{Bucket: aws.String("my-sample-bucket"), Key: aws.String("my/key.json")},
+			Output:        &s3.CreateMultipartUploadOutput{UploadId: aws.String("upload-1")},
+		})
+		stubber.Add(testtools.Stub{
+			OperationName: "UploadPart",
+			Input:         &s3.UploadPartInput{},
+			Error:         raiseErr,
+		})
+		stubber.Add(testtools.Stub{
+			OperationName: "AbortMultipartUpload",
+			Input:         &s3.AbortMultipartUploadInput{Bucket: aws.String("my-sample-bucket"), Key: aws.String("my/key.json"), UploadId: aws.String("upload-1")},
+			Output:        &s3.AbortMultipartUploadOutput{},
+		})
+
+		upload := newMultipartUpload(ctx, client, "my-sample-bucket", "my/key.json", 10)
+		_, err := upload.Write([]byte("0123456789"))
+		testtools.VerifyError(err, raiseErr, t)
+
+		err = upload.Abort()
+		testtools.ExitTest(stubber, t)
+		assert.NoError(t, err)
+	})
+}
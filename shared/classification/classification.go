@@ -0,0 +1,74 @@
+package classification
+
+import (
+	"context"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"shared/blobstore"
+)
+
+// Version is the newest version of the classification file this module understands.
+const Version = 1
+
+// File assigns accounts to a workload and environment by hand. The file is YAML or JSON, JSON is valid YAML.
+type File struct {
+	Version  int                 `yaml:"Version"`
+	Accounts map[string]Override `yaml:"Accounts"`
+}
+
+// Override is applied before the tags and naming heuristics, a field that is not set is still resolved by these. It is
+// passed along with every account, so it is read once per run.
+type Override struct {
+	Workload     string `yaml:"Workload" json:"Workload,omitempty"`
+	Environment  string `yaml:"Environment" json:"Environment,omitempty"`
+	Owner        string `yaml:"Owner" json:"Owner,omitempty"`
+	ContactEmail string `yaml:"ContactEmail" json:"ContactEmail,omitempty"`
+	CostCenter   string `yaml:"CostCenter" json:"CostCenter,omitempty"`
+	Exclude      bool   `yaml:"Exclude" json:"Exclude,omitempty"`
+}
+
+// Load reads the classification file from the bucket.
+func Load(ctx context.Context, store blobstore.BlobStore, bucket string, key string) (*File, error) {
+	data, err := store.Download(ctx, bucket, key)
+
+	if err != nil {
+		return nil, fmt.Errorf("could not read the classification file s3://%s/%s: %w", bucket, key, err)
+	}
+
+	file, err := Parse(data)
+
+	if err != nil {
+		return nil, fmt.Errorf("could not parse the classification file s3://%s/%s: %w", bucket, key, err)
+	}
+
+	return file, nil
+}
+
+func Parse(data []byte) (*File, error) {
+	var file File
+
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+
+	if file.Version < 1 || file.Version > Version {
+		return nil, fmt.Errorf("version %d is not supported, expected 1 to %d", file.Version, Version)
+	}
+
+	return &file, nil
+}
+
+// Override returns the override of the account, or nil when there is no file or entry.
+func (f *File) Override(accountId string) *Override {
+	if f == nil {
+		return nil
+	}
+
+	override, ok := f.Accounts[accountId]
+
+	if !ok {
+		return nil
+	}
+
+	return &override
+}
@@ -0,0 +1,54 @@
+package classification
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"shared/blobstore"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	t.Run("YAML", func(t *testing.T) {
+		file, err := Parse([]byte(`
+Version: 1
+Accounts:
+  "111122223333":
+    Workload: payments
+    Environment: production
+    Owner: team-payments
+`))
+		require.NoError(t, err)
+		assert.Equal(t, &Override{Workload: "payments", Environment: "production", Owner: "team-payments"}, file.Override("111122223333"))
+		assert.Nil(t, file.Override("333322221111"))
+	})
+
+	t.Run("Partial overrides in JSON", func(t *testing.T) {
+		file, err := Parse([]byte(`{"Version": 1, "Accounts": {"111122223333": {"Environment": "sandbox", "Exclude": true}}}`))
+		require.NoError(t, err)
+		assert.Equal(t, &Override{Environment: "sandbox", Exclude: true}, file.Override("111122223333"))
+	})
+
+	t.Run("Unsupported version", func(t *testing.T) {
+		_, err := Parse([]byte(`{"Version": 2, "Accounts": {}}`))
+		assert.EqualError(t, err, "version 2 is not supported, expected 1 to 1")
+	})
+
+	t.Run("Without a file", func(t *testing.T) {
+		var file *File
+		assert.Nil(t, file.Override("111122223333"))
+	})
+}
+
+func TestLoad(t *testing.T) {
+	ctx := context.Background()
+	store := blobstore.NewLocal(t.TempDir())
+	_ = store.Upload(ctx, "my-sample-bucket", "classification.yaml", []byte(`{"Version": 1, "Accounts": {"111122223333": {"Workload": "payments"}}}`))
+
+	file, err := Load(ctx, store, "my-sample-bucket", "classification.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, "payments", file.Override("111122223333").Workload)
+
+	_, err = Load(ctx, store, "my-sample-bucket", "missing.yaml")
+	assert.ErrorContains(t, err, "could not read the classification file s3://my-sample-bucket/missing.yaml")
+}
@@ -0,0 +1,41 @@
+package control
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Status is the outcome of a control in an account.
+type Status string
+
+const (
+	// StatusPassed means every finding of the control passed.
+	StatusPassed Status = "PASSED"
+	// StatusFailed means at least one finding of the control failed or has a warning.
+	StatusFailed Status = "FAILED"
+	// StatusNoFindings means the control is part of the report, but the account has no findings for it. It counts as
+	// passed in the score.
+	StatusNoFindings Status = "NO_FINDINGS"
+)
+
+// Result is the outcome of a control in an account, calculate-score records these for reports with an export.
+type Result struct {
+	Control string `json:"Control"`
+	// Title is the title of the first finding of the control, empty without findings.
+	Title  string `json:"Title,omitempty"`
+	Status Status `json:"Status"`
+	// FailingResources are the resources of the failed findings, sorted and without duplicates.
+	FailingResources []string `json:"FailingResources,omitempty"`
+	// FindingIds are the ids of all findings of the control, sorted and without duplicates.
+	FindingIds []string `json:"FindingIds,omitempty"`
+}
+
+// Key returns the key of the control results next to the findings of an account.
+func Key(findingsKey string) string {
+	return fmt.Sprintf("%s.control-results.json", strings.TrimSuffix(findingsKey, ".json"))
+}
+
+// FailedKey returns the key of the failed controls next to the findings of an account.
+func FailedKey(findingsKey string) string {
+	return fmt.Sprintf("%s.failed-controls.json", strings.TrimSuffix(findingsKey, ".json"))
+}
@@ -0,0 +1,14 @@
+package control
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestKey(t *testing.T) {
+	assert.Equal(t, "my-report/accounts/2023/08/13/111122223333.control-results.json", Key("my-report/accounts/2023/08/13/111122223333.json"))
+}
+
+func TestFailedKey(t *testing.T) {
+	assert.Equal(t, "my-report/accounts/2023/08/13/111122223333.failed-controls.json", FailedKey("my-report/accounts/2023/08/13/111122223333.json"))
+}
@@ -0,0 +1,96 @@
+package dimension
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"shared/finding"
+	"strings"
+)
+
+// The dimensions a report can be split by, a tag dimension is written as Tag:<key>.
+const (
+	Account      = "Account"
+	Region       = "Region"
+	ResourceType = "ResourceType"
+	TagPrefix    = "Tag:"
+)
+
+// OrganizationalUnit is added by workload-context when the OU path of the account is resolved, it cannot be split by.
+const OrganizationalUnit = "OrganizationalUnit"
+
+// Membership is added by fetch-account-mapping to the accounts outside the organization, when the report tags these.
+const Membership = "Membership"
+
+// Missing is the value of a dimension that is not set on a finding, like a tag the resource does not have.
+const Missing = "None"
+
+// Dimension is a value the findings of an account are split by, it is passed along to label the score.
+type Dimension struct {
+	Name  string `json:"Name"`
+	Value string `json:"Value"`
+}
+
+// Validate returns an error for unknown dimension names.
+func Validate(names []string) error {
+	for _, name := range names {
+		switch {
+		case name == Account, name == Region, name == ResourceType:
+		case strings.HasPrefix(name, TagPrefix) && len(name) > len(TagPrefix):
+		default:
+			return fmt.Errorf("unknown split dimension `%s`, use %s, %s, %s or %s<key>", name, Account, Region, ResourceType, TagPrefix)
+		}
+	}
+
+	return nil
+}
+
+// Resolve returns the values of the named dimensions for a finding. Findings are always split per account, so the
+// Account dimension does not add a value.
+func Resolve(names []string, record *finding.Finding) []Dimension {
+	var dimensions []Dimension
+
+	for _, name := range names {
+		var value string
+
+		switch {
+		case name == Account:
+			continue
+		case name == Region:
+			value = record.Region
+		case name == ResourceType:
+			value = record.ResourceType
+		case strings.HasPrefix(name, TagPrefix):
+			value = record.ResourceTags[strings.TrimPrefix(name, TagPrefix)]
+		}
+
+		if value == "" {
+			value = Missing
+		}
+
+		dimensions = append(dimensions, Dimension{Name: name, Value: value})
+	}
+
+	return dimensions
+}
+
+// Key identifies a score of an account: the account id, followed by the id of the dimensions when the report is split.
+func Key(accountId string, dimensions []Dimension) string {
+	if len(dimensions) == 0 {
+		return accountId
+	}
+
+	return accountId + "/" + Id(dimensions)
+}
+
+// Id returns a short identifier for a set of dimension values, that can be used in an object key.
+func Id(dimensions []Dimension) string {
+	var values []string
+
+	for _, dimension := range dimensions {
+		values = append(values, dimension.Name+"="+dimension.Value)
+	}
+
+	sum := sha256.Sum256([]byte(strings.Join(values, "\n")))
+	return hex.EncodeToString(sum[:6])
+}
@@ -0,0 +1,88 @@
+package dimension
+
+import (
+	"github.com/stretchr/testify/assert"
+	"shared/finding"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, Validate([]string{"Account", "Region", "ResourceType", "Tag:team"}))
+	assert.NoError(t, Validate(nil))
+	assert.Error(t, Validate([]string{"region"}))
+	assert.Error(t, Validate([]string{"Tag:"}))
+}
+
+func TestResolve(t *testing.T) {
+	record := &finding.Finding{
+		AwsAccountId: "111122223333",
+		Region:       "eu-west-1",
+		ResourceType: "AwsS3Bucket",
+		ResourceTags: map[string]string{"team": "platform"},
+	}
+
+	t.Run("Resolve values", func(t *testing.T) {
+		dimensions := Resolve([]string{"Account", "Region", "ResourceType", "Tag:team"}, record)
+		assert.Equal(t, []Dimension{
+			{Name: "Region", Value: "eu-west-1"},
+			{Name: "ResourceType", Value: "AwsS3Bucket"},
+			{Name: "Tag:team", Value: "platform"},
+		}, dimensions)
+	})
+
+	t.Run("Missing values", func(t *testing.T) {
+		dimensions := Resolve([]string{"Tag:cost-center"}, record)
+		assert.Equal(t, []Dimension{{Name: "Tag:cost-center", Value: Missing}}, dimensions)
+	})
+
+	t.Run("Split per account only", func(t *testing.T) {
+		assert.Nil(t, Resolve([]string{"Account"}, record))
+		assert.Nil(t, Resolve(nil, record))
+	})
+}
+
+func TestId(t *testing.T) {
+	first := Id([]Dimension{{Name: "Region", Value: "eu-west-1"}})
+	assert.Equal(t, 12, len(first))
+	assert.Equal(t, first, Id([]Dimension{{Name: "Region", Value: "eu-west-1"}}))
+	assert.NotEqual(t, first, Id([]Dimension{{Name: "Region", Value: "eu-central-1"}}))
+}
+
+func TestKey(t *testing.T) {
+	dimensions := []Dimension{{Name: "Region", Value: "eu-west-1"}}
+	assert.Equal(t, "111122223333", Key("111122223333", nil))
+	assert.Equal(t, "111122223333/"+Id(dimensions), Key("111122223333", dimensions))
+}
+
+func TestMetric(t *testing.T) {
+	labels := Labels{
+		Report:      "my-report",
+		AccountId:   "111122223333",
+		Workload:    "payments",
+		Environment: "production",
+		Dimensions:  []Dimension{{Name: "Region", Value: "eu-west-1"}},
+	}
+
+	t.Run("Default dimensions", func(t *testing.T) {
+		assert.Equal(t, []Dimension{
+			{Name: "Report", Value: "my-report"},
+			{Name: "Workload", Value: "payments"},
+			{Name: "Environment", Value: "production"},
+			{Name: "Region", Value: "eu-west-1"},
+		}, Metric(labels, nil))
+	})
+
+	t.Run("Dimension set", func(t *testing.T) {
+		assert.Equal(t, []Dimension{
+			{Name: "AccountId", Value: "111122223333"},
+			{Name: "Region", Value: "eu-west-1"},
+			{Name: "OrganizationalUnit", Value: Missing},
+		}, Metric(labels, []string{"AccountId", "Region", "OrganizationalUnit"}))
+	})
+
+	t.Run("Status", func(t *testing.T) {
+		dimensions := []Dimension{{Name: "Report", Value: "my-report"}}
+		assert.Equal(t, []Dimension{{Name: "Report", Value: "my-report"}, {Name: "Status", Value: "SCORED"}}, WithStatus(dimensions, "SCORED"))
+		assert.Equal(t, 1, len(dimensions))
+	})
+}
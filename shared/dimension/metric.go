@@ -0,0 +1,62 @@
+package dimension
+
+// Status is the dimension the accounts are counted by, it follows the dimensions of a set and cannot be used in one.
+const Status = "Status"
+
+// Labels are the values of a score its metrics can be published under.
+type Labels struct {
+	Report             string
+	AccountId          string
+	AccountName        string
+	Workload           string
+	Environment        string
+	OrganizationalUnit string
+	// Dimensions are the values of a split report.
+	Dimensions []Dimension
+}
+
+// Metric returns the dimensions the metrics of a score are published under. Without a set these are the Report,
+// Workload and Environment followed by the split dimensions. A set picks the values by name, the split dimensions can be
+// used by their name as well, for example Region or Tag:team, and a value that is not set is Missing.
+func Metric(labels Labels, set []string) []Dimension {
+	if set == nil {
+		dimensions := []Dimension{
+			{Name: "Report", Value: labels.Report},
+			{Name: "Workload", Value: labels.Workload},
+			{Name: "Environment", Value: labels.Environment},
+		}
+
+		return append(dimensions, labels.Dimensions...)
+	}
+
+	values := map[string]string{
+		"Report":           labels.Report,
+		"AccountId":        labels.AccountId,
+		"AccountName":      labels.AccountName,
+		"Workload":         labels.Workload,
+		"Environment":      labels.Environment,
+		OrganizationalUnit: labels.OrganizationalUnit,
+	}
+
+	for _, split := range labels.Dimensions {
+		values[split.Name] = split.Value
+	}
+
+	dimensions := make([]Dimension, 0, len(set))
+	for _, name := range set {
+		value := values[name]
+
+		if value == "" {
+			value = Missing
+		}
+
+		dimensions = append(dimensions, Dimension{Name: name, Value: value})
+	}
+
+	return dimensions
+}
+
+// WithStatus returns a copy of the dimensions followed by the status, the dimensions the accounts are counted under.
+func WithStatus(dimensions []Dimension, status string) []Dimension {
+	return append(append([]Dimension{}, dimensions...), Dimension{Name: Status, Value: status})
+}
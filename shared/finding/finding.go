@@ -0,0 +1,103 @@
+package finding
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/securityhub/types"
+)
+
+// Version is the schema version of the Finding written by this module. Bump it whenever a field is
+// added, removed or changes meaning, so older readers refuse artifacts they do not understand.
+const Version = 3
+
+// Finding is the stripped down Security Hub finding that is passed between the steps of the pipeline.
+// The resource fields describe the first resource of the finding, which is the resource the control evaluated.
+type Finding struct {
+	Version        int               `json:"Version,omitempty"`
+	Id             string            `json:"Id"`
+	Status         string            `json:"Status"`
+	ProductArn     string            `json:"ProductArn"`
+	GeneratorId    string            `json:"GeneratorId"`
+	AwsAccountId   string            `json:"AwsAccountId"`
+	AwsAccountName string            `json:"AwsAccountName"`
+	Title          string            `json:"Title"`
+	Region         string            `json:"Region"`
+	ResourceId     string            `json:"ResourceId,omitempty"`
+	ResourceType   string            `json:"ResourceType"`
+	ResourceTags   map[string]string `json:"ResourceTags,omitempty"`
+}
+
+type UnsupportedVersionError struct {
+	Id      string
+	Version int
+}
+
+func (e *UnsupportedVersionError) Error() string {
+	return fmt.Sprintf("finding %s has schema version %d, only versions up to %d are supported", e.Id, e.Version, Version)
+}
+
+// FromSecurityHub strips a Security Hub finding down to the fields needed to calculate a score.
+func FromSecurityHub(finding types.AwsSecurityFinding) *Finding {
+	var status string
+	var resourceId string
+	var resourceType string
+	var resourceTags map[string]string
+
+	if finding.Compliance != nil {
+		status = string(finding.Compliance.Status)
+	}
+
+	if len(finding.Resources) > 0 {
+		resourceId = aws.ToString(finding.Resources[0].Id)
+		resourceType = aws.ToString(finding.Resources[0].Type)
+		resourceTags = finding.Resources[0].Tags
+	}
+
+	return &Finding{
+		Version:        Version,
+		Id:             aws.ToString(finding.Id),
+		Status:         status,
+		ProductArn:     aws.ToString(finding.ProductArn),
+		GeneratorId:    aws.ToString(finding.GeneratorId),
+		AwsAccountId:   aws.ToString(finding.AwsAccountId),
+		AwsAccountName: aws.ToString(finding.AwsAccountName),
+		Title:          aws.ToString(finding.Title),
+		Region:         aws.ToString(finding.Region),
+		ResourceId:     resourceId,
+		ResourceType:   resourceType,
+		ResourceTags:   resourceTags,
+	}
+}
+
+// Validate returns an UnsupportedVersionError when the finding was written by a newer schema.
+// Findings without a version predate the versioned model and are treated as version 1.
+// Version 2 added the region and resource fields, these are empty on older findings. Version 3 added the resource id.
+func (x *Finding) Validate() error {
+	if x.Version > Version {
+		return &UnsupportedVersionError{Id: x.Id, Version: x.Version}
+	}
+
+	return nil
+}
+
+// Decode unmarshals a list of findings and validates the schema version of every finding.
+func Decode(data []byte) ([]*Finding, error) {
+	var findings []*Finding
+
+	err := json.Unmarshal(data, &findings)
+
+	if err != nil {
+		return findings, err
+	}
+
+	for _, finding := range findings {
+		err = finding.Validate()
+
+		if err != nil {
+			return findings, err
+		}
+	}
+
+	return findings, nil
+}
@@ -0,0 +1,127 @@
+package finding
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/securityhub/types"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"os"
+	"testing"
+)
+
+func readStrippedFindings(path string) []*Finding {
+	file, _ := os.ReadFile(path)
+
+	var findings []*Finding
+	_ = json.Unmarshal(file, &findings)
+	return findings
+}
+
+func TestFromSecurityHub(t *testing.T) {
+	t.Run("Strip finding", func(t *testing.T) {
+		finding := FromSecurityHub(types.AwsSecurityFinding{
+			Id:             aws.String("finding-1"),
+			Compliance:     &types.Compliance{Status: types.ComplianceStatusFailed},
+			ProductArn:     aws.String("arn:aws:securityhub:eu-west-1::product/aws/securityhub"),
+			GeneratorId:    aws.String("control-1"),
+			AwsAccountId:   aws.String("111122223333"),
+			AwsAccountName: aws.String("acme-workload-development"),
+			Title:          aws.String("Control 1"),
+			Region:         aws.String("eu-west-1"),
+			Resources: []types.Resource{
+				{Id: aws.String("arn:aws:s3:::my-bucket"), Type: aws.String("AwsS3Bucket"), Tags: map[string]string{"team": "platform"}},
+				{Type: aws.String("AwsAccount")},
+			},
+		})
+
+		assert.Equal(t, Version, finding.Version)
+		assert.Equal(t, "finding-1", finding.Id)
+		assert.Equal(t, "FAILED", finding.Status)
+		assert.Equal(t, "control-1", finding.GeneratorId)
+		assert.Equal(t, "111122223333", finding.AwsAccountId)
+		assert.Equal(t, "acme-workload-development", finding.AwsAccountName)
+		assert.Equal(t, "Control 1", finding.Title)
+		assert.Equal(t, "eu-west-1", finding.Region)
+		assert.Equal(t, "arn:aws:s3:::my-bucket", finding.ResourceId)
+		assert.Equal(t, "AwsS3Bucket", finding.ResourceType)
+		assert.Equal(t, map[string]string{"team": "platform"}, finding.ResourceTags)
+	})
+
+	t.Run("Missing optional fields", func(t *testing.T) {
+		finding := FromSecurityHub(types.AwsSecurityFinding{Id: aws.String("finding-1")})
+		assert.Equal(t, "", finding.Status)
+		assert.Equal(t, "", finding.AwsAccountName)
+		assert.Equal(t, "", finding.ResourceId)
+		assert.Equal(t, "", finding.ResourceType)
+	})
+}
+
+func TestDecode(t *testing.T) {
+	t.Run("Decode findings", func(t *testing.T) {
+		findings, err := Decode([]byte(`[{"Id": "finding-1"}, {"Version": 1, "Id": "finding-2"}]`))
+		assert.NoError(t, err)
+		assert.Equal(t, 2, len(findings))
+	})
+
+	t.Run("Refuse newer schema versions", func(t *testing.T) {
+		_, err := Decode([]byte(`[{"Version": 999, "Id": "finding-1"}]`))
+		var versionErr *UnsupportedVersionError
+		assert.True(t, errors.As(err, &versionErr))
+		assert.Equal(t, 999, versionErr.Version)
+	})
+
+	t.Run("Invalid JSON", func(t *testing.T) {
+		_, err := Decode([]byte(`{`))
+		assert.Error(t, err)
+	})
+}
+
+func TestStream(t *testing.T) {
+	source := readStrippedFindings("../../events/stripped-findings.json")
+
+	t.Run("Round trip is identical to json.Marshal", func(t *testing.T) {
+		expected, _ := json.Marshal(source)
+		decoder := NewDecoder(bytes.NewReader(expected))
+
+		var buffer bytes.Buffer
+		encoder := NewEncoder(&buffer)
+
+		for {
+			finding, err := decoder.Next()
+			if err == io.EOF {
+				break
+			}
+			assert.NoError(t, err)
+			assert.NoError(t, encoder.Encode(finding))
+		}
+
+		assert.NoError(t, encoder.Close())
+		assert.Equal(t, len(source), encoder.Count())
+		assert.Equal(t, string(expected), buffer.String())
+	})
+
+	t.Run("Empty batches", func(t *testing.T) {
+		for _, data := range []string{"null", "[]"} {
+			_, err := NewDecoder(bytes.NewReader([]byte(data))).Next()
+			assert.Equal(t, io.EOF, err)
+		}
+
+		var buffer bytes.Buffer
+		assert.NoError(t, NewEncoder(&buffer).Close())
+		assert.Equal(t, "[]", buffer.String())
+	})
+
+	t.Run("Not a list", func(t *testing.T) {
+		_, err := NewDecoder(bytes.NewReader([]byte(`{"Id": "finding-1"}`))).Next()
+		assert.Error(t, err)
+	})
+
+	t.Run("Newer schema version", func(t *testing.T) {
+		_, err := NewDecoder(bytes.NewReader([]byte(`[{"Version": 999}]`))).Next()
+		var versionErr *UnsupportedVersionError
+		assert.True(t, errors.As(err, &versionErr))
+	})
+}
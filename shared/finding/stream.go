@@ -0,0 +1,121 @@
+package finding
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+// Decoder reads a JSON list of findings one finding at a time, so the full list never has to be in memory.
+type Decoder struct {
+	decoder *json.Decoder
+	started bool
+	done    bool
+}
+
+func NewDecoder(reader io.Reader) *Decoder {
+	return &Decoder{decoder: json.NewDecoder(reader)}
+}
+
+// Next returns the next finding, or io.EOF once the list has been read completely.
+func (x *Decoder) Next() (*Finding, error) {
+	if x.done {
+		return nil, io.EOF
+	}
+
+	if !x.started {
+		x.started = true
+		token, err := x.decoder.Token()
+
+		if err != nil {
+			return nil, err
+		}
+
+		// An empty batch is stored as `null`
+		if token == nil {
+			x.done = true
+			return nil, io.EOF
+		}
+
+		if token != json.Delim('[') {
+			return nil, errors.New("expected a list of findings")
+		}
+	}
+
+	if !x.decoder.More() {
+		x.done = true
+		_, err := x.decoder.Token()
+
+		if err != nil {
+			return nil, err
+		}
+
+		return nil, io.EOF
+	}
+
+	var finding Finding
+	err := x.decoder.Decode(&finding)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = finding.Validate()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &finding, nil
+}
+
+// Encoder writes findings as a JSON list one finding at a time, the output is identical to json.Marshal.
+type Encoder struct {
+	writer io.Writer
+	count  int
+}
+
+func NewEncoder(writer io.Writer) *Encoder {
+	return &Encoder{writer: writer}
+}
+
+func (x *Encoder) Encode(finding *Finding) error {
+	data, err := json.Marshal(finding)
+
+	if err != nil {
+		return err
+	}
+
+	if x.count == 0 {
+		data = append([]byte("["), data...)
+	} else {
+		data = append([]byte(","), data...)
+	}
+
+	_, err = x.writer.Write(data)
+
+	if err != nil {
+		return err
+	}
+
+	x.count++
+	return nil
+}
+
+// Close terminates the list, it does not close the underlying writer.
+func (x *Encoder) Close() error {
+	var err error
+
+	if x.count == 0 {
+		_, err = x.writer.Write([]byte("[]"))
+	} else {
+		_, err = x.writer.Write([]byte("]"))
+	}
+
+	return err
+}
+
+// Count returns the number of findings encoded so far.
+func (x *Encoder) Count() int {
+	return x.count
+}
@@ -0,0 +1,94 @@
+package inventory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"shared/blobstore"
+	"sort"
+	"time"
+)
+
+// Version is the newest version of the snapshot format this module understands.
+const Version = 1
+
+// Key is the location of the snapshot in the bucket, it is shared by all reports.
+const Key = "inventory/accounts.json"
+
+// Account is the metadata of an account in the organization.
+type Account struct {
+	Id                 string            `json:"Id"`
+	Name               string            `json:"Name"`
+	Status             string            `json:"Status"`
+	Tags               map[string]string `json:"Tags,omitempty"`
+	OrganizationalUnit string            `json:"OrganizationalUnit"`
+	// JoinedTimestamp is when the account joined the organization, as a unix timestamp.
+	JoinedTimestamp int64 `json:"JoinedTimestamp,omitempty"`
+}
+
+// Snapshot is the account inventory of the organization at Timestamp.
+type Snapshot struct {
+	Version   int       `json:"Version"`
+	Timestamp int64     `json:"Timestamp"`
+	Accounts  []Account `json:"Accounts"`
+}
+
+// New returns a snapshot of the accounts, sorted by id.
+func New(accounts []Account, now time.Time) *Snapshot {
+	sort.Slice(accounts, func(i, j int) bool {
+		return accounts[i].Id < accounts[j].Id
+	})
+
+	return &Snapshot{Version: Version, Timestamp: now.Unix(), Accounts: accounts}
+}
+
+// Stale reports whether the snapshot is older than the ttl.
+func (s *Snapshot) Stale(ttl time.Duration, now time.Time) bool {
+	return now.Sub(time.Unix(s.Timestamp, 0)) >= ttl
+}
+
+// Mapping returns the accounts by id.
+func (s *Snapshot) Mapping() map[string]Account {
+	mapping := map[string]Account{}
+
+	for _, account := range s.Accounts {
+		mapping[account.Id] = account
+	}
+
+	return mapping
+}
+
+// Load reads the snapshot from the bucket, it returns nil when there is no snapshot yet.
+func Load(ctx context.Context, store blobstore.BlobStore, bucket string) (*Snapshot, error) {
+	data, err := store.Download(ctx, bucket, Key)
+
+	if blobstore.IsNotFound(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshot Snapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, err
+	}
+
+	if snapshot.Version > Version {
+		return nil, fmt.Errorf("account inventory version %d is newer than the supported version %d", snapshot.Version, Version)
+	}
+
+	return &snapshot, nil
+}
+
+// Save writes the snapshot to the bucket, replacing the previous one.
+func Save(ctx context.Context, store blobstore.BlobStore, bucket string, snapshot *Snapshot) error {
+	data, err := json.Marshal(snapshot)
+
+	if err != nil {
+		return err
+	}
+
+	return store.Upload(ctx, bucket, Key, data)
+}
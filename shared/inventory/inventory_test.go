@@ -0,0 +1,50 @@
+package inventory
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"shared/blobstore"
+	"testing"
+	"time"
+)
+
+func TestSnapshot(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1691920532, 0)
+
+	t.Run("Missing snapshot", func(t *testing.T) {
+		store := blobstore.NewLocal(t.TempDir())
+		snapshot, err := Load(ctx, store, "my-sample-bucket")
+		assert.NoError(t, err)
+		assert.Nil(t, snapshot)
+	})
+
+	t.Run("Save and load", func(t *testing.T) {
+		store := blobstore.NewLocal(t.TempDir())
+		snapshot := New([]Account{
+			{Id: "333322221111", Name: "acme-workload-test", Status: "ACTIVE"},
+			{Id: "111122223333", Name: "acme-workload-development", Status: "ACTIVE", Tags: map[string]string{"owner": "payments"}},
+		}, now)
+
+		assert.NoError(t, Save(ctx, store, "my-sample-bucket", snapshot))
+		loaded, err := Load(ctx, store, "my-sample-bucket")
+		assert.NoError(t, err)
+		assert.Equal(t, snapshot, loaded)
+		assert.Equal(t, "111122223333", loaded.Accounts[0].Id)
+		assert.Equal(t, "payments", loaded.Mapping()["111122223333"].Tags["owner"])
+	})
+
+	t.Run("Stale", func(t *testing.T) {
+		snapshot := New(nil, now)
+		assert.False(t, snapshot.Stale(time.Hour, now.Add(59*time.Minute)))
+		assert.True(t, snapshot.Stale(time.Hour, now.Add(time.Hour)))
+		assert.True(t, snapshot.Stale(0, now))
+	})
+
+	t.Run("Refuse newer versions", func(t *testing.T) {
+		store := blobstore.NewLocal(t.TempDir())
+		_ = store.Upload(ctx, "my-sample-bucket", Key, []byte(`{"Version": 2}`))
+		_, err := Load(ctx, store, "my-sample-bucket")
+		assert.ErrorContains(t, err, "version 2 is newer")
+	})
+}
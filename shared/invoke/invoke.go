@@ -0,0 +1,60 @@
+package invoke
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/aws/aws-lambda-go/lambda"
+	"io"
+	"os"
+)
+
+// Start hands the handler to the Lambda runtime, unless LOCAL_EVENT is set. In that case the handler is invoked once
+// with the event read from the file in LOCAL_EVENT, or from stdin when it is "-", and the response is written to stdout.
+func Start[Request any, Response any](handler func(context.Context, Request) (Response, error)) {
+	path := os.Getenv("LOCAL_EVENT")
+
+	if path == "" {
+		lambda.Start(handler)
+		return
+	}
+
+	err := Local(context.Background(), handler, path, os.Stdin, os.Stdout)
+
+	if err != nil {
+		_, _ = os.Stderr.WriteString("error: " + err.Error() + "\n")
+		os.Exit(1)
+	}
+}
+
+// Local invokes the handler with the event stored at path, "-" reads the event from stdin.
+func Local[Request any, Response any](ctx context.Context, handler func(context.Context, Request) (Response, error), path string, stdin io.Reader, stdout io.Writer) error {
+	var data []byte
+	var err error
+
+	if path == "-" {
+		data, err = io.ReadAll(stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	var request Request
+	err = json.Unmarshal(data, &request)
+
+	if err != nil {
+		return err
+	}
+
+	response, err := handler(ctx, request)
+
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(response)
+}
@@ -0,0 +1,59 @@
+package invoke
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type request struct {
+	Name string
+}
+
+type response struct {
+	Greeting string
+}
+
+func greet(ctx context.Context, request request) (response, error) {
+	if request.Name == "" {
+		return response{}, errors.New("missing name")
+	}
+
+	return response{Greeting: "Hello " + request.Name}, nil
+}
+
+func TestLocal(t *testing.T) {
+	t.Run("Event from stdin", func(t *testing.T) {
+		var stdout bytes.Buffer
+		err := Local(context.Background(), greet, "-", strings.NewReader(`{"Name": "Joris"}`), &stdout)
+		assert.NoError(t, err)
+		assert.Equal(t, "{\n  \"Greeting\": \"Hello Joris\"\n}\n", stdout.String())
+	})
+
+	t.Run("Event from file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "event.json")
+		_ = os.WriteFile(path, []byte(`{"Name": "Joris"}`), 0o644)
+
+		var stdout bytes.Buffer
+		err := Local(context.Background(), greet, path, nil, &stdout)
+		assert.NoError(t, err)
+		assert.Contains(t, stdout.String(), "Hello Joris")
+	})
+
+	t.Run("Handler error", func(t *testing.T) {
+		var stdout bytes.Buffer
+		err := Local(context.Background(), greet, "-", strings.NewReader(`{}`), &stdout)
+		assert.EqualError(t, err, "missing name")
+		assert.Equal(t, 0, stdout.Len())
+	})
+
+	t.Run("Invalid event", func(t *testing.T) {
+		err := Local(context.Background(), greet, "-", strings.NewReader(`{`), &bytes.Buffer{})
+		assert.Error(t, err)
+	})
+}
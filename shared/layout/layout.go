@@ -0,0 +1,46 @@
+package layout
+
+import (
+	"fmt"
+	"github.com/gofrs/uuid"
+	"path/filepath"
+	"time"
+)
+
+// Key returns the object key for an artifact: <report>/<prefix>/<yyyy>/<mm>/<dd>/<name>.json
+func Key(report string, prefix string, t time.Time, name string) string {
+	return filepath.Join(
+		report,
+		prefix,
+		fmt.Sprintf("%d", t.Year()),
+		fmt.Sprintf("%02d", int(t.Month())),
+		fmt.Sprintf("%02d", t.Day()),
+		fmt.Sprintf("%s.json", name),
+	)
+}
+
+// Unique returns a key named after a time based UUID, for artifacts that are written many times during a run.
+func Unique(report string, prefix string) string {
+	id, _ := uuid.NewV6()
+	return Key(report, prefix, time.Now(), id.String())
+}
+
+// Folder returns the folder of the files of a run that are not JSON, like exports and documents:
+// <report>/<prefix>/<yyyy>/<mm>/<dd>/<timestamp>
+func Folder(report string, prefix string, timestamp int64) string {
+	t := time.Unix(timestamp, 0)
+	return filepath.Join(
+		report,
+		prefix,
+		fmt.Sprintf("%d", t.Year()),
+		fmt.Sprintf("%02d", int(t.Month())),
+		fmt.Sprintf("%02d", t.Day()),
+		fmt.Sprintf("%d", timestamp),
+	)
+}
+
+// Timestamped returns a key named after the run timestamp, for artifacts that are written once per run.
+func Timestamped(report string, prefix string, timestamp int64) string {
+	t := time.Unix(timestamp, 0)
+	return Key(report, prefix, t, fmt.Sprintf("%d", t.Unix()))
+}
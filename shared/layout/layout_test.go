@@ -0,0 +1,32 @@
+package layout
+
+import (
+	"github.com/stretchr/testify/assert"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestLayout(t *testing.T) {
+	t.Run("Key", func(t *testing.T) {
+		key := Key("my-report", "raw", time.Date(2023, 8, 3, 10, 0, 0, 0, time.UTC), "batch")
+		assert.Equal(t, "my-report/raw/2023/08/03/batch.json", key)
+	})
+
+	t.Run("Unique", func(t *testing.T) {
+		key := Unique("my-report", "raw")
+		regex := regexp.MustCompile("^my-report/raw/[0-9]{4}/[0-9]{2}/[0-9]{2}/[a-z0-9]{8}-[a-z0-9]{4}-[a-z0-9]{4}-[a-z0-9]{4}-[a-z0-9]{12}.json$")
+		assert.Regexp(t, regex, key)
+		assert.NotEqual(t, key, Unique("my-report", "raw"))
+	})
+
+	t.Run("Timestamped", func(t *testing.T) {
+		key := Timestamped("my-report", "111122223333", 1691920532)
+		assert.Equal(t, "my-report/111122223333/2023/08/13/1691920532.json", key)
+	})
+
+	t.Run("Folder", func(t *testing.T) {
+		folder := Folder("my-report", "exports", 1691920532)
+		assert.Equal(t, "my-report/exports/2023/08/13/1691920532", folder)
+	})
+}
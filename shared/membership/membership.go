@@ -0,0 +1,56 @@
+package membership
+
+import "fmt"
+
+// Membership tells how an account with findings relates to the organization.
+type Membership string
+
+const (
+	// Organization means the account is part of the organization.
+	Organization Membership = "ORGANIZATION"
+	// Invited means the account is a Security Hub member by invitation, but not part of the organization.
+	Invited Membership = "INVITED"
+	// Unknown means neither Organizations nor Security Hub know the account, for example an account that has left.
+	Unknown Membership = "UNKNOWN"
+)
+
+// Handling is what a report does with the accounts outside the organization.
+type Handling string
+
+const (
+	// Score scores the accounts like the accounts of the organization.
+	Score Handling = "SCORE"
+	// Tag scores the accounts with an additional Membership dimension, so they are kept apart in the metrics.
+	Tag Handling = "TAG"
+	// Drop excludes the accounts from the report.
+	Drop Handling = "DROP"
+)
+
+// ResolveHandling returns the handling, Drop when it is not set.
+func ResolveHandling(handling Handling) (Handling, error) {
+	switch handling {
+	case "":
+		return Drop, nil
+	case Score, Tag, Drop:
+		return handling, nil
+	}
+
+	return "", fmt.Errorf("unknown handling of non-organization accounts `%s`, use %s, %s or %s", handling, Score, Tag, Drop)
+}
+
+// Summary counts the accounts outside the organization and tells how they were handled.
+type Summary struct {
+	Handling Handling `json:"Handling"`
+	Invited  int      `json:"Invited"`
+	Unknown  int      `json:"Unknown"`
+}
+
+// Add counts an account outside the organization.
+func (s *Summary) Add(membership Membership) {
+	switch membership {
+	case Invited:
+		s.Invited++
+	case Unknown:
+		s.Unknown++
+	}
+}
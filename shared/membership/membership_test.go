@@ -0,0 +1,29 @@
+package membership
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestResolveHandling(t *testing.T) {
+	handling, err := ResolveHandling("")
+	assert.NoError(t, err)
+	assert.Equal(t, Drop, handling)
+
+	handling, err = ResolveHandling(Tag)
+	assert.NoError(t, err)
+	assert.Equal(t, Tag, handling)
+
+	_, err = ResolveHandling("IGNORE")
+	assert.Error(t, err)
+}
+
+func TestSummary(t *testing.T) {
+	summary := Summary{Handling: Score}
+	summary.Add(Invited)
+	summary.Add(Unknown)
+	summary.Add(Unknown)
+	summary.Add(Organization)
+
+	assert.Equal(t, Summary{Handling: Score, Invited: 1, Unknown: 2}, summary)
+}
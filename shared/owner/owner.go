@@ -0,0 +1,30 @@
+package owner
+
+// Owner tells who to contact about an account, it is passed along with every score.
+type Owner struct {
+	Team       string `json:"Team,omitempty"`
+	Email      string `json:"Email,omitempty"`
+	CostCenter string `json:"CostCenter,omitempty"`
+}
+
+// Merge returns the owner with the empty fields taken from other, so sources can be combined by precedence.
+func (o Owner) Merge(other Owner) Owner {
+	if o.Team == "" {
+		o.Team = other.Team
+	}
+
+	if o.Email == "" {
+		o.Email = other.Email
+	}
+
+	if o.CostCenter == "" {
+		o.CostCenter = other.CostCenter
+	}
+
+	return o
+}
+
+// IsZero reports whether nothing is known about the owner.
+func (o Owner) IsZero() bool {
+	return o == Owner{}
+}
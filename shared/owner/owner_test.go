@@ -0,0 +1,17 @@
+package owner
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	file := Owner{Team: "payments"}
+	tags := Owner{Team: "billing", Email: "billing@example.com"}
+	rule := Owner{Team: "platform", CostCenter: "CC-1234"}
+
+	merged := file.Merge(tags).Merge(rule)
+	assert.Equal(t, Owner{Team: "payments", Email: "billing@example.com", CostCenter: "CC-1234"}, merged)
+	assert.False(t, merged.IsZero())
+	assert.True(t, Owner{}.IsZero())
+}
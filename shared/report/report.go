@@ -0,0 +1,116 @@
+package report
+
+import (
+	"shared/membership"
+	"slices"
+)
+
+// Options are the settings of a report, they are given in the input of the state machine and passed along by every step.
+type Options struct {
+	// NonOrganizationAccounts is what the report does with the findings of accounts outside the organization.
+	NonOrganizationAccounts membership.Handling `json:"NonOrganizationAccounts,omitempty"`
+	// GracePeriodDays is how long a newly joined account is baselining, instead of scored.
+	GracePeriodDays int `json:"GracePeriodDays,omitempty"`
+	// ControlMetrics publishes the number of accounts failing each control of the report.
+	ControlMetrics bool `json:"ControlMetrics,omitempty"`
+	// Namespace is the CloudWatch namespace of the metrics, SecurityPosture when not set.
+	Namespace string `json:"Namespace,omitempty"`
+	// DimensionSets are the combinations of dimensions the metrics of every account are published under, for example
+	// [["Report"], ["Report", "Environment"]]. Without sets the Report, Workload and Environment are used.
+	DimensionSets [][]string `json:"DimensionSets,omitempty"`
+	// Sinks are where the metrics are published: CloudWatch, EMF, Prometheus, OTLP or StatsD. Without sinks the
+	// metrics mode of publish-metrics decides between CloudWatch and EMF.
+	Sinks []string `json:"Sinks,omitempty"`
+	// Regressions imports a finding into the Security Hub of every account of which the score regressed.
+	Regressions *Regressions `json:"Regressions,omitempty"`
+	// Notifications are the rules that send alerts about the scores of the report to webhooks.
+	Notifications []NotificationRule `json:"Notifications,omitempty"`
+	// Documents renders an HTML and Markdown document per workload, and a summary of the organization.
+	Documents *Documents `json:"Documents,omitempty"`
+	// Export writes the result of every control in every account to a spreadsheet.
+	Export *Export `json:"Export,omitempty"`
+	// Dashboard builds a CloudWatch dashboard of the workloads and environments of the latest run.
+	Dashboard *Dashboard `json:"Dashboard,omitempty"`
+}
+
+// RecordsFailedControls reports whether calculate-score has to record the failed controls of every account.
+func (o Options) RecordsFailedControls() bool {
+	if o.ControlMetrics || o.Regressions != nil || o.Documents != nil {
+		return true
+	}
+
+	for _, rule := range o.Notifications {
+		if len(rule.FailingControls) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// PublishesToCloudWatch reports whether publish-metrics puts the metrics in CloudWatch, directly or through the
+// embedded metric format. Without sinks the metrics mode of publish-metrics uses one of both.
+func (o Options) PublishesToCloudWatch() bool {
+	return len(o.Sinks) == 0 || slices.Contains(o.Sinks, "CloudWatch") || slices.Contains(o.Sinks, "EMF")
+}
+
+// RecordsControlResults reports whether calculate-score has to record the result of every control of every account.
+func (o Options) RecordsControlResults() bool {
+	return o.Export != nil
+}
+
+// Regressions are the scores at which an account gets a finding in Security Hub, the finding is archived once the score
+// recovers.
+type Regressions struct {
+	// Thresholds are the lowest acceptable score per environment, the threshold of `*` applies to the other environments.
+	Thresholds map[string]float64 `json:"Thresholds,omitempty"`
+	// MaxDrop is the number of points the score may drop since the previous run, drops are not checked without it.
+	MaxDrop float64 `json:"MaxDrop,omitempty"`
+}
+
+// NotificationRule alerts the webhooks about the accounts it matches, an alert is sent once until its condition clears.
+type NotificationRule struct {
+	// Name identifies the alerts of the rule between runs.
+	Name string `json:"Name"`
+	// Reports, Workloads and Environments limit the accounts of the rule, an empty list matches every value.
+	Reports      []string `json:"Reports,omitempty"`
+	Workloads    []string `json:"Workloads,omitempty"`
+	Environments []string `json:"Environments,omitempty"`
+	// BelowTarget alerts when the score is below the target.
+	BelowTarget float64 `json:"BelowTarget,omitempty"`
+	// MaxDrop alerts when the score dropped more than this number of points since the previous run.
+	MaxDrop float64 `json:"MaxDrop,omitempty"`
+	// FailingControls alerts when one of these controls starts failing, `*` matches every control.
+	FailingControls []string `json:"FailingControls,omitempty"`
+	// Webhooks are the names of the webhooks the alerts are sent to, as configured on notify-changes.
+	Webhooks []string `json:"Webhooks"`
+}
+
+// Documents are the readable reports of the scores, rendered with Go templates that can be replaced per report.
+type Documents struct {
+	// Templates is a prefix in the bucket with templates that replace the built-in templates of the same name.
+	Templates string `json:"Templates,omitempty"`
+	// TopControls is the number of failing controls a document lists, 10 when not set.
+	TopControls int `json:"TopControls,omitempty"`
+}
+
+// Export is the account by control matrix of a run, for auditors.
+type Export struct {
+	// Formats are the files written per run: CSV, XLSX or both when not set.
+	Formats []string `json:"Formats,omitempty"`
+}
+
+// Dashboard is the layout of the CloudWatch dashboard of a report, every workload gets a row of the same widgets.
+type Dashboard struct {
+	// Name is the name of the dashboard, the name of the report when not set.
+	Name string `json:"Name,omitempty"`
+	// Widgets are shown for every workload in this order: Score, ScoreTrend, Findings, ControlsFailed or Status. Score and
+	// Findings when not set.
+	Widgets []string `json:"Widgets,omitempty"`
+	// Columns is the number of widgets next to each other, 2 when not set.
+	Columns int `json:"Columns,omitempty"`
+	// Height is the height of a widget in grid units, 6 when not set.
+	Height int `json:"Height,omitempty"`
+	// Period is the period of the metrics in seconds, 21600 when not set.
+	Period int `json:"Period,omitempty"`
+}
@@ -0,0 +1,27 @@
+package report
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestRecordsFailedControls(t *testing.T) {
+	assert.False(t, Options{}.RecordsFailedControls())
+	assert.True(t, Options{ControlMetrics: true}.RecordsFailedControls())
+	assert.True(t, Options{Regressions: &Regressions{MaxDrop: 5}}.RecordsFailedControls())
+	assert.True(t, Options{Documents: &Documents{}}.RecordsFailedControls())
+	assert.False(t, Options{Notifications: []NotificationRule{{Name: "target", BelowTarget: 80}}}.RecordsFailedControls())
+	assert.True(t, Options{Notifications: []NotificationRule{{Name: "controls", FailingControls: []string{"*"}}}}.RecordsFailedControls())
+}
+
+func TestRecordsControlResults(t *testing.T) {
+	assert.False(t, Options{ControlMetrics: true}.RecordsControlResults())
+	assert.True(t, Options{Export: &Export{}}.RecordsControlResults())
+}
+
+func TestPublishesToCloudWatch(t *testing.T) {
+	assert.True(t, Options{}.PublishesToCloudWatch())
+	assert.True(t, Options{Sinks: []string{"Prometheus", "EMF"}}.PublishesToCloudWatch())
+	assert.True(t, Options{Sinks: []string{"CloudWatch"}}.PublishesToCloudWatch())
+	assert.False(t, Options{Sinks: []string{"Prometheus", "OTLP"}}.PublishesToCloudWatch())
+}
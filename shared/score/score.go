@@ -0,0 +1,50 @@
+package score
+
+// Status tells how the score of an account was determined, a score is only meaningful when the status is StatusScored.
+type Status string
+
+const (
+	// StatusScored means the findings of the account were evaluated against the controls of the report.
+	StatusScored Status = "SCORED"
+	// StatusNoFindings means the account has no findings at all, for example because it was created recently.
+	StatusNoFindings Status = "NO_FINDINGS"
+	// StatusStandardNotEnabled means the account has Security Hub findings, but none for the report.
+	StatusStandardNotEnabled Status = "STANDARD_NOT_ENABLED"
+	// StatusExcluded means the account is excluded by the classification file, it is not published.
+	StatusExcluded Status = "EXCLUDED"
+	// StatusBaselining means the account joined the organization recently, and is still in its grace period.
+	StatusBaselining Status = "BASELINING"
+	// StatusError means the score could not be calculated.
+	StatusError Status = "ERROR"
+)
+
+// Resolve returns the status, payloads written before the status was introduced were always scored.
+func Resolve(status Status) Status {
+	if status == "" {
+		return StatusScored
+	}
+
+	return status
+}
+
+// Baseline is the grace period of an account that joined the organization recently.
+type Baseline struct {
+	// Until is the end of the grace period, as a unix timestamp.
+	Until int64 `json:"Until"`
+	// RemainingHours is the time left in the grace period at the time of the report, rounded up.
+	RemainingHours int `json:"RemainingHours"`
+}
+
+// NewBaseline returns the grace period of an account that joined at joined, or nil when it has passed at timestamp.
+func NewBaseline(joined int64, graceDays int, timestamp int64) *Baseline {
+	if joined == 0 || graceDays <= 0 {
+		return nil
+	}
+
+	until := joined + int64(graceDays)*24*60*60
+	if until <= timestamp {
+		return nil
+	}
+
+	return &Baseline{Until: until, RemainingHours: int((until - timestamp + 60*60 - 1) / (60 * 60))}
+}
@@ -0,0 +1,22 @@
+package score
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	assert.Equal(t, StatusScored, Resolve(""))
+	assert.Equal(t, StatusBaselining, Resolve(StatusBaselining))
+}
+
+func TestNewBaseline(t *testing.T) {
+	joined := int64(1691920532)
+	day := int64(24 * 60 * 60)
+
+	assert.Nil(t, NewBaseline(joined, 0, joined), "no grace period configured")
+	assert.Nil(t, NewBaseline(0, 7, joined), "unknown join date")
+	assert.Nil(t, NewBaseline(joined, 7, joined+7*day), "grace period has passed")
+	assert.Equal(t, &Baseline{Until: joined + 7*day, RemainingHours: 168}, NewBaseline(joined, 7, joined))
+	assert.Equal(t, &Baseline{Until: joined + 7*day, RemainingHours: 1}, NewBaseline(joined, 7, joined+7*day-60))
+}
@@ -0,0 +1,67 @@
+package statefile
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"shared/blobstore"
+)
+
+// Header is embedded in every state file, Version is the version of the format the file was written with.
+type Header struct {
+	Version int `json:"Version"`
+}
+
+func (x *Header) header() *Header {
+	return x
+}
+
+// versioned is a pointer to a state that embeds Header.
+type versioned[T any] interface {
+	*T
+	header() *Header
+}
+
+// Key returns the key of the state file a function keeps between the runs of a report, for example
+// `<report>/regressions/state.json`.
+func Key(report string, name string) string {
+	return fmt.Sprintf("%s/%s/state.json", report, name)
+}
+
+// Load reads the state file, the state is empty for the first run of a report. A file written with a newer version
+// than the caller supports is refused, saving it would drop what the caller does not understand.
+func Load[T any, P versioned[T]](ctx context.Context, store blobstore.BlobStore, bucket string, key string, version int) (P, error) {
+	state := P(new(T))
+	data, err := store.Download(ctx, bucket, key)
+
+	if blobstore.IsNotFound(err) {
+		state.header().Version = version
+		return state, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, err
+	}
+
+	if state.header().Version > version {
+		return nil, fmt.Errorf("state `%s` has version %d, newer than the supported version %d", key, state.header().Version, version)
+	}
+
+	return state, nil
+}
+
+// Save writes the state file with the version of the caller.
+func Save[T any, P versioned[T]](ctx context.Context, store blobstore.BlobStore, bucket string, key string, version int, state P) error {
+	state.header().Version = version
+	data, err := json.Marshal(state)
+
+	if err != nil {
+		return err
+	}
+
+	return store.Upload(ctx, bucket, key, data)
+}
@@ -0,0 +1,58 @@
+package statefile
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"shared/blobstore"
+	"testing"
+)
+
+type testState struct {
+	Header
+	Scores map[string]float64 `json:"Scores"`
+}
+
+func TestStateFile(t *testing.T) {
+	ctx := context.Background()
+	key := Key("my-report", "regressions")
+
+	t.Run("Key", func(t *testing.T) {
+		assert.Equal(t, "my-report/regressions/state.json", key)
+	})
+
+	t.Run("Empty state on the first run", func(t *testing.T) {
+		state, err := Load[testState](ctx, blobstore.NewLocal(t.TempDir()), "my-sample-bucket", key, 2)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, state.Version)
+		assert.Empty(t, state.Scores)
+	})
+
+	t.Run("Save and load", func(t *testing.T) {
+		store := blobstore.NewLocal(t.TempDir())
+		err := Save(ctx, store, "my-sample-bucket", key, 1, &testState{Scores: map[string]float64{"111122223333": 80}})
+		assert.NoError(t, err)
+
+		data, _ := store.Download(ctx, "my-sample-bucket", key)
+		assert.JSONEq(t, `{"Version": 1, "Scores": {"111122223333": 80}}`, string(data))
+
+		state, err := Load[testState](ctx, store, "my-sample-bucket", key, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]float64{"111122223333": 80}, state.Scores)
+	})
+
+	t.Run("Refuse a newer version", func(t *testing.T) {
+		store := blobstore.NewLocal(t.TempDir())
+		_ = store.Upload(ctx, "my-sample-bucket", key, []byte(`{"Version": 3}`))
+
+		_, err := Load[testState](ctx, store, "my-sample-bucket", key, 2)
+		assert.EqualError(t, err, "state `my-report/regressions/state.json` has version 3, newer than the supported version 2")
+	})
+
+	t.Run("Fail on a malformed state", func(t *testing.T) {
+		store := blobstore.NewLocal(t.TempDir())
+		_ = store.Upload(ctx, "my-sample-bucket", key, []byte(`{`))
+
+		_, err := Load[testState](ctx, store, "my-sample-bucket", key, 2)
+		assert.Error(t, err)
+	})
+}